@@ -6,8 +6,12 @@ import (
 	"context"
 	"encoding/json"
 	api "github.com/FantasyRL/go-mcp-demo/api/model/api"
+	"github.com/FantasyRL/go-mcp-demo/api/mw"
 	"github.com/FantasyRL/go-mcp-demo/api/pack"
-	"github.com/FantasyRL/go-mcp-demo/internal/host"
+	"github.com/FantasyRL/go-mcp-demo/internal/auth"
+	"github.com/FantasyRL/go-mcp-demo/internal/usage"
+	"github.com/FantasyRL/go-mcp-demo/pkg/constant"
+	"github.com/FantasyRL/go-mcp-demo/pkg/errno"
 	"github.com/cloudwego/hertz/pkg/app"
 	"github.com/cloudwego/hertz/pkg/protocol/consts"
 	"github.com/cloudwego/hertz/pkg/protocol/sse"
@@ -24,13 +28,41 @@ func Chat(ctx context.Context, c *app.RequestContext) {
 		return
 	}
 
-	resp := new(api.ChatResponse)
-	msg, err := host.NewHost(ctx, clientSet).Chat(1, req.Message)
+	if _, err = clientSet.AiProviderCli.ResolveModel(req.Model); err != nil {
+		pack.RespError(c, err)
+		return
+	}
+	images, err := bindImages(c, req.Images)
+	if err != nil {
+		pack.RespError(c, err)
+		return
+	}
+	opts, err := bindOptions(req.Temperature, req.TopP, req.MaxTokens, req.Seed, req.Stop)
 	if err != nil {
 		pack.RespError(c, err)
 		return
 	}
-	resp.Response = msg
+	var schema map[string]any
+	if req.ResponseSchema != "" {
+		if err = json.Unmarshal([]byte(req.ResponseSchema), &schema); err != nil {
+			pack.RespError(c, errno.ParamError.WithMessage("response_schema 不是合法的 JSON Schema 对象: "+err.Error()))
+			return
+		}
+	}
+
+	uid := mw.GetUserID(c)
+	h := newHost(ctx).WithModel(req.Model).WithImages(images).WithOptions(opts).WithResponseSchema(schema)
+	msg, err := h.Chat(uid, req.Message)
+	if err != nil {
+		pack.RespError(c, err)
+		return
+	}
+	u := h.Usage(uid)
+	resp := &api.ChatResponse{
+		Response:          msg,
+		Usage:             packUsage(u.Usage),
+		ConversationUsage: packUsage(u.Conversation),
+	}
 	pack.RespData(c, resp)
 }
 
@@ -43,24 +75,248 @@ func ChatSSE(ctx context.Context, c *app.RequestContext) {
 		c.String(consts.StatusBadRequest, err.Error())
 		return
 	}
+	// 模型不可用时直接返回错误，不建立 SSE
+	if _, err := clientSet.AiProviderCli.ResolveModel(req.Model); err != nil {
+		pack.RespError(c, err)
+		return
+	}
+	images, err := bindImages(c, req.Images)
+	if err != nil {
+		pack.RespError(c, err)
+		return
+	}
+	opts, err := bindOptions(req.Temperature, req.TopP, req.MaxTokens, req.Seed, req.Stop)
+	if err != nil {
+		pack.RespError(c, err)
+		return
+	}
+	// 流式回答边生成边推送，无法校验与修复，结构化输出只支持 POST /api/v1/chat
+	if len(c.Query("response_schema")) != 0 {
+		pack.RespError(c, errno.ParamError.WithMessage("response_schema 只支持非流式接口 POST /api/v1/chat"))
+		return
+	}
 
 	w := sse.NewWriter(c)
 	defer w.Close()
 
-	emit := func(_ string, v any) error {
+	// 事件名写入 SSE 的 event 字段（见 constant.SSEEvent*），客户端据此区分推理、回答与工具调用
+	emit := func(event string, v any) error {
 		switch x := v.(type) {
 		case string: // 用于 [DONE]
-			return w.WriteEvent("", "", []byte(x))
+			return w.WriteEvent("", event, []byte(x))
 		case json.RawMessage:
-			return w.WriteEvent("", "", x)
+			return w.WriteEvent("", event, x)
 		default:
 			b, _ := json.Marshal(v)
-			return w.WriteEvent("", "", b)
+			return w.WriteEvent("", event, b)
 		}
 	}
 
-	if err := host.NewHost(ctx, clientSet).StreamChatOpenAI(ctx, 1, req.Message, emit); err != nil {
-		_ = emit("error", map[string]any{"error": err.Error()})
+	if err := newHost(ctx).WithModel(req.Model).WithImages(images).WithOptions(opts).StreamChatOpenAI(ctx, mw.GetUserID(c), req.Message, emit); err != nil {
+		_ = emit(constant.SSEEventError, map[string]any{"error": err.Error()})
+		// 响应头已经发出，只能通过 c.Errors 让 Sentinel 统计这次失败
+		_ = c.Error(err)
+		return
+	}
+}
+
+// GetUsage .
+// @router /api/v1/usage [GET]
+func GetUsage(ctx context.Context, c *app.RequestContext) {
+	var err error
+	var req api.GetUsageRequest
+	err = c.BindAndValidate(&req)
+	if err != nil {
+		c.String(consts.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err = checkDay("from", req.From); err != nil {
+		pack.RespError(c, err)
+		return
+	}
+	if err = checkDay("to", req.To); err != nil {
+		pack.RespError(c, err)
+		return
+	}
+	records := usage.Default().Report(mw.GetUserID(c), req.From, req.To)
+	var total usage.Usage
+	resp := &api.GetUsageResponse{Records: make([]*api.UsageRecord, 0, len(records))}
+	for i := range records {
+		total.Add(records[i].Usage)
+		resp.Records = append(resp.Records, packUsageRecord(&records[i]))
+	}
+	resp.Total = packUsage(total)
+	pack.RespData(c, resp)
+}
+
+// ListModels .
+// @router /api/v1/models [GET]
+func ListModels(ctx context.Context, c *app.RequestContext) {
+	var err error
+	var req api.ListModelsRequest
+	err = c.BindAndValidate(&req)
+	if err != nil {
+		c.String(consts.StatusBadRequest, err.Error())
+		return
+	}
+
+	models := clientSet.AiProviderCli.Models()
+	resp := &api.ListModelsResponse{Models: make([]*api.ModelInfo, 0, len(models))}
+	for _, m := range models {
+		resp.Models = append(resp.Models, &api.ModelInfo{
+			Id:        m.ID,
+			Provider:  m.Provider,
+			Model:     m.Model,
+			Type:      m.Type,
+			IsDefault: m.Default,
+		})
+	}
+	pack.RespData(c, resp)
+}
+
+// UploadImages .
+// @router /api/v1/images [POST]
+func UploadImages(ctx context.Context, c *app.RequestContext) {
+	var err error
+	var req api.UploadImagesRequest
+	err = c.BindAndValidate(&req)
+	if err != nil {
+		c.String(consts.StatusBadRequest, err.Error())
+		return
+	}
+
+	images, err := bindImages(c, nil)
+	if err != nil {
+		pack.RespError(c, err)
+		return
+	}
+	if len(images) == 0 {
+		pack.RespError(c, errno.ParamError.WithMessage("请使用 multipart/form-data 上传 images 文件"))
+		return
+	}
+	pack.RespData(c, &api.UploadImagesResponse{Images: images})
+}
+
+// Login .
+// @router /api/v1/auth/login [POST]
+func Login(ctx context.Context, c *app.RequestContext) {
+	var err error
+	var req api.LoginRequest
+	err = c.BindAndValidate(&req)
+	if err != nil {
+		c.String(consts.StatusBadRequest, err.Error())
+		return
+	}
+
+	a := auth.Default()
+	if a == nil {
+		pack.RespError(c, errAuthDisabled)
+		return
+	}
+	tokens, err := a.Login(req.Username, req.Password)
+	if err != nil {
+		pack.RespError(c, err)
+		return
+	}
+	pack.RespData(c, packTokens(tokens))
+}
+
+// RefreshToken .
+// @router /api/v1/auth/refresh [POST]
+func RefreshToken(ctx context.Context, c *app.RequestContext) {
+	var err error
+	var req api.RefreshTokenRequest
+	err = c.BindAndValidate(&req)
+	if err != nil {
+		c.String(consts.StatusBadRequest, err.Error())
+		return
+	}
+
+	a := auth.Default()
+	if a == nil {
+		pack.RespError(c, errAuthDisabled)
+		return
+	}
+	tokens, err := a.Refresh(req.RefreshToken)
+	if err != nil {
+		pack.RespError(c, err)
+		return
+	}
+	pack.RespData(c, packTokens(tokens))
+}
+
+// CreateAPIKey .
+// @router /api/v1/auth/api-keys [POST]
+func CreateAPIKey(ctx context.Context, c *app.RequestContext) {
+	var err error
+	var req api.CreateAPIKeyRequest
+	err = c.BindAndValidate(&req)
+	if err != nil {
+		c.String(consts.StatusBadRequest, err.Error())
+		return
+	}
+
+	a := auth.Default()
+	if a == nil {
+		pack.RespError(c, errAuthDisabled)
+		return
+	}
+	if req.Name == "" {
+		pack.RespError(c, errno.ParamError.WithMessage("name 不能为空"))
+		return
+	}
+	key, secret, err := a.APIKeys().Create(mw.GetUserID(c), req.Name)
+	if err != nil {
+		pack.RespError(c, err)
+		return
+	}
+	pack.RespData(c, &api.CreateAPIKeyResponse{Key: packAPIKey(key), Secret: secret})
+}
+
+// ListAPIKeys .
+// @router /api/v1/auth/api-keys [GET]
+func ListAPIKeys(ctx context.Context, c *app.RequestContext) {
+	var err error
+	var req api.ListAPIKeysRequest
+	err = c.BindAndValidate(&req)
+	if err != nil {
+		c.String(consts.StatusBadRequest, err.Error())
+		return
+	}
+
+	a := auth.Default()
+	if a == nil {
+		pack.RespError(c, errAuthDisabled)
+		return
+	}
+	keys := a.APIKeys().List(mw.GetUserID(c))
+	resp := &api.ListAPIKeysResponse{Keys: make([]*api.APIKey, 0, len(keys))}
+	for i := range keys {
+		resp.Keys = append(resp.Keys, packAPIKey(&keys[i]))
+	}
+	pack.RespData(c, resp)
+}
+
+// RevokeAPIKey .
+// @router /api/v1/auth/api-keys/:id [DELETE]
+func RevokeAPIKey(ctx context.Context, c *app.RequestContext) {
+	var err error
+	var req api.RevokeAPIKeyRequest
+	err = c.BindAndValidate(&req)
+	if err != nil {
+		c.String(consts.StatusBadRequest, err.Error())
+		return
+	}
+
+	a := auth.Default()
+	if a == nil {
+		pack.RespError(c, errAuthDisabled)
+		return
+	}
+	if err = a.APIKeys().Revoke(mw.GetUserID(c), req.Id); err != nil {
+		pack.RespError(c, err)
 		return
 	}
+	pack.RespSuccess(c)
 }
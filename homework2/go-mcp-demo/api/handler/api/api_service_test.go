@@ -0,0 +1,69 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/FantasyRL/go-mcp-demo/config"
+	"github.com/FantasyRL/go-mcp-demo/pkg/base"
+	"github.com/FantasyRL/go-mcp-demo/pkg/base/ai_provider"
+	"github.com/FantasyRL/go-mcp-demo/pkg/constant"
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/openai/openai-go/v2"
+)
+
+type noTools struct{}
+
+func (noTools) ConvertToolsToOllama() []map[string]any                      { return nil }
+func (noTools) ConvertToolsToOpenAI() []openai.ChatCompletionToolUnionParam { return nil }
+func (noTools) CallTool(context.Context, string, any) (string, error)       { return "", nil }
+func (noTools) Close()                                                      {}
+
+// bufWriter 记录 SSE 写出的内容
+type bufWriter struct{ strings.Builder }
+
+func (w *bufWriter) Flush() error    { return nil }
+func (w *bufWriter) Finalize() error { return nil }
+
+func TestChatSSEEventNames(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, delta := range []string{`{"reasoning_content":"same"}`, `{"content":"same"}`} {
+			fmt.Fprintf(w, "data: {\"id\":\"1\",\"object\":\"chat.completion.chunk\",\"model\":\"m\",\"choices\":[{\"index\":0,\"delta\":%s}]}\n\n", delta)
+		}
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	defer srv.Close()
+	config.SetAiProvider(&config.AiProviderConfig{
+		Model: "remote/m",
+		Providers: map[string]config.AiProviderEndpoint{
+			"remote": {Type: constant.AiProviderTypeOpenAI, BaseURL: srv.URL, APIKey: "sk"},
+		},
+	})
+	defer func() { config.SetAiProvider(nil) }()
+	old := clientSet
+	clientSet = &base.ClientSet{MCPCli: noTools{}, AiProviderCli: ai_provider.NewAiProviderClient()}
+	defer func() { clientSet = old }()
+
+	c := app.NewContext(0)
+	c.Request.SetRequestURI("/api/v1/chat/sse?message=hi")
+	var out bufWriter
+	c.Response.HijackWriter(&out)
+	ChatSSE(context.Background(), c)
+
+	// 推理与回答的内容相同，只能靠事件名区分
+	got := out.String()
+	for _, want := range []string{
+		"event: " + constant.SSEEventReasoning + "\ndata: {\"text\":\"same\"}\n\n",
+		"event: " + constant.SSEEventDelta + "\ndata: {\"text\":\"same\"}\n\n",
+		"event: " + constant.SSEEventDone + "\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Fatalf("missing %q in:\n%s", want, got)
+		}
+	}
+}
@@ -0,0 +1,27 @@
+package api
+
+import (
+	api "github.com/FantasyRL/go-mcp-demo/api/model/api"
+	"github.com/FantasyRL/go-mcp-demo/internal/auth"
+	"github.com/FantasyRL/go-mcp-demo/pkg/errno"
+)
+
+var errAuthDisabled = errno.AuthError.WithMessage("未开启鉴权，请在配置中设置 auth.enable")
+
+func packTokens(t *auth.TokenPair) *api.TokenResponse {
+	return &api.TokenResponse{
+		AccessToken:  t.AccessToken,
+		RefreshToken: t.RefreshToken,
+		ExpiresIn:    t.ExpiresIn,
+	}
+}
+
+func packAPIKey(k *auth.APIKey) *api.APIKey {
+	return &api.APIKey{
+		Id:         k.ID,
+		Name:       k.Name,
+		Prefix:     k.Prefix,
+		CreatedAt:  k.CreatedAt,
+		LastUsedAt: k.LastUsedAt,
+	}
+}
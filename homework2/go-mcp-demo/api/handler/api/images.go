@@ -0,0 +1,42 @@
+package api
+
+import (
+	"io"
+	"strings"
+
+	"github.com/FantasyRL/go-mcp-demo/internal/host"
+	"github.com/FantasyRL/go-mcp-demo/pkg/errno"
+	"github.com/cloudwego/hertz/pkg/app"
+)
+
+// bindImages 收集请求中的图片并转为引用：inputs 为请求字段 images，multipart/form-data 请求中的 images 文件追加在后面
+func bindImages(c *app.RequestContext, inputs []string) ([]string, error) {
+	refs, err := host.Images().Normalize(inputs)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(string(c.ContentType()), "multipart/form-data") {
+		return refs, nil
+	}
+	form, err := c.MultipartForm()
+	if err != nil {
+		return nil, errno.ParamError.WithMessage(err.Error())
+	}
+	for _, fh := range form.File["images"] {
+		f, err := fh.Open()
+		if err != nil {
+			return nil, err
+		}
+		data, err := io.ReadAll(f)
+		_ = f.Close()
+		if err != nil {
+			return nil, err
+		}
+		ref, err := host.Images().Save(data)
+		if err != nil {
+			return nil, err
+		}
+		refs = append(refs, ref)
+	}
+	return refs, nil
+}
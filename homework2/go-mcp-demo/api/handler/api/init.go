@@ -1,6 +1,10 @@
 package api
 
 import (
+	"context"
+	"github.com/FantasyRL/go-mcp-demo/internal/host"
+	"github.com/FantasyRL/go-mcp-demo/internal/ratelimit"
+	"github.com/FantasyRL/go-mcp-demo/internal/usage"
 	"github.com/FantasyRL/go-mcp-demo/pkg/base"
 	"github.com/FantasyRL/go-mcp-demo/pkg/constant"
 )
@@ -10,3 +14,12 @@ var clientSet *base.ClientSet
 func Init() {
 	clientSet = base.NewClientSet(base.WithMCPClient([]string{constant.ServiceNameMCPLocal, constant.ServiceNameMCPRemote}), base.WithAiProviderClient())
 }
+
+// newHost 创建本次请求的 host，按用户汇总 token 用量，开启限流时同时计入每日配额
+func newHost(ctx context.Context) *host.Host {
+	h := host.NewHost(ctx, clientSet).WithUsageStore(usage.Default())
+	if l := ratelimit.Default(); l != nil {
+		h.WithUsageRecorder(l)
+	}
+	return h
+}
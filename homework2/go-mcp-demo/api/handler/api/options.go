@@ -0,0 +1,23 @@
+package api
+
+import (
+	"github.com/FantasyRL/go-mcp-demo/config"
+	"github.com/FantasyRL/go-mcp-demo/pkg/errno"
+)
+
+// bindOptions 请求中的生成参数，覆盖服务商配置的 options，取值范围与配置文件一致
+func bindOptions(temperature, topP *float64, maxTokens, seed *int64, stop []string) (config.OllamaOptions, error) {
+	o := config.OllamaOptions{Temperature: temperature, TopP: topP, Stop: stop}
+	if maxTokens != nil {
+		n := int(*maxTokens)
+		o.MaxTokens = &n
+	}
+	if seed != nil {
+		n := int(*seed)
+		o.Seed = &n
+	}
+	if err := o.Validate(); err != nil {
+		return o, errno.ParamError.WithMessage(err.Error())
+	}
+	return o, nil
+}
@@ -0,0 +1,41 @@
+package api
+
+import (
+	"time"
+
+	api "github.com/FantasyRL/go-mcp-demo/api/model/api"
+	"github.com/FantasyRL/go-mcp-demo/internal/usage"
+	"github.com/FantasyRL/go-mcp-demo/pkg/errno"
+)
+
+func packUsage(u usage.Usage) *api.Usage {
+	return &api.Usage{
+		PromptTokens:     u.PromptTokens,
+		CompletionTokens: u.CompletionTokens,
+		TotalTokens:      u.TotalTokens,
+		Cost:             u.Cost,
+	}
+}
+
+func packUsageRecord(r *usage.Record) *api.UsageRecord {
+	return &api.UsageRecord{
+		Day:              r.Day,
+		Model:            r.Model,
+		Calls:            r.Calls,
+		PromptTokens:     r.PromptTokens,
+		CompletionTokens: r.CompletionTokens,
+		TotalTokens:      r.TotalTokens,
+		Cost:             r.Cost,
+	}
+}
+
+// checkDay 校验查询用量的日期参数，为空表示不限制
+func checkDay(name, day string) error {
+	if day == "" {
+		return nil
+	}
+	if _, err := time.Parse(time.DateOnly, day); err != nil {
+		return errno.ParamError.WithMessage(name + " 需要是 YYYY-MM-DD 格式的日期")
+	}
+	return nil
+}
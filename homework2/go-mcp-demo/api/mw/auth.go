@@ -0,0 +1,65 @@
+package mw
+
+import (
+	"context"
+
+	"github.com/FantasyRL/go-mcp-demo/api/pack"
+	"github.com/FantasyRL/go-mcp-demo/internal/auth"
+	"github.com/FantasyRL/go-mcp-demo/pkg/constant"
+	"github.com/cloudwego/hertz/pkg/app"
+)
+
+// Auth 校验 Authorization: Bearer <access_token> 或 X-API-Key，并把用户 id 写入 RequestContext
+// 未开启鉴权时所有请求视为默认用户
+func Auth() app.HandlerFunc {
+	return authenticate(false)
+}
+
+// SSEAuth 同 Auth，另外允许通过 ?access_token= 携带访问令牌，只用于 /chat/sse：
+// 浏览器 EventSource 无法设置请求头，其他接口不接受 query 中的令牌，避免令牌出现在日志与 Referer 中
+func SSEAuth() app.HandlerFunc {
+	return authenticate(true)
+}
+
+func authenticate(allowQuery bool) app.HandlerFunc {
+	return func(ctx context.Context, c *app.RequestContext) {
+		a := auth.Default()
+		if a == nil {
+			c.Set(constant.AuthUserIDKey, constant.AuthDefaultUserID)
+			c.Next(ctx)
+			return
+		}
+		authorization := string(c.GetHeader("Authorization"))
+		if authorization == "" && allowQuery {
+			if token := c.Query(constant.AuthAccessTokenQuery); token != "" {
+				authorization = "Bearer " + token
+			}
+		}
+		id, err := a.Authenticate(authorization, string(c.GetHeader(constant.AuthAPIKeyHeader)))
+		if err != nil {
+			pack.RespError(c, err)
+			c.Abort()
+			return
+		}
+		c.Set(constant.AuthUserIDKey, id.UserID)
+		if id.APIKeyID != "" {
+			c.Set(constant.AuthAPIKeyIDKey, id.APIKeyID)
+		}
+		c.Next(ctx)
+	}
+}
+
+// GetUserID 返回 Auth 写入的用户 id
+func GetUserID(c *app.RequestContext) int64 {
+	if v, ok := c.Get(constant.AuthUserIDKey); ok {
+		if uid, ok := v.(int64); ok {
+			return uid
+		}
+	}
+	return constant.AuthDefaultUserID
+}
+
+// GetAPIKeyID 通过 API Key 认证时返回其 id，否则为空
+func GetAPIKeyID(c *app.RequestContext) string {
+	return c.GetString(constant.AuthAPIKeyIDKey)
+}
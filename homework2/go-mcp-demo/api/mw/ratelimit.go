@@ -0,0 +1,68 @@
+package mw
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/FantasyRL/go-mcp-demo/api/pack"
+	"github.com/FantasyRL/go-mcp-demo/internal/ratelimit"
+	"github.com/FantasyRL/go-mcp-demo/pkg/constant"
+	"github.com/cloudwego/hertz/pkg/app"
+)
+
+// RateLimit 按用户/API Key 限制每分钟请求数并检查每日 token 配额，需放在 Auth 之后
+func RateLimit() app.HandlerFunc {
+	return func(ctx context.Context, c *app.RequestContext) {
+		l := ratelimit.Default()
+		if l == nil {
+			c.Next(ctx)
+			return
+		}
+		st, err := l.Allow(GetUserID(c), GetAPIKeyID(c))
+		setRateLimitHeaders(c, st)
+		if err != nil {
+			if errors.Is(err, ratelimit.ErrTooManyRequests) {
+				retry := int(time.Until(st.Reset).Seconds()) + 1
+				c.Header(constant.RateLimitHeaderRetryAfter, strconv.Itoa(retry))
+			}
+			pack.RespError(c, err)
+			c.Abort()
+			return
+		}
+		c.Next(ctx)
+	}
+}
+
+// StreamLimit 限制同一用户/API Key 同时进行的流式对话数，需放在 Auth 之后
+func StreamLimit() app.HandlerFunc {
+	return func(ctx context.Context, c *app.RequestContext) {
+		l := ratelimit.Default()
+		if l == nil {
+			c.Next(ctx)
+			return
+		}
+		release, err := l.AcquireStream(GetUserID(c), GetAPIKeyID(c))
+		if err != nil {
+			pack.RespError(c, err)
+			c.Abort()
+			return
+		}
+		// SSE handler 在流结束后才返回
+		defer release()
+		c.Next(ctx)
+	}
+}
+
+func setRateLimitHeaders(c *app.RequestContext, st ratelimit.Status) {
+	if st.Limit >= 0 {
+		c.Header(constant.RateLimitHeaderLimit, strconv.Itoa(st.Limit))
+		c.Header(constant.RateLimitHeaderRemaining, strconv.Itoa(st.Remaining))
+		c.Header(constant.RateLimitHeaderReset, strconv.FormatInt(st.Reset.Unix(), 10))
+	}
+	if st.TokenLimit > 0 {
+		c.Header(constant.RateLimitHeaderTokensLimit, strconv.FormatInt(st.TokenLimit, 10))
+		c.Header(constant.RateLimitHeaderTokensRemaining, strconv.FormatInt(st.TokenRemaining, 10))
+	}
+}
@@ -0,0 +1,76 @@
+package mw
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/FantasyRL/go-mcp-demo/pkg/constant"
+	"github.com/FantasyRL/go-mcp-demo/pkg/logger"
+	sentinel "github.com/alibaba/sentinel-golang/api"
+	"github.com/alibaba/sentinel-golang/core/base"
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/protocol/consts"
+)
+
+// Sentinel 每个请求依次进入网关整体资源 "api" 与接口资源 "<METHOD>:<路由>"，如 "GET:/api/v1/chat/sse"
+// 资源在 handler 返回后才退出，因此 SSE 的并发隔离规则统计的是进行中的流；
+// handler 失败（见 handlerError）时在退出前记录错误，error_ratio / error_count 熔断规则据此生效
+func Sentinel() app.HandlerFunc {
+	return func(ctx context.Context, c *app.RequestContext) {
+		resources := []string{constant.SentinelResourceAPI}
+		if path := c.FullPath(); path != "" {
+			resources = append(resources, RouteResource(string(c.Method()), path))
+		}
+
+		entries := make([]*base.SentinelEntry, 0, len(resources))
+		defer func() {
+			// panic 由外层的 recovery 中间件处理，这里只记录为失败
+			r := recover()
+			err := handlerError(c)
+			if r != nil {
+				err = fmt.Errorf("panic: %v", r)
+			}
+			for i := len(entries) - 1; i >= 0; i-- {
+				if err != nil {
+					sentinel.TraceError(entries[i], err)
+				}
+				entries[i].Exit()
+			}
+			if r != nil {
+				panic(r)
+			}
+		}()
+		for _, res := range resources {
+			e, blockErr := sentinel.Entry(res,
+				sentinel.WithResourceType(base.ResTypeWeb),
+				sentinel.WithTrafficType(base.Inbound))
+			if blockErr != nil {
+				logger.Errorf("frequent requests have been rejected by the gateway. resource: %s, type: %s, clientIP: %v\n",
+					res, blockErr.BlockType(), c.ClientIP())
+				c.AbortWithStatusJSON(consts.StatusOK, map[string]interface{}{
+					"code":    500,
+					"message": "服务器当前处于请求高峰，请稍后再试",
+				})
+				return
+			}
+			entries = append(entries, e)
+		}
+		c.Next(ctx)
+	}
+}
+
+// handlerError 请求失败时返回原因：handler 通过 pack.RespError 返回了业务错误（HTTP 仍为 200），或响应状态码不是 2xx
+func handlerError(c *app.RequestContext) error {
+	if e := c.Errors.Last(); e != nil {
+		return e.Err
+	}
+	if code := c.Response.StatusCode(); code < 200 || code >= 300 {
+		return fmt.Errorf("http status %d", code)
+	}
+	return nil
+}
+
+// RouteResource 返回接口对应的 Sentinel 资源名
+func RouteResource(method, path string) string {
+	return method + ":" + path
+}
@@ -0,0 +1,51 @@
+package mw
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/FantasyRL/go-mcp-demo/api/pack"
+	"github.com/FantasyRL/go-mcp-demo/config"
+	"github.com/FantasyRL/go-mcp-demo/pkg/base/guard"
+	"github.com/cloudwego/hertz/pkg/app"
+	hzconfig "github.com/cloudwego/hertz/pkg/common/config"
+	"github.com/cloudwego/hertz/pkg/common/ut"
+	"github.com/cloudwego/hertz/pkg/route"
+)
+
+func TestSentinelRouteErrorBreaker(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Sentinel.CircuitBreaker = []config.SentinelCircuitBreakerRule{{
+		Resource:         RouteResource("GET", "/fail"),
+		Strategy:         "error_count",
+		Threshold:        2,
+		MinRequestAmount: 1,
+		StatInterval:     time.Minute,
+		RetryTimeout:     time.Minute,
+	}}
+	config.SetSentinel(&cfg.Sentinel)
+	defer func() {
+		config.SetSentinel(nil)
+		_ = guard.LoadRules()
+	}()
+	if err := guard.LoadRules(); err != nil {
+		t.Fatal(err)
+	}
+
+	calls := 0
+	e := route.NewEngine(hzconfig.NewOptions(nil))
+	e.Use(Sentinel())
+	// 业务错误以 HTTP 200 返回，同样计入失败
+	e.GET("/fail", func(ctx context.Context, c *app.RequestContext) {
+		calls++
+		pack.RespError(c, errors.New("upstream failed"))
+	})
+	for i := 0; i < 4; i++ {
+		ut.PerformRequest(e, "GET", "/fail", nil)
+	}
+	if calls != 2 {
+		t.Fatalf("handler called %d times, breaker should open after 2 failures", calls)
+	}
+}
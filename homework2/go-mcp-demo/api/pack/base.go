@@ -18,7 +18,9 @@ type RespWithData struct {
 	Data any    `json:"data"`
 }
 
+// RespError 以 HTTP 200 返回业务错误，同时把错误记在 c.Errors 中，供 Sentinel 等中间件统计失败
 func RespError(c *app.RequestContext, err error) {
+	_ = c.Error(err)
 	Errno := errno.ConvertErr(err)
 	c.JSON(consts.StatusOK, Base{
 		Code: strconv.FormatInt(Errno.ErrorCode, 10),
@@ -22,6 +22,16 @@ func Register(r *server.Hertz) {
 		{
 			_v1 := _api.Group("/v1", _v1Mw()...)
 			_v1.POST("/chat", append(_chat0Mw(), api.Chat)...)
+			_v1.GET("/models", append(_modelsMw(), api.ListModels)...)
+			_v1.POST("/images", append(_imagesMw(), api.UploadImages)...)
+			_v1.GET("/usage", append(_usageMw(), api.GetUsage)...)
+			_auth := _v1.Group("/auth", _authMw()...)
+			_auth.POST("/api-keys", append(_api_keysMw(), api.CreateAPIKey)...)
+			_auth.GET("/api-keys", append(_api_keys0Mw(), api.ListAPIKeys)...)
+			_api_keys := _auth.Group("/api-keys", _api_keys1Mw()...)
+			_api_keys.DELETE("/:id", append(_idMw(), api.RevokeAPIKey)...)
+			_auth.POST("/login", append(_loginMw(), api.Login)...)
+			_auth.POST("/refresh", append(_refreshMw(), api.RefreshToken)...)
 			_chat := _v1.Group("/chat", _chatMw()...)
 			_chat.GET("/sse", append(_chatsseMw(), api.ChatSSE)...)
 		}
@@ -2,7 +2,10 @@
 
 package api
 
-import "github.com/cloudwego/hertz/pkg/app"
+import (
+	"github.com/FantasyRL/go-mcp-demo/api/mw"
+	"github.com/cloudwego/hertz/pkg/app"
+)
 
 func rootMw() []app.HandlerFunc {
 	// your code...
@@ -25,11 +28,53 @@ func _chatMw() []app.HandlerFunc {
 }
 
 func _chat0Mw() []app.HandlerFunc {
+	return []app.HandlerFunc{mw.Auth(), mw.RateLimit()}
+}
+
+func _chatsseMw() []app.HandlerFunc {
+	return []app.HandlerFunc{mw.SSEAuth(), mw.RateLimit(), mw.StreamLimit()}
+}
+
+func _authMw() []app.HandlerFunc {
 	// your code...
 	return nil
 }
 
-func _chatsseMw() []app.HandlerFunc {
+func _api_keysMw() []app.HandlerFunc {
+	return []app.HandlerFunc{mw.Auth()}
+}
+
+func _api_keys0Mw() []app.HandlerFunc {
+	return []app.HandlerFunc{mw.Auth()}
+}
+
+func _api_keys1Mw() []app.HandlerFunc {
+	return []app.HandlerFunc{mw.Auth()}
+}
+
+func _idMw() []app.HandlerFunc {
+	// your code...
+	return nil
+}
+
+func _loginMw() []app.HandlerFunc {
+	// your code...
+	return nil
+}
+
+func _refreshMw() []app.HandlerFunc {
 	// your code...
 	return nil
 }
+
+func _modelsMw() []app.HandlerFunc {
+	return []app.HandlerFunc{mw.Auth()}
+}
+
+func _imagesMw() []app.HandlerFunc {
+	return []app.HandlerFunc{mw.Auth(), mw.RateLimit()}
+}
+
+func _usageMw() []app.HandlerFunc {
+	return []app.HandlerFunc{mw.Auth()}
+}
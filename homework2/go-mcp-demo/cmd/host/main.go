@@ -3,35 +3,61 @@ package main
 import (
 	"context"
 	"flag"
+	"fmt"
 	"github.com/FantasyRL/go-mcp-demo/api/handler/api"
+	"github.com/FantasyRL/go-mcp-demo/api/mw"
 	"github.com/FantasyRL/go-mcp-demo/api/router"
 	"github.com/FantasyRL/go-mcp-demo/config"
+	"github.com/FantasyRL/go-mcp-demo/internal/auth"
+	"github.com/FantasyRL/go-mcp-demo/internal/ratelimit"
+	"github.com/FantasyRL/go-mcp-demo/internal/usage"
+	"github.com/FantasyRL/go-mcp-demo/pkg/base/guard"
 	"github.com/FantasyRL/go-mcp-demo/pkg/constant"
 	"github.com/FantasyRL/go-mcp-demo/pkg/logger"
 	"github.com/FantasyRL/go-mcp-demo/pkg/utils"
+	"log"
+	"os"
+	"slices"
 	"time"
 
 	sentinel "github.com/alibaba/sentinel-golang/api"
-	"github.com/alibaba/sentinel-golang/core/flow"
 	"github.com/cloudwego/hertz/pkg/app"
 	"github.com/cloudwego/hertz/pkg/app/middlewares/server/recovery"
 	"github.com/cloudwego/hertz/pkg/app/server"
 	"github.com/cloudwego/hertz/pkg/protocol/consts"
 	"github.com/hertz-contrib/cors"
 	"github.com/hertz-contrib/gzip"
-	"github.com/hertz-contrib/opensergo/sentinel/adapter"
 )
 
 var (
-	serviceName = constant.ServiceNameAPI
-	configPath  = flag.String("cfg", "config/config.yaml", "config file path")
+	serviceName  = constant.ServiceNameAPI
+	configPath   = flag.String("cfg", "config/config.yaml", "config file path")
+	hashPassword = flag.String("hash-password", "", "print the hash of the given password for auth.users and exit")
 )
 
 func init() {
 	flag.Parse()
+	if *hashPassword != "" {
+		hash, err := auth.HashPassword(*hashPassword)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Println(hash)
+		os.Exit(0)
+	}
+	if flag.Arg(0) == "validate" {
+		// 仅校验配置：<binary> validate [-cfg config/config.yaml]
+		_ = flag.CommandLine.Parse(flag.Args()[1:])
+		os.Exit(config.ValidateCommand(*configPath, serviceName))
+	}
 	config.Load(*configPath, serviceName)
 	logger.Init(serviceName, config.GetLoggerLevel())
+	auth.Init()
+	ratelimit.Init()
+	usage.Init()
 	api.Init()
+	// 热更新模型、系统提示词、日志等级与 sentinel 规则
+	config.Watch()
 }
 
 func main() {
@@ -54,35 +80,49 @@ func main() {
 	h.Use(recovery.Recovery(recovery.WithRecoveryHandler(recoveryHandler)))
 
 	// Cors
-	h.Use(cors.New(cors.Config{
-		AllowOrigins:     []string{"*"},
-		AllowCredentials: true,
-		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"*"},
-		MaxAge:           12 * time.Hour,
-		ExposeHeaders:    []string{"Content-Length"},
-	}))
+	h.Use(cors.New(corsConfig()))
 
 	// gzip
 	h.Use(gzip.Gzip(gzip.BestSpeed))
 
 	// Sentinel
 	initSentinel()
-	h.Use(adapter.SentinelServerMiddleware(
-		adapter.WithServerResourceExtractor(func(c context.Context, ctx *app.RequestContext) string {
-			return "api"
-		}),
-		adapter.WithServerBlockFallback(func(ctx context.Context, c *app.RequestContext) {
-			logger.Errorf("frequent requests have been rejected by the gateway. clientIP: %v\n", c.ClientIP())
-			c.AbortWithStatusJSON(consts.StatusOK, map[string]interface{}{
-				"code":    500,
-				"message": "服务器当前处于请求高峰，请稍后再试",
-			})
-		}),
-	))
+	h.Use(mw.Sentinel())
 
 	router.Register(h)
 	h.Spin()
+
+	// Spin 在优雅退出、在途请求结束后返回，此时写入剩余的用量记录
+	if err := usage.Default().Close(); err != nil {
+		logger.Errorf("Api: save usage failed, err: %v", err)
+	}
+}
+
+// corsConfig 未配置 server.cors-origins 或包含 "*" 时允许任意来源，但不允许携带凭证；
+// 否则只允许配置的来源并允许携带凭证
+func corsConfig() cors.Config {
+	c := cors.Config{
+		AllowMethods: []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
+		AllowHeaders: []string{"Origin", "Content-Type", "Authorization", constant.AuthAPIKeyHeader},
+		MaxAge:       12 * time.Hour,
+		ExposeHeaders: []string{
+			"Content-Length",
+			constant.RateLimitHeaderLimit,
+			constant.RateLimitHeaderRemaining,
+			constant.RateLimitHeaderReset,
+			constant.RateLimitHeaderTokensLimit,
+			constant.RateLimitHeaderTokensRemaining,
+			constant.RateLimitHeaderRetryAfter,
+		},
+	}
+	origins := config.Server().CORSOrigins
+	if len(origins) == 0 || slices.Contains(origins, "*") {
+		c.AllowAllOrigins = true
+		return c
+	}
+	c.AllowOrigins = origins
+	c.AllowCredentials = true
+	return c
 }
 
 func recoveryHandler(ctx context.Context, c *app.RequestContext, err interface{}, stack []byte) {
@@ -99,18 +139,10 @@ func initSentinel() {
 		logger.Fatalf("Unexpected error: %+v", err)
 	}
 
-	// limit QPS to 100
-	_, err = flow.LoadRules([]*flow.Rule{
-		{
-			Resource:               "api",
-			Threshold:              100,
-			TokenCalculateStrategy: flow.Direct,
-			ControlBehavior:        flow.Reject,
-			StatIntervalInMs:       1000,
-		},
-	})
-	if err != nil {
+	// 规则来自 sentinel 配置，配置文件修改后自动重新加载
+	if err = guard.LoadRules(); err != nil {
 		logger.Fatalf("Unexpected error: %+v", err)
 		return
 	}
+	guard.WatchRules()
 }
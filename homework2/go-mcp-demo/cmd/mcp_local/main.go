@@ -11,6 +11,7 @@ import (
 	"github.com/FantasyRL/go-mcp-demo/pkg/constant"
 	"github.com/FantasyRL/go-mcp-demo/pkg/logger"
 	"github.com/FantasyRL/go-mcp-demo/pkg/utils"
+	"os"
 )
 
 var (
@@ -22,8 +23,14 @@ var (
 
 func init() {
 	flag.Parse()
+	if flag.Arg(0) == "validate" {
+		// 仅校验配置：<binary> validate [-cfg config/config.yaml]
+		_ = flag.CommandLine.Parse(flag.Args()[1:])
+		os.Exit(config.ValidateCommand(*configPath, serviceName))
+	}
 	config.Load(*configPath, serviceName)
 	logger.Init(serviceName, config.GetLoggerLevel())
+	config.Watch()
 	mcp_local.InjectDependencies()
 	toolSet = tool_set.NewToolSet(mcp_inject.WithLongRunningOperationTool(),
 		mcp_inject.WithDevRunnerTools(),
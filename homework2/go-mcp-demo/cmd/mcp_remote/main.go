@@ -9,6 +9,7 @@ import (
 	"github.com/FantasyRL/go-mcp-demo/pkg/constant"
 	"github.com/FantasyRL/go-mcp-demo/pkg/logger"
 	"github.com/FantasyRL/go-mcp-demo/pkg/utils"
+	"os"
 )
 
 var (
@@ -19,8 +20,14 @@ var (
 
 func init() {
 	flag.Parse()
+	if flag.Arg(0) == "validate" {
+		// 仅校验配置：<binary> validate [-cfg config/config.yaml]
+		_ = flag.CommandLine.Parse(flag.Args()[1:])
+		os.Exit(config.ValidateCommand(*configPath, serviceName))
+	}
 	config.Load(*configPath, serviceName)
 	logger.Init(serviceName, config.GetLoggerLevel())
+	config.Watch()
 	toolSet = tool_set.NewToolSet(mcp_inject.WithTimeTool())
 }
 
@@ -0,0 +1,81 @@
+package config
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/FantasyRL/go-mcp-demo/pkg/constant"
+)
+
+// Endpoints 返回所有模型服务商；未配置 providers 时按 mode 由旧配置生成一个
+func (c *AiProviderConfig) Endpoints() map[string]AiProviderEndpoint {
+	if len(c.Providers) > 0 {
+		return c.Providers
+	}
+	switch c.Mode {
+	case constant.AiProviderModeLocal:
+		return map[string]AiProviderEndpoint{c.Mode: {
+			Type:    constant.AiProviderTypeOllama,
+			BaseURL: c.BaseURL,
+			Options: c.Options,
+		}}
+	case constant.AiProviderModeRemote:
+		return map[string]AiProviderEndpoint{c.Mode: {
+			Type:    constant.AiProviderTypeOpenAI,
+			BaseURL: c.Remote.BaseURL,
+			APIKey:  c.Remote.APIKey,
+			Options: c.Options,
+		}}
+	}
+	return nil
+}
+
+// ResolveModel 将模型引用解析为服务商名与模型名，ref 为空时使用默认模型：
+// 1. "服务商/模型名"：服务商存在时直接使用（模型名本身可以包含 "/"）
+// 2. 模型名：使用 models 中包含该模型的服务商（按服务商名排序取第一个）
+// 3. 只有一个服务商时，任意模型名都交给它
+// 服务商配置了 models 时只允许使用其中的模型
+func (c *AiProviderConfig) ResolveModel(ref string) (provider string, model string, err error) {
+	if ref == "" {
+		ref = c.Model
+	}
+	if ref == "" {
+		return "", "", fmt.Errorf("no model specified and ai_provider.model is empty")
+	}
+	endpoints := c.Endpoints()
+
+	if p, m, ok := strings.Cut(ref, constant.AiProviderModelSeparator); ok {
+		if ep, exists := endpoints[p]; exists {
+			if len(ep.Models) > 0 && !slices.Contains(ep.Models, m) {
+				return "", "", fmt.Errorf("model %q is not enabled for provider %q", m, p)
+			}
+			return p, m, nil
+		}
+	}
+
+	names := make([]string, 0, len(endpoints))
+	for name := range endpoints {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if slices.Contains(endpoints[name].Models, ref) {
+			return name, ref, nil
+		}
+	}
+	if len(names) == 1 && len(endpoints[names[0]].Models) == 0 {
+		return names[0], ref, nil
+	}
+	return "", "", fmt.Errorf("unknown model %q", ref)
+}
+
+// ModelForTool 返回工具内部调用模型时使用的模型引用，未单独配置时使用默认模型
+func (c *AiProviderConfig) ModelForTool(tool string) string {
+	// viper 会把 map 的 key 转为小写
+	if m := c.ToolModels[strings.ToLower(tool)]; m != "" {
+		return m
+	}
+	return c.Model
+}
@@ -1,49 +1,149 @@
 package config
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
 	"github.com/spf13/viper"
 	"github.com/west2-online/fzuhelper-server/pkg/constants"
 	"log"
+	"os"
+	"sync/atomic"
 )
 
 var (
-	AiProvider   *AiProviderConfig
-	CLI          *cliConfig
 	MCP          *mcpConfig
-	Server       *server
 	Registry     *registryConfig
+	Auth         *authConfig
+	RateLimit    *rateLimitConfig
 	Service      *service
 	runtimeViper = viper.New()
+
+	// 可以热更新的配置，热更新时整体替换，通过同名函数读取
+	aiProviderCfg atomic.Pointer[AiProviderConfig]
+	cliCfg        atomic.Pointer[cliConfig]
+	serverCfg     atomic.Pointer[server]
+	sentinelCfg   atomic.Pointer[sentinelConfig]
+
+	loaded      *Config // 最近一次成功解析的配置文件内容
+	serviceName string
 )
 
+// Load 读取配置文件并解析到包级变量，配置不合法时直接退出
+// 配置文件中可以使用 ${VAR} / ${VAR:-默认值} 引用环境变量，任意配置项也可以被 MCPDEMO_ 前缀的环境变量覆盖（见 EnvPrefix）
 func Load(path string, srv string) {
 	runtimeViper.SetConfigFile(path)
 	runtimeViper.SetConfigType("yaml")
+	bindEnvs(runtimeViper)
 
-	if err := runtimeViper.ReadInConfig(); err != nil {
-		log.Fatal(err)
-		return
-	}
-	cfg := new(Config)
-	if err := runtimeViper.Unmarshal(&cfg); err != nil {
-		log.Fatal(err)
+	cfg, err := loadConfig(runtimeViper, srv)
+	if err != nil {
+		log.Fatalf("config: %s: %v", path, err)
 		return
 	}
 
-	AiProvider = &cfg.AiProvider
-	CLI = &cfg.CLI
+	loaded = cfg
+	serviceName = srv
+	SetAiProvider(&cfg.AiProvider)
+	SetCLI(&cfg.CLI)
 	MCP = &cfg.MCP
-	Server = &cfg.Server
+	SetServer(&cfg.Server)
 	Registry = &cfg.Registry
+	Auth = &cfg.Auth
+	RateLimit = &cfg.RateLimit
+	SetSentinel(&cfg.Sentinel)
 	Service = getService(srv)
 }
 
+// AiProvider 返回当前的 ai_provider 配置，未加载时为 nil
+// 返回值不会被修改，热更新会替换成新的配置，同一个请求内应只读取一次，避免前后使用不同版本的配置
+func AiProvider() *AiProviderConfig { return aiProviderCfg.Load() }
+
+// CLI 返回当前的 cli 配置，未加载时为 nil，用法同 AiProvider
+func CLI() *cliConfig { return cliCfg.Load() }
+
+// Server 返回当前的 server 配置，未加载时为 nil，用法同 AiProvider
+func Server() *server { return serverCfg.Load() }
+
+// Sentinel 返回当前的 sentinel 配置，未加载时为 nil，用法同 AiProvider
+func Sentinel() *sentinelConfig { return sentinelCfg.Load() }
+
+// SetAiProvider 替换 ai_provider 配置，用于热更新与测试；c 设置后不能再修改
+func SetAiProvider(c *AiProviderConfig) { aiProviderCfg.Store(c) }
+
+// SetCLI 替换 cli 配置，用法同 SetAiProvider
+func SetCLI(c *cliConfig) { cliCfg.Store(c) }
+
+// SetServer 替换 server 配置，用法同 SetAiProvider
+func SetServer(c *server) { serverCfg.Store(c) }
+
+// SetSentinel 替换 sentinel 配置，用法同 SetAiProvider
+func SetSentinel(c *sentinelConfig) { sentinelCfg.Store(c) }
+
+// loadConfig 读取 v 对应的配置文件：替换环境变量引用 -> 按 schema 校验文件内容 -> 解析（叠加环境变量覆盖）-> 按服务校验
+// 配置问题汇总为一个 ValidationError 返回
+func loadConfig(v *viper.Viper, srv string) (*Config, error) {
+	content, err := os.ReadFile(v.ConfigFileUsed())
+	if err != nil {
+		return nil, err
+	}
+	if content, err = expandEnv(content); err != nil {
+		return nil, err
+	}
+	if err = v.ReadConfig(bytes.NewReader(content)); err != nil {
+		return nil, err
+	}
+	errs, err := validateSchema(content)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := new(Config)
+	if err := v.Unmarshal(cfg); err != nil {
+		if len(errs) > 0 {
+			// 类型错误已经由 schema 给出更具体的位置
+			return nil, errs
+		}
+		return nil, err
+	}
+	if err := cfg.Validate(srv); err != nil {
+		var ve ValidationError
+		errors.As(err, &ve)
+		errs = errs.merge(ve)
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return cfg, nil
+}
+
+// ValidateFile 校验配置文件（包括环境变量覆盖），不修改包级变量
+func ValidateFile(path string, srv string) error {
+	v := viper.New()
+	v.SetConfigFile(path)
+	v.SetConfigType("yaml")
+	bindEnvs(v)
+	_, err := loadConfig(v, srv)
+	return err
+}
+
+// ValidateCommand 各服务 validate 子命令的实现：打印全部配置问题，返回进程退出码
+func ValidateCommand(path string, srv string) int {
+	if err := ValidateFile(path, srv); err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", path, err)
+		return 1
+	}
+	fmt.Printf("%s: ok\n", path)
+	return 0
+}
+
 // GetLoggerLevel 会返回服务的日志等级
 func GetLoggerLevel() string {
-	if Server == nil {
+	srv := Server()
+	if srv == nil {
 		return constants.DefaultLogLevel
 	}
-	return Server.LogLevel
+	return srv.LogLevel
 }
 
 func getService(name string) *service {
@@ -0,0 +1,290 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/FantasyRL/go-mcp-demo/pkg/constant"
+	"github.com/spf13/viper"
+)
+
+const testYAML = `
+# 注释中的 ${NOT_SET} 不会被替换
+server:
+  log-level: "INFO"
+  private-key: "pbkdf2$1$abc"
+ai_provider:
+  mode: "remote"
+  model: "${TEST_MODEL:-deepseek-chat}"
+  remote:
+    base_url: "https://api.deepseek.com/v1"
+    api_key: "${TEST_API_KEY}"
+mcp:
+  transport: "http"
+  http:
+    base_url: "http://127.0.0.1:10002/mcp"
+`
+
+func TestExpandEnv(t *testing.T) {
+	t.Setenv("TEST_API_KEY", "sk-file")
+
+	out, err := expandEnv([]byte(testYAML))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := string(out)
+	for _, want := range []string{`model: "deepseek-chat"`, `api_key: "sk-file"`, `private-key: "pbkdf2$1$abc"`} {
+		if !strings.Contains(s, want) {
+			t.Fatalf("missing %s in:\n%s", want, s)
+		}
+	}
+
+	_, err = expandEnv([]byte(`a: "${TEST_UNSET_A}" b: "${TEST_UNSET_B}"`))
+	if err == nil || !strings.Contains(err.Error(), "TEST_UNSET_A, TEST_UNSET_B") {
+		t.Fatalf("expected missing variables error, got %v", err)
+	}
+}
+
+func TestEnvOverride(t *testing.T) {
+	t.Setenv("TEST_API_KEY", "sk-file")
+	t.Setenv("MCPDEMO_AI_PROVIDER_REMOTE_API_KEY", "sk-env")
+	t.Setenv("MCPDEMO_SERVER_LOG_LEVEL", "debug")
+	t.Setenv("MCPDEMO_RATE_LIMIT_USER_REQUESTS_PER_MINUTE", "12") // 配置文件中没有的 key
+
+	content, err := expandEnv([]byte(testYAML))
+	if err != nil {
+		t.Fatal(err)
+	}
+	v := viper.New()
+	v.SetConfigType("yaml")
+	bindEnvs(v)
+	if err := v.ReadConfig(bytes.NewReader(content)); err != nil {
+		t.Fatal(err)
+	}
+	cfg := new(Config)
+	if err := v.Unmarshal(cfg); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.AiProvider.Remote.APIKey != "sk-env" || cfg.Server.LogLevel != "debug" || cfg.RateLimit.User.RequestsPerMinute != 12 {
+		t.Fatalf("env not applied: %+v %+v %+v", cfg.AiProvider.Remote, cfg.Server, cfg.RateLimit.User)
+	}
+	if cfg.AiProvider.Model != "deepseek-chat" {
+		t.Fatalf("model = %q", cfg.AiProvider.Model)
+	}
+	if err := cfg.Validate(constant.ServiceNameAPI); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	temp := 3.0
+	cfg := &Config{}
+	cfg.Server.LogLevel = "verbose"
+	cfg.AiProvider.Mode = constant.AiProviderModeRemote
+	cfg.AiProvider.Remote.BaseURL = "api.deepseek.com"
+	cfg.AiProvider.Options.Temperature = &temp
+	cfg.Auth.Enable = true
+
+	err := cfg.Validate(constant.ServiceNameAPI)
+	var ve ValidationError
+	if !errors.As(err, &ve) {
+		t.Fatalf("expected ValidationError, got %v", err)
+	}
+	var fields []string
+	for _, fe := range ve {
+		fields = append(fields, fe.Field)
+	}
+	for _, want := range []string{
+		"server.log-level", "ai_provider.model", "ai_provider.remote.base_url", "ai_provider.remote.api_key",
+		"ai_provider.options.temperature", "server.private-key", "auth.users",
+	} {
+		if !slices.Contains(fields, want) {
+			t.Errorf("missing error for %s, got %v", want, fields)
+		}
+	}
+
+	// mcp_remote 不需要模型配置
+	cfg = &Config{}
+	cfg.MCP.Transport = constant.MCPTransportHTTP
+	if err := cfg.Validate(constant.ServiceNameMCPRemote); err != nil {
+		t.Fatal(err)
+	}
+
+	// host 直连 MCP 时需要 base_url
+	cfg.Registry.Provider = constant.RegistryProviderNone
+	err = cfg.Validate(constant.ServiceNameAPI)
+	if !errors.As(err, &ve) || !slices.ContainsFunc(ve, func(fe FieldError) bool { return fe.Field == "mcp.http.base_url" }) {
+		t.Fatalf("expected mcp.http.base_url error, got %v", err)
+	}
+}
+
+func TestValidateSchema(t *testing.T) {
+	errs, err := validateSchema([]byte(`
+ollama:
+  model: "qwen3:1.7b"
+server:
+  log-level: "LOUD"
+ai_provider:
+  mode: "cloud"
+  options:
+    temperature: "hot"
+    request_timeout: "30 seconds"
+mcp:
+  transport: "http"
+sentinel:
+  flow:
+    - threshold: 10
+services:
+  host:
+    addr: []
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"ai_provider.mode", "ai_provider.options.request_timeout", "ai_provider.options.temperature",
+		"ollama", "sentinel.flow[0].resource", "server.log-level", "services.host.addr",
+	}
+	var got []string
+	for _, fe := range errs {
+		got = append(got, fe.Field)
+	}
+	if !slices.Equal(got, want) {
+		t.Fatalf("schema errors = %v", errs)
+	}
+
+	// 示例配置需要始终符合 schema
+	for _, path := range []string{"config.example.yaml", "config.stdio.example.yaml"} {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if content, err = expandEnv(content); err != nil {
+			t.Fatal(err)
+		}
+		if errs, err := validateSchema(content); err != nil || len(errs) > 0 {
+			t.Errorf("%s: %v %v", path, err, errs)
+		}
+	}
+}
+
+func TestRestartRequired(t *testing.T) {
+	old := &Config{}
+	old.AiProvider.Model = "a"
+	old.Registry.Provider = constant.RegistryProviderConsul
+
+	cur := *old
+	cur.AiProvider.Model = "b"
+	cur.CLI.SystemPrompt = "hi"
+	if fields := restartRequired(old, &cur); len(fields) != 0 {
+		t.Fatalf("hot fields reported: %v", fields)
+	}
+
+	cur.Registry.Provider = constant.RegistryProviderEtcd
+	cur.AiProvider.Remote.APIKey = "sk"
+	if fields := restartRequired(old, &cur); !slices.Equal(fields, []string{"ai_provider", "registry"}) {
+		t.Fatalf("fields = %v", fields)
+	}
+}
+
+func TestApplyWhileReading(t *testing.T) {
+	base := &Config{}
+	base.AiProvider.Model = "a"
+	base.AiProvider.Options.RequestTimout = time.Minute
+	base.Server.LogLevel = "info"
+	SetAiProvider(&base.AiProvider)
+	SetCLI(&base.CLI)
+	SetServer(&base.Server)
+	SetSentinel(&base.Sentinel)
+	t.Cleanup(func() {
+		SetAiProvider(nil)
+		SetCLI(nil)
+		SetServer(nil)
+		SetSentinel(nil)
+	})
+
+	// 读者与热更新并发，配合 -race 检查
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				ai := AiProvider()
+				if ai.Model != "a" && ai.Model != "b" {
+					t.Errorf("model = %q", ai.Model)
+					return
+				}
+				_ = CLI().SystemPrompt
+				_ = GetLoggerLevel()
+				_ = Sentinel().Flow
+			}
+		}()
+	}
+	for i := 0; i < 100; i++ {
+		next := &Config{}
+		next.AiProvider.Model = []string{"a", "b"}[i%2]
+		next.CLI.SystemPrompt = "p"
+		next.Server.LogLevel = []string{"info", "debug"}[i%2]
+		apply(next)
+	}
+	close(stop)
+	wg.Wait()
+
+	// 热更新保留启动时确定的请求超时
+	if got := AiProvider(); got.Model != "b" || got.Options.RequestTimout != time.Minute {
+		t.Fatalf("ai_provider = %+v", got)
+	}
+	if GetLoggerLevel() != "debug" || CLI().SystemPrompt != "p" {
+		t.Fatalf("log level = %q, system prompt = %q", GetLoggerLevel(), CLI().SystemPrompt)
+	}
+}
+
+func TestResolveModel(t *testing.T) {
+	ai := &AiProviderConfig{
+		Model: "deepseek/deepseek-chat",
+		Providers: map[string]AiProviderEndpoint{
+			"deepseek": {Type: constant.AiProviderTypeOpenAI, Models: []string{"deepseek-chat", "deepseek-reasoner"}},
+			"ollama":   {Type: constant.AiProviderTypeOllama, Models: []string{"qwen3:1.7b", "library/qwen3:8b"}},
+		},
+		ToolModels: map[string]string{"solver": "qwen3:1.7b"},
+	}
+	for ref, want := range map[string][2]string{
+		"":                        {"deepseek", "deepseek-chat"},
+		"deepseek-reasoner":       {"deepseek", "deepseek-reasoner"},
+		"ollama/library/qwen3:8b": {"ollama", "library/qwen3:8b"},
+		"library/qwen3:8b":        {"ollama", "library/qwen3:8b"},
+		ai.ModelForTool("Solver"): {"ollama", "qwen3:1.7b"},
+	} {
+		p, m, err := ai.ResolveModel(ref)
+		if err != nil || p != want[0] || m != want[1] {
+			t.Errorf("ResolveModel(%q) = %s, %s, %v, want %v", ref, p, m, err, want)
+		}
+	}
+	for _, ref := range []string{"gpt-4o", "ollama/deepseek-chat", "openai/gpt-4o"} {
+		if _, _, err := ai.ResolveModel(ref); err == nil {
+			t.Errorf("ResolveModel(%q) should fail", ref)
+		}
+	}
+
+	// 旧配置按 mode 生成单个服务商，不限制模型名
+	legacy := &AiProviderConfig{Mode: constant.AiProviderModeLocal, BaseURL: "http://127.0.0.1:11434", Model: "qwen3:1.7b"}
+	if p, m, err := legacy.ResolveModel("llama3"); err != nil || p != constant.AiProviderModeLocal || m != "llama3" {
+		t.Fatalf("legacy ResolveModel = %s, %s, %v", p, m, err)
+	}
+	if legacy.ModelForTool("solver") != "qwen3:1.7b" {
+		t.Fatal("ModelForTool should fall back to model")
+	}
+}
@@ -0,0 +1,88 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// EnvPrefix 环境变量前缀，任意配置项都可以用环境变量覆盖：
+// 去掉层级中的 "." 与 "-"，统一换成 "_" 并转为大写，例如
+// ai_provider.remote.api_key -> MCPDEMO_AI_PROVIDER_REMOTE_API_KEY
+// server.log-level           -> MCPDEMO_SERVER_LOG_LEVEL
+// 切片类型使用逗号分隔，如 MCPDEMO_SERVER_CORS_ORIGINS=http://a.com,http://b.com
+const EnvPrefix = "MCPDEMO"
+
+// envRef 匹配配置文件中的 ${VAR} 与 ${VAR:-默认值}；不处理 $VAR，避免误替换密码哈希等包含 $ 的值
+var envRef = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)(:-([^}]*))?\}`)
+
+// expandEnv 替换配置文件内容中的环境变量引用（跳过注释行），未设置且没有默认值的变量会返回错误
+func expandEnv(content []byte) ([]byte, error) {
+	missing := map[string]struct{}{}
+	lines := bytes.SplitAfter(content, []byte("\n"))
+	for i, line := range lines {
+		if bytes.HasPrefix(bytes.TrimSpace(line), []byte("#")) {
+			continue
+		}
+		lines[i] = envRef.ReplaceAllFunc(line, func(ref []byte) []byte {
+			m := envRef.FindSubmatch(ref)
+			if v, ok := os.LookupEnv(string(m[1])); ok {
+				return []byte(v)
+			}
+			if len(m[2]) > 0 {
+				return m[3]
+			}
+			missing[string(m[1])] = struct{}{}
+			return nil
+		})
+	}
+	if len(missing) > 0 {
+		names := make([]string, 0, len(missing))
+		for name := range missing {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("environment variable %s referenced in config is not set (use ${NAME:-default} to make it optional)",
+			strings.Join(names, ", "))
+	}
+	return bytes.Join(lines, nil), nil
+}
+
+// bindEnvs 为 Config 中的每一个配置项绑定环境变量
+// viper 的 AutomaticEnv 只对配置文件中已经出现的 key 生效，Unmarshal 前需要显式 BindEnv
+func bindEnvs(v *viper.Viper) {
+	v.SetEnvPrefix(EnvPrefix)
+	v.SetEnvKeyReplacer(strings.NewReplacer(".", "_", "-", "_"))
+	v.AutomaticEnv()
+	for _, key := range configKeys(reflect.TypeOf(Config{}), "") {
+		_ = v.BindEnv(key)
+	}
+}
+
+// configKeys 按 mapstructure tag 展开结构体的所有叶子 key
+func configKeys(t reflect.Type, prefix string) []string {
+	var keys []string
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name := f.Tag.Get("mapstructure")
+		if name == "" {
+			name = strings.ToLower(f.Name)
+		}
+		key := prefix + name
+		if ft := f.Type; ft.Kind() == reflect.Struct {
+			keys = append(keys, configKeys(ft, key+".")...)
+			continue
+		}
+		keys = append(keys, key)
+	}
+	return keys
+}
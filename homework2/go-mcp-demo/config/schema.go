@@ -0,0 +1,40 @@
+package config
+
+import (
+	"bytes"
+	_ "embed"
+	"sync"
+
+	"github.com/FantasyRL/go-mcp-demo/pkg/utils/jsonschema"
+	"github.com/spf13/viper"
+)
+
+//go:embed config.schema.json
+var schemaJSON []byte
+
+var configSchema = sync.OnceValue(func() *jsonschema.Schema {
+	s, err := jsonschema.Parse(schemaJSON)
+	if err != nil {
+		panic(err)
+	}
+	return s
+})
+
+// Schema 返回 config.yaml 的 JSON Schema
+func Schema() []byte {
+	return schemaJSON
+}
+
+// validateSchema 按 schema 校验配置文件内容（不含环境变量覆盖），可以发现拼错或已废弃的配置项
+func validateSchema(content []byte) (ValidationError, error) {
+	raw := viper.New()
+	raw.SetConfigType("yaml")
+	if err := raw.ReadConfig(bytes.NewReader(content)); err != nil {
+		return nil, err
+	}
+	var errs ValidationError
+	for _, e := range configSchema().Validate(raw.AllSettings()) {
+		errs = append(errs, FieldError{Field: e.Path, Msg: e.Msg})
+	}
+	return errs, nil
+}
@@ -5,28 +5,69 @@ import (
 )
 
 type server struct {
-	Secret   string `mapstructure:"private-key"`
-	Version  string
-	Name     string
-	LogLevel string `mapstructure:"log-level"`
+	Secret      string `mapstructure:"private-key"`
+	Version     string
+	Name        string
+	LogLevel    string   `mapstructure:"log-level"`
+	CORSOrigins []string `mapstructure:"cors-origins"` // 允许跨域的来源，为空或包含 "*" 时允许任意来源但不允许携带凭证
 }
 
+// OllamaOptions 生成参数，各服务商协议的转换见 ai_provider.BuildOptions / ai_provider.ApplyOpenAIOptions
 type OllamaOptions struct {
 	Temperature   *float64       `mapstructure:"temperature"`
 	TopP          *float64       `mapstructure:"top_p"`
 	TopK          *int           `mapstructure:"top_k"`
 	MaxTokens     *int           `mapstructure:"max_tokens"`
+	Seed          *int           `mapstructure:"seed"`
+	Stop          []string       `mapstructure:"stop"`
 	Extra         map[string]any `mapstructure:"extra"` // 透传到 options
 	KeepAlive     string         `mapstructure:"keep_alive"`
 	RequestTimout time.Duration  `mapstructure:"request_timeout"`
 }
 
+// AiProviderConfig 模型配置
+// - Providers 非空时使用多个命名的服务商，忽略 Mode/BaseURL/Remote/Options
+// - Providers 为空时按 Mode 生成一个名为 "local" 或 "remote" 的服务商，兼容旧配置
+// 模型使用 "服务商/模型名" 或单独的模型名引用，见 ResolveModel
 type AiProviderConfig struct {
-	Mode    string                 `mapstructure:"mode"`
-	BaseURL string                 `mapstructure:"base_url"` // e.g. http://127.0.0.1:11434
-	Model   string                 `mapstructure:"model"`    // e.g. qwen3:1.7b
-	Remote  AiProviderRemoteConfig `mapstructure:"remote"`
-	Options OllamaOptions          `mapstructure:"options"`
+	Mode           string                        `mapstructure:"mode"`
+	BaseURL        string                        `mapstructure:"base_url"` // e.g. http://127.0.0.1:11434
+	Model          string                        `mapstructure:"model"`    // 默认模型 e.g. qwen3:1.7b、deepseek/deepseek-chat
+	Remote         AiProviderRemoteConfig        `mapstructure:"remote"`
+	Options        OllamaOptions                 `mapstructure:"options"`
+	Providers      map[string]AiProviderEndpoint `mapstructure:"providers"`       // 服务商名 -> 配置，名称请使用小写
+	ToolModels     map[string]string             `mapstructure:"tool_models"`     // 工具名 -> 该工具内部调用的模型，未配置时使用 Model
+	EmbeddingModel string                        `mapstructure:"embedding_model"` // ai_provider.Client.Embed 默认使用的向量模型，仅支持 ollama 与 openai 服务商
+	Fallback       []string                      `mapstructure:"fallback"`        // 请求的模型不可用时依次尝试的模型引用
+	Retry          AiProviderRetryConfig         `mapstructure:"retry"`
+	Prices         []ModelPrice                  `mapstructure:"prices"` // 用于估算费用，未配置的模型费用为 0
+}
+
+// ModelPrice 模型价格，单位为每百万 token 的美元
+// 模型名中常有 "."（如 gpt-4.1），不能作为 viper 的 map key，因此使用列表
+type ModelPrice struct {
+	Model  string  `mapstructure:"model"` // 模型引用 "服务商/模型名" 或模型名，前者优先
+	Input  float64 `mapstructure:"input"`
+	Output float64 `mapstructure:"output"`
+}
+
+// AiProviderRetryConfig 调用模型失败时的重试策略，仅在 429/5xx/网络错误且尚未输出内容时重试
+type AiProviderRetryConfig struct {
+	MaxRetries int           `mapstructure:"max_retries"` // 同一服务商的重试次数，0 表示不重试，直接切换到 fallback
+	Backoff    time.Duration `mapstructure:"backoff"`     // 首次重试前的等待时间，之后每次翻倍，默认 500ms
+	MaxBackoff time.Duration `mapstructure:"max_backoff"` // 单次等待上限（包括 Retry-After），默认 5s
+}
+
+// AiProviderEndpoint 一个命名的模型服务商
+type AiProviderEndpoint struct {
+	Type    string        `mapstructure:"type"`     // "ollama" | "openai"(OpenAI 兼容接口) | "anthropic"(Messages API) | "gemini"(generateContent)
+	BaseURL string        `mapstructure:"base_url"` // ollama 填服务地址，openai 填到 /v1，anthropic 填 https://api.anthropic.com，gemini 填 https://generativelanguage.googleapis.com
+	APIKey  string        `mapstructure:"api_key"`
+	Models  []string      `mapstructure:"models"` // 允许使用的模型，为空时不限制
+	Options OllamaOptions `mapstructure:"options"`
+	// 仅 openai 服务商："chat_completions"(默认) | "responses"，使用 responses 时对话走 Responses API
+	API          string   `mapstructure:"api"`
+	BuiltinTools []string `mapstructure:"builtin_tools"` // 仅 api 为 responses 时有效："web_search" | "code_interpreter"
 }
 type AiProviderRemoteConfig struct {
 	Provider string `mapstructure:"provider"`
@@ -39,6 +80,9 @@ type cliConfig struct {
 	SystemPrompt string `mapstructure:"system_prompt"`
 	History      bool   `mapstructure:"history"`
 	MaxTurns     int    `mapstructure:"max_turns"`
+	ImageDir     string `mapstructure:"image_dir"`      // 对话图片的存储目录，为空时只保存在内存中（最多 256MB，超出淘汰最久未用的）
+	MaxImageSize int64  `mapstructure:"max_image_size"` // 单张图片的最大字节数，默认 10MB
+	UsageFile    string `mapstructure:"usage_file"`     // 按用户/天/模型汇总的 token 用量持久化文件，每 10 秒及退出时落盘，为空时只保存在内存中
 }
 
 /************ MCP（仅关注自身传输及超时，不再包含 Consul） ************/
@@ -52,11 +96,55 @@ type mcpHTTP struct {
 	BaseURL string `mapstructure:"base_url"` // 直连时使用，如 "http://127.0.0.1:8080/mcp"
 }
 
+// mcpTLS MCP Streamable HTTP 的 TLS 配置
+// - 服务端：CertFile/KeyFile 非空时以 HTTPS 提供服务；ClientCAFile 非空时要求客户端证书（mTLS）
+// - 客户端：连接 https 实例时使用 CAFile 校验服务端，ClientCertFile/ClientKeyFile 用于 mTLS
+type mcpTLS struct {
+	CertFile           string `mapstructure:"cert_file"`
+	KeyFile            string `mapstructure:"key_file"`
+	ClientCAFile       string `mapstructure:"client_ca_file"`
+	CAFile             string `mapstructure:"ca_file"`
+	ClientCertFile     string `mapstructure:"client_cert_file"`
+	ClientKeyFile      string `mapstructure:"client_key_file"`
+	ServerName         string `mapstructure:"server_name"` // 覆盖校验证书时使用的主机名
+	InsecureSkipVerify bool   `mapstructure:"insecure_skip_verify"`
+}
+
+// mcpAuth MCP Streamable HTTP 的鉴权配置，服务端与 host 客户端共用
+// Mode:
+// - none(默认): 不鉴权
+// - static: 服务端校验 Tokens 中的任一 token，客户端发送 Token
+// - hmac: 双方使用 server.private-key 签发/校验 HS256 token
+// - oauth: 服务端作为 OAuth 2.1 资源服务器通过内省校验 token，客户端使用 client_credentials 获取 token
+type mcpAuth struct {
+	Mode    string        `mapstructure:"mode"`
+	Tokens  []string      `mapstructure:"tokens"`   // static: 服务端允许的 token
+	Token   string        `mapstructure:"token"`    // static: 客户端发送的 token
+	HMACTTL time.Duration `mapstructure:"hmac_ttl"` // hmac: 客户端签发 token 的有效期，默认 5m
+	OAuth   MCPOAuth      `mapstructure:"oauth"`
+}
+
+type MCPOAuth struct {
+	Resource             string   `mapstructure:"resource"`              // 资源标识，如 https://mcp.example.com/mcp；空则按请求地址推导
+	AuthorizationServers []string `mapstructure:"authorization_servers"` // 授权服务器 issuer，写入资源元数据
+	Scopes               []string `mapstructure:"scopes"`                // 访问 MCP 需要的 scope
+	// 服务端：RFC 7662 token 内省
+	IntrospectionURL          string `mapstructure:"introspection_url"`
+	IntrospectionClientID     string `mapstructure:"introspection_client_id"`
+	IntrospectionClientSecret string `mapstructure:"introspection_client_secret"`
+	// 客户端：client_credentials 获取 access token
+	TokenURL     string `mapstructure:"token_url"`
+	ClientID     string `mapstructure:"client_id"`
+	ClientSecret string `mapstructure:"client_secret"`
+}
+
 type mcpConfig struct {
 	ServerName string   `mapstructure:"server_name"`
 	Transport  string   `mapstructure:"transport"` // "stdio" | "sse" | "http"
 	Stdio      mcpStdio `mapstructure:"stdio"`
 	HTTP       mcpHTTP  `mapstructure:"http"`
+	TLS        mcpTLS   `mapstructure:"tls"`
+	Auth       mcpAuth  `mapstructure:"auth"`
 }
 
 type consulConfig struct {
@@ -67,18 +155,145 @@ type consulConfig struct {
 	Tag        string `mapstructure:"tag"`        // mcp
 	Scheme     string `mapstructure:"scheme"`     // "http" | "https"
 	Path       string `mapstructure:"path"`       // 例如 "/mcp"
+	Check      string `mapstructure:"check"`      // 健康检查方式 "http"(默认) | "tcp" | "ttl"
+	// HTTPS 健康检查是否跳过证书校验，默认校验（Consul agent 需信任 MCP 服务的证书）
+	TLSSkipVerify bool `mapstructure:"tls_skip_verify"`
+}
+
+type etcdConfig struct {
+	Endpoints   []string      `mapstructure:"endpoints"`    // 例如 ["http://127.0.0.1:2379"]
+	Username    string        `mapstructure:"username"`     // 可空
+	Password    string        `mapstructure:"password"`     // 可空
+	Prefix      string        `mapstructure:"prefix"`       // key 前缀，默认 /go-mcp-demo/services
+	LeaseTTL    time.Duration `mapstructure:"lease_ttl"`    // 注册租约 TTL，默认 10s
+	DialTimeout time.Duration `mapstructure:"dial_timeout"` // 单次请求超时，默认 5s
+}
+
+type nacosConfig struct {
+	Addresses         []string      `mapstructure:"addresses"`          // 例如 ["http://127.0.0.1:8848/nacos"]
+	Namespace         string        `mapstructure:"namespace"`          // 命名空间 ID，可空
+	Group             string        `mapstructure:"group"`              // 默认 DEFAULT_GROUP
+	Cluster           string        `mapstructure:"cluster"`            // 默认 DEFAULT
+	Username          string        `mapstructure:"username"`           // 可空
+	Password          string        `mapstructure:"password"`           // 可空
+	BeatInterval      time.Duration `mapstructure:"beat_interval"`      // 心跳间隔，默认 5s
+	SubscribeInterval time.Duration `mapstructure:"subscribe_interval"` // 订阅刷新间隔，默认 5s
+	PushPort          int           `mapstructure:"push_port"`          // 接收 UDP 推送的端口，默认随机
+	ClientIP          string        `mapstructure:"client_ip"`          // Nacos 推送的目标 IP，必须是本机地址（只在该地址上监听），默认自动探测
+	Timeout           time.Duration `mapstructure:"timeout"`            // 单次请求超时，默认 5s
 }
 
 // registryConfig 是“注册中心/地址解析”的顶层入口
-// Provider: "consul" | "static" | "none"（或留空）
+// Provider: "consul" | "etcd" | "nacos" | "static" | "none"（或留空）
 // - 当 Provider=consul 时使用 Consul 子配置
+// - 当 Provider=etcd 时使用 Etcd 子配置
+// - 当 Provider=nacos 时使用 Nacos 子配置
 // - 当 Provider=static 时使用 services.* 的静态地址（见 services 配置）
 // - 当 BaseURL 非空时，优先使用 BaseURL（由 MCP 自身指定）
 type registryConfig struct {
-	Provider        string        `mapstructure:"provider"` // "consul" | "none"
-	Consul          consulConfig  `mapstructure:"consul"`
-	RefreshInterval time.Duration `mapstructure:"refresh_interval"`
-	ResolveTimeout  time.Duration `mapstructure:"resolve_timeout"`
+	Provider        string              `mapstructure:"provider"` // "consul" | "etcd" | "nacos" | "none"
+	Consul          consulConfig        `mapstructure:"consul"`
+	Etcd            etcdConfig          `mapstructure:"etcd"`
+	Nacos           nacosConfig         `mapstructure:"nacos"`
+	Watch           bool                `mapstructure:"watch"` // 使用注册中心的推送/阻塞查询代替定时轮询
+	RefreshInterval time.Duration       `mapstructure:"refresh_interval"`
+	ResolveTimeout  time.Duration       `mapstructure:"resolve_timeout"`
+	ToolNamespace   toolNamespaceConfig `mapstructure:"tool_namespace"`
+	LoadBalance     loadBalanceConfig   `mapstructure:"load_balance"`
+}
+
+// loadBalanceConfig 同一工具存在多个 MCP 实例时的负载均衡与故障转移
+type loadBalanceConfig struct {
+	Strategy        string               `mapstructure:"strategy"`         // "round_robin"(默认) | "least_inflight" | "consistent_hash"
+	MaxRetries      int                  `mapstructure:"max_retries"`      // 幂等工具失败后换实例重试的次数
+	IdempotentTools []string             `mapstructure:"idempotent_tools"` // 额外视为幂等的工具（除 MCP 注解 readOnly/idempotent 之外）
+	CircuitBreaker  circuitBreakerConfig `mapstructure:"circuit_breaker"`
+}
+
+type circuitBreakerConfig struct {
+	FailureThreshold int           `mapstructure:"failure_threshold"` // 连续失败多少次后熔断
+	OpenTimeout      time.Duration `mapstructure:"open_timeout"`      // 熔断持续时间
+}
+
+// toolNamespaceConfig 聚合多个 MCP 服务时工具名的命名空间策略
+// Mode:
+// - auto(默认): 仅当不同服务暴露同名工具时，改用 "服务名__工具名"；暴露名分配后在进程内保持不变（先上线的服务保留原名）
+// - always: 所有工具都加上服务名前缀
+// - never: 不加前缀，同名工具只保留一个（会打印冲突告警）
+// Aliases: 暴露给模型的工具别名，key 为 "服务名__工具名" 或原始工具名，value 为别名
+// 需要跨重启也完全固定的工具名请使用 always 或 Aliases
+type toolNamespaceConfig struct {
+	Mode      string            `mapstructure:"mode"`
+	Separator string            `mapstructure:"separator"` // 默认 "__"
+	Aliases   map[string]string `mapstructure:"aliases"`
+}
+
+// authConfig host HTTP API 的鉴权配置，令牌使用 server.private-key 签发
+// - 开启后 /api/v1/chat 等接口需要携带 Authorization: Bearer <access_token> 或 X-API-Key
+// - 未开启时所有请求视为用户 1，与之前的行为一致
+type authConfig struct {
+	Enable          bool          `mapstructure:"enable"`
+	AccessTokenTTL  time.Duration `mapstructure:"access_token_ttl"`  // 默认 2h
+	RefreshTokenTTL time.Duration `mapstructure:"refresh_token_ttl"` // 默认 168h
+	APIKeyFile      string        `mapstructure:"api_key_file"`      // API Key 持久化文件，为空时仅保存在内存中
+	Users           []authUser    `mapstructure:"users"`
+}
+
+type authUser struct {
+	ID       int64  `mapstructure:"id"`
+	Username string `mapstructure:"username"`
+	Password string `mapstructure:"password"` // 密码哈希，使用 host -hash-password 生成
+}
+
+// rateLimitConfig host 按用户/API Key 的限流与每日 token 配额，超出时返回 BizLimitCode
+// 通过访问令牌认证时只检查 User；通过 API Key 认证时 User 与 APIKey 都要满足
+type rateLimitConfig struct {
+	Enable          bool          `mapstructure:"enable"`
+	User            rateLimitRule `mapstructure:"user"`
+	APIKey          rateLimitRule `mapstructure:"api_key"`
+	DailyTokenQuota int64         `mapstructure:"daily_token_quota"` // 每个用户每天可消耗的 LLM token，0 表示不限制
+}
+
+type rateLimitRule struct {
+	RequestsPerMinute    int `mapstructure:"requests_per_minute"`    // 0 表示不限制
+	MaxConcurrentStreams int `mapstructure:"max_concurrent_streams"` // 同时进行的 SSE 对话数，0 表示不限制
+}
+
+// sentinelConfig Sentinel 规则，修改配置文件后自动重新加载
+// 资源名：
+// - "api": 网关整体，所有请求都会经过
+// - "<METHOD>:<路由>": 单个接口，如 "POST:/api/v1/chat"、"GET:/api/v1/chat/sse"
+// - "ai_provider" / "ai_provider:<服务商>": 调用模型（整体 / 单个服务商）
+// - "mcp" / "mcp:<工具名>": 调用 MCP 工具（整体 / 单个工具）
+// Flow 为空时默认对 "api" 限制 100 QPS
+type sentinelConfig struct {
+	Flow           []SentinelFlowRule           `mapstructure:"flow"`
+	Isolation      []SentinelIsolationRule      `mapstructure:"isolation"`
+	CircuitBreaker []SentinelCircuitBreakerRule `mapstructure:"circuit_breaker"`
+}
+
+type SentinelFlowRule struct {
+	Resource        string        `mapstructure:"resource"`
+	Threshold       float64       `mapstructure:"threshold"`         // StatInterval 内允许通过的请求数
+	StatInterval    time.Duration `mapstructure:"stat_interval"`     // 统计窗口，默认 1s
+	ControlBehavior string        `mapstructure:"control_behavior"`  // "reject"(默认) | "throttling"(匀速排队)
+	MaxQueueingTime time.Duration `mapstructure:"max_queueing_time"` // throttling 时的最长排队时间
+}
+
+// SentinelIsolationRule 并发隔离，适合限制同时进行的 SSE 流
+type SentinelIsolationRule struct {
+	Resource       string `mapstructure:"resource"`
+	MaxConcurrency uint32 `mapstructure:"max_concurrency"`
+}
+
+type SentinelCircuitBreakerRule struct {
+	Resource         string        `mapstructure:"resource"`
+	Strategy         string        `mapstructure:"strategy"`           // "error_ratio"(默认) | "error_count" | "slow_request_ratio"
+	Threshold        float64       `mapstructure:"threshold"`          // 错误比例/错误数/慢请求比例
+	MinRequestAmount uint64        `mapstructure:"min_request_amount"` // 统计窗口内请求数少于该值时不熔断
+	StatInterval     time.Duration `mapstructure:"stat_interval"`      // 统计窗口，默认 10s
+	RetryTimeout     time.Duration `mapstructure:"retry_timeout"`      // 熔断持续时间，之后放行探测请求，默认 30s
+	MaxAllowedRT     time.Duration `mapstructure:"max_allowed_rt"`     // slow_request_ratio 时超过该耗时视为慢请求
 }
 
 type service struct {
@@ -93,4 +308,7 @@ type Config struct {
 	CLI        cliConfig        `mapstructure:"cli"`
 	MCP        mcpConfig        `mapstructure:"mcp"`
 	Registry   registryConfig   `mapstructure:"registry"`
+	Auth       authConfig       `mapstructure:"auth"`
+	RateLimit  rateLimitConfig  `mapstructure:"rate_limit"`
+	Sentinel   sentinelConfig   `mapstructure:"sentinel"`
 }
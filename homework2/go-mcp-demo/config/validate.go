@@ -0,0 +1,255 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/FantasyRL/go-mcp-demo/pkg/constant"
+)
+
+// FieldError 单个配置项的错误，Field 为配置文件中的路径
+type FieldError struct {
+	Field string
+	Msg   string
+}
+
+func (e FieldError) Error() string {
+	if e.Field == "" {
+		return e.Msg
+	}
+	return e.Field + ": " + e.Msg
+}
+
+// ValidationError 汇总所有配置错误，一次性提示给用户
+type ValidationError []FieldError
+
+func (e ValidationError) Error() string {
+	lines := make([]string, 0, len(e)+1)
+	lines = append(lines, fmt.Sprintf("%d config error(s):", len(e)))
+	for _, fe := range e {
+		lines = append(lines, "  - "+fe.Error())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// merge 追加 other 中尚未报告过的配置项
+func (e ValidationError) merge(other ValidationError) ValidationError {
+	for _, fe := range other {
+		if !slices.ContainsFunc(e, func(x FieldError) bool { return x.Field == fe.Field }) {
+			e = append(e, fe)
+		}
+	}
+	return e
+}
+
+var logLevels = []string{"trace", "debug", "info", "notice", "warn", "error", "fatal"}
+
+// Validate 检查配置是否可用，srv 为当前服务名；host 与 mcp_local 会调用模型，额外检查模型相关配置
+// 返回 nil 或 ValidationError
+func (c *Config) Validate(srv string) error {
+	var errs ValidationError
+	add := func(field, format string, args ...any) {
+		errs = append(errs, FieldError{Field: field, Msg: fmt.Sprintf(format, args...)})
+	}
+	oneOf := func(field, v string, allowed ...string) {
+		if !slices.Contains(allowed, v) {
+			add(field, "unsupported value %q, must be one of %s", v, strings.Join(allowed, " | "))
+		}
+	}
+
+	if lv := c.Server.LogLevel; lv != "" && !slices.Contains(logLevels, strings.ToLower(lv)) {
+		add("server.log-level", "unsupported value %q, must be one of %s", lv, strings.ToUpper(strings.Join(logLevels, " | ")))
+	}
+
+	if srv == constant.ServiceNameAPI || srv == constant.ServiceNameMCPLocal {
+		c.validateAiProvider(add, oneOf)
+	}
+	if srv == constant.ServiceNameAPI {
+		if c.Auth.Enable {
+			if c.Server.Secret == "" {
+				add("server.private-key", "required when auth.enable is true")
+			}
+			if len(c.Auth.Users) == 0 {
+				add("auth.users", "at least one user is required when auth.enable is true")
+			}
+			for i, u := range c.Auth.Users {
+				if u.Username == "" || u.Password == "" {
+					add(fmt.Sprintf("auth.users[%d]", i), "username and password are required (generate the hash with: host -hash-password <password>)")
+				}
+			}
+		}
+		if c.RateLimit.DailyTokenQuota < 0 {
+			add("rate_limit.daily_token_quota", "must not be negative")
+		}
+	}
+
+	if c.MCP.Transport == "" {
+		add("mcp.transport", "required, must be one of %s | %s | %s", constant.MCPTransportStdio, constant.MCPTransportSSE, constant.MCPTransportHTTP)
+	} else {
+		oneOf("mcp.transport", c.MCP.Transport, constant.MCPTransportStdio, constant.MCPTransportSSE, constant.MCPTransportHTTP)
+	}
+	if srv == constant.ServiceNameAPI {
+		switch {
+		case c.MCP.Transport == constant.MCPTransportStdio:
+			if c.MCP.Stdio.ServerCmd == "" {
+				add("mcp.stdio.server_cmd", "required when mcp.transport is %q", constant.MCPTransportStdio)
+			}
+		case c.Registry.Provider == "" || c.Registry.Provider == constant.RegistryProviderNone:
+			// 不使用注册中心时直连 mcp.http.base_url
+			if !validURL(c.MCP.HTTP.BaseURL) {
+				add("mcp.http.base_url", "must be an http(s) URL such as http://127.0.0.1:10002/mcp when registry.provider is none, got %q", c.MCP.HTTP.BaseURL)
+			}
+		}
+	}
+	oneOf("mcp.auth.mode", c.MCP.Auth.Mode, "", constant.MCPAuthModeNone, constant.MCPAuthModeStatic, constant.MCPAuthModeHMAC, constant.MCPAuthModeOAuth)
+	oneOf("registry.provider", c.Registry.Provider, "", constant.RegistryProviderNone,
+		constant.RegistryProviderConsul, constant.RegistryProviderEtcd, constant.RegistryProviderNacos)
+
+	for i, r := range c.Sentinel.Flow {
+		if r.Resource == "" {
+			add(fmt.Sprintf("sentinel.flow[%d].resource", i), "required")
+		}
+	}
+	for i, r := range c.Sentinel.CircuitBreaker {
+		if r.Resource == "" {
+			add(fmt.Sprintf("sentinel.circuit_breaker[%d].resource", i), "required")
+		}
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+func (c *Config) validateAiProvider(add func(field, format string, args ...any), oneOf func(field, v string, allowed ...string)) {
+	ai := &c.AiProvider
+	if len(ai.Providers) == 0 {
+		oneOf("ai_provider.mode", ai.Mode, constant.AiProviderModeLocal, constant.AiProviderModeRemote)
+		switch ai.Mode {
+		case constant.AiProviderModeLocal:
+			if !validURL(ai.BaseURL) {
+				add("ai_provider.base_url", "must be an http(s) URL such as http://127.0.0.1:11434, got %q", ai.BaseURL)
+			}
+		case constant.AiProviderModeRemote:
+			if !validURL(ai.Remote.BaseURL) {
+				add("ai_provider.remote.base_url", "must be an http(s) URL such as https://api.deepseek.com/v1, got %q", ai.Remote.BaseURL)
+			}
+			if ai.Remote.APIKey == "" {
+				add("ai_provider.remote.api_key", "required, set it in the config file or via %s_AI_PROVIDER_REMOTE_API_KEY", EnvPrefix)
+			}
+		}
+		validateOptions(add, "ai_provider.options", ai.Options)
+	}
+
+	names := make([]string, 0, len(ai.Providers))
+	for name := range ai.Providers {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		ep := ai.Providers[name]
+		field := "ai_provider.providers." + name
+		oneOf(field+".type", ep.Type, constant.AiProviderTypeOllama, constant.AiProviderTypeOpenAI, constant.AiProviderTypeAnthropic, constant.AiProviderTypeGemini)
+		if !validURL(ep.BaseURL) {
+			add(field+".base_url", "must be an http(s) URL, got %q", ep.BaseURL)
+		}
+		if (ep.Type == constant.AiProviderTypeAnthropic || ep.Type == constant.AiProviderTypeGemini) && ep.APIKey == "" {
+			add(field+".api_key", "required for %s provider", ep.Type)
+		}
+		if ep.API != "" {
+			oneOf(field+".api", ep.API, constant.AiProviderAPIChatCompletions, constant.AiProviderAPIResponses)
+			if ep.Type != constant.AiProviderTypeOpenAI {
+				add(field+".api", "only supported for %s provider", constant.AiProviderTypeOpenAI)
+			}
+		}
+		for i, tool := range ep.BuiltinTools {
+			oneOf(fmt.Sprintf("%s.builtin_tools[%d]", field, i), tool, constant.AiProviderBuiltinToolWebSearch, constant.AiProviderBuiltinToolCodeInterpreter)
+		}
+		if len(ep.BuiltinTools) > 0 && ep.API != constant.AiProviderAPIResponses {
+			add(field+".builtin_tools", "requires api %q", constant.AiProviderAPIResponses)
+		}
+		validateOptions(add, field+".options", ep.Options)
+	}
+
+	if ai.Model == "" {
+		add("ai_provider.model", "required")
+	} else if _, _, err := ai.ResolveModel(ai.Model); err != nil && len(ai.Endpoints()) > 0 {
+		add("ai_provider.model", "%v", err)
+	}
+	tools := make([]string, 0, len(ai.ToolModels))
+	for tool := range ai.ToolModels {
+		tools = append(tools, tool)
+	}
+	slices.Sort(tools)
+	for _, tool := range tools {
+		if _, _, err := ai.ResolveModel(ai.ToolModels[tool]); err != nil {
+			add("ai_provider.tool_models."+tool, "%v", err)
+		}
+	}
+	if ai.EmbeddingModel != "" {
+		if _, _, err := ai.ResolveModel(ai.EmbeddingModel); err != nil {
+			add("ai_provider.embedding_model", "%v", err)
+		}
+	}
+	for i, ref := range ai.Fallback {
+		if _, _, err := ai.ResolveModel(ref); err != nil || ref == "" {
+			add(fmt.Sprintf("ai_provider.fallback[%d]", i), "unknown model %q", ref)
+		}
+	}
+	if ai.Retry.MaxRetries < 0 {
+		add("ai_provider.retry.max_retries", "must not be negative")
+	}
+	if ai.Retry.Backoff < 0 {
+		add("ai_provider.retry.backoff", "must not be negative")
+	}
+	if ai.Retry.MaxBackoff < 0 {
+		add("ai_provider.retry.max_backoff", "must not be negative")
+	}
+	for i, p := range ai.Prices {
+		field := fmt.Sprintf("ai_provider.prices[%d]", i)
+		if p.Model == "" {
+			add(field+".model", "required")
+		}
+		if p.Input < 0 || p.Output < 0 {
+			add(field, "price must not be negative")
+		}
+	}
+}
+
+func validateOptions(add func(field, format string, args ...any), field string, opts OllamaOptions) {
+	if opts.Temperature != nil && (*opts.Temperature < 0 || *opts.Temperature > 2) {
+		add(field+".temperature", "must be between 0 and 2, got %v", *opts.Temperature)
+	}
+	if opts.TopP != nil && (*opts.TopP < 0 || *opts.TopP > 1) {
+		add(field+".top_p", "must be between 0 and 1, got %v", *opts.TopP)
+	}
+	if opts.TopK != nil && *opts.TopK < 0 {
+		add(field+".top_k", "must not be negative")
+	}
+	if opts.MaxTokens != nil && *opts.MaxTokens <= 0 {
+		add(field+".max_tokens", "must be positive")
+	}
+	if opts.RequestTimout < 0 {
+		add(field+".request_timeout", "must not be negative")
+	}
+}
+
+// Validate 检查单次请求覆盖的 options，返回第一个错误，字段名不带前缀
+func (o OllamaOptions) Validate() error {
+	var errs ValidationError
+	validateOptions(func(field, format string, args ...any) {
+		errs = append(errs, FieldError{Field: strings.TrimPrefix(field, "."), Msg: fmt.Sprintf(format, args...)})
+	}, "", o)
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs[0]
+}
+
+func validURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
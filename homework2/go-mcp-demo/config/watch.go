@@ -0,0 +1,142 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/FantasyRL/go-mcp-demo/pkg/logger"
+	"github.com/fsnotify/fsnotify"
+)
+
+var (
+	subscribersMu sync.Mutex
+	subscribers   []func()
+	reloadMu      sync.Mutex
+)
+
+// Subscribe 注册配置文件变更后的回调，回调时配置已经重新解析完成
+func Subscribe(fn func()) {
+	subscribersMu.Lock()
+	defer subscribersMu.Unlock()
+	subscribers = append(subscribers, fn)
+}
+
+// Watch 监听配置文件变化并热更新以下配置，其余配置修改后需要重启才能生效：
+// - ai_provider.model / ai_provider.remote.model / ai_provider.tool_models / ai_provider.fallback / ai_provider.retry / ai_provider.prices
+// - ai_provider.options 与 ai_provider.providers.*.options / models（request_timeout 除外，新增或删除服务商需要重启）
+// - cli.system_prompt
+// - server.log-level
+// - sentinel
+// 新配置不合法时保留原配置
+func Watch() {
+	runtimeViper.OnConfigChange(func(e fsnotify.Event) {
+		reload(e.Name)
+	})
+	runtimeViper.WatchConfig()
+}
+
+func reload(name string) {
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+
+	// viper 读取的是原始文件，这里重新读取一次以替换环境变量引用
+	cfg, err := loadConfig(runtimeViper, serviceName)
+	if err != nil {
+		logger.Errorf("config: reload %s failed, keep previous config: %v", name, err)
+		return
+	}
+	if fields := restartRequired(loaded, cfg); len(fields) > 0 {
+		logger.Warnf("config: changes to %s take effect after restart", strings.Join(fields, ", "))
+	}
+	loaded = cfg
+	apply(cfg)
+	logger.Infof("config: %s reloaded", name)
+
+	subscribersMu.Lock()
+	fns := append([]func(){}, subscribers...)
+	subscribersMu.Unlock()
+	for _, fn := range fns {
+		fn()
+	}
+}
+
+// apply 只替换可以热更新的字段；使用新的副本原子替换，避免修改正在被读取的结构体
+func apply(cfg *Config) {
+	ai := *AiProvider()
+	ai.Model = cfg.AiProvider.Model
+	ai.Remote.Model = cfg.AiProvider.Remote.Model
+	timeout := ai.Options.RequestTimout // 超时在创建客户端时已经确定
+	ai.Options = cfg.AiProvider.Options
+	ai.Options.RequestTimout = timeout
+	ai.ToolModels = cfg.AiProvider.ToolModels
+	ai.Fallback = cfg.AiProvider.Fallback
+	ai.Retry = cfg.AiProvider.Retry
+	ai.Prices = cfg.AiProvider.Prices
+	if len(ai.Providers) > 0 {
+		providers := make(map[string]AiProviderEndpoint, len(ai.Providers))
+		for name, ep := range ai.Providers {
+			if next, ok := cfg.AiProvider.Providers[name]; ok {
+				timeout := ep.Options.RequestTimout
+				ep.Models = next.Models
+				ep.Options = next.Options
+				ep.Options.RequestTimout = timeout
+			}
+			providers[name] = ep
+		}
+		ai.Providers = providers
+	}
+	SetAiProvider(&ai)
+
+	cli := *CLI()
+	cli.SystemPrompt = cfg.CLI.SystemPrompt
+	SetCLI(&cli)
+
+	srv := *Server()
+	if srv.LogLevel != cfg.Server.LogLevel {
+		logger.SetLevel(cfg.Server.LogLevel)
+	}
+	srv.LogLevel = cfg.Server.LogLevel
+	SetServer(&srv)
+
+	SetSentinel(&cfg.Sentinel)
+}
+
+// restartRequired 返回除热更新字段外发生变化的顶层配置
+func restartRequired(old, cur *Config) []string {
+	if old == nil {
+		return nil
+	}
+	a, b := maskHotFields(*old), maskHotFields(*cur)
+	va, vb := reflect.ValueOf(a), reflect.ValueOf(b)
+	var fields []string
+	for i := 0; i < va.NumField(); i++ {
+		if !reflect.DeepEqual(va.Field(i).Interface(), vb.Field(i).Interface()) {
+			fields = append(fields, va.Type().Field(i).Tag.Get("mapstructure"))
+		}
+	}
+	return fields
+}
+
+func maskHotFields(c Config) Config {
+	c.AiProvider.Model = ""
+	c.AiProvider.Remote.Model = ""
+	c.AiProvider.Options = OllamaOptions{RequestTimout: c.AiProvider.Options.RequestTimout}
+	c.AiProvider.ToolModels = nil
+	c.AiProvider.Fallback = nil
+	c.AiProvider.Retry = AiProviderRetryConfig{}
+	c.AiProvider.Prices = nil
+	if len(c.AiProvider.Providers) > 0 {
+		providers := make(map[string]AiProviderEndpoint, len(c.AiProvider.Providers))
+		for name, ep := range c.AiProvider.Providers {
+			ep.Models = nil
+			ep.Options = OllamaOptions{RequestTimout: ep.Options.RequestTimout}
+			providers[name] = ep
+		}
+		c.AiProvider.Providers = providers
+	}
+	c.CLI.SystemPrompt = ""
+	c.Server.LogLevel = ""
+	c.Sentinel = sentinelConfig{}
+	return c
+}
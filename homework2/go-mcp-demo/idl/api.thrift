@@ -7,6 +7,48 @@ struct ChatRequest{
         description: "用户发送的消息内容",
         type: "string"
     }')
+    2: string model(api.body="model", openapi.property='{
+        title: "模型",
+        description: "provider/model 或模型名，为空时使用默认模型，可选值见 /api/v1/models",
+        type: "string"
+    }')
+    3: list<string> images(api.body="images", openapi.property='{
+        title: "图片",
+        description: "/api/v1/images 返回的引用、http(s) 链接或 base64；使用 multipart/form-data 时也可以直接上传 images 文件",
+        type: "array",
+        items: {type: "string"}
+    }')
+    4: string response_schema(api.body="response_schema", openapi.property='{
+        title: "输出 JSON Schema",
+        description: "要求回答为符合该 JSON Schema 的 JSON（字符串形式的 schema）；不符合时会让模型修复一次，仍不符合返回错误",
+        type: "string"
+    }')
+    5: optional double temperature(api.body="temperature", openapi.property='{
+        title: "温度",
+        description: "覆盖服务商配置的 options.temperature，0 ~ 2",
+        type: "number"
+    }')
+    6: optional double top_p(api.body="top_p", openapi.property='{
+        title: "top_p",
+        description: "覆盖服务商配置的 options.top_p，0 ~ 1",
+        type: "number"
+    }')
+    7: optional i64 max_tokens(api.body="max_tokens", openapi.property='{
+        title: "最大输出 token 数",
+        description: "覆盖服务商配置的 options.max_tokens（ollama 为 num_predict）",
+        type: "integer"
+    }')
+    8: optional i64 seed(api.body="seed", openapi.property='{
+        title: "随机种子",
+        description: "覆盖服务商配置的 options.seed，服务商支持时用于复现结果",
+        type: "integer"
+    }')
+    9: list<string> stop(api.body="stop", openapi.property='{
+        title: "停止词",
+        description: "覆盖服务商配置的 options.stop，生成到其中任意一个时停止",
+        type: "array",
+        items: {type: "string"}
+    }')
 }(
     openapi.schema='{
         title: "聊天请求",
@@ -21,6 +63,16 @@ struct ChatResponse{
         description: "AI生成的回复内容",
         type: "string"
     }')
+    2: Usage usage(api.body="usage", openapi.property='{
+        title: "本轮用量",
+        description: "本次请求中所有模型调用（包括工具轮次）的 token 用量与费用",
+        type: "object"
+    }')
+    3: Usage conversation_usage(api.body="conversation_usage", openapi.property='{
+        title: "会话累计用量",
+        description: "当前会话（进程内）累计的 token 用量与费用",
+        type: "object"
+    }')
 }(
     openapi.schema='{
         title: "聊天响应",
@@ -29,12 +81,75 @@ struct ChatResponse{
     }'
 )
 
+struct Usage{
+    1: i64 prompt_tokens(api.body="prompt_tokens", openapi.property='{
+        title: "输入 token 数",
+        type: "integer"
+    }')
+    2: i64 completion_tokens(api.body="completion_tokens", openapi.property='{
+        title: "输出 token 数",
+        type: "integer"
+    }')
+    3: i64 total_tokens(api.body="total_tokens", openapi.property='{
+        title: "总 token 数",
+        type: "integer"
+    }')
+    4: double cost(api.body="cost", openapi.property='{
+        title: "费用",
+        description: "按 ai_provider.prices 估算的费用（美元），未配置价格的模型为 0",
+        type: "number"
+    }')
+}(
+    openapi.schema='{
+        title: "token 用量",
+        description: "token 用量与估算费用",
+        required: ["prompt_tokens", "completion_tokens", "total_tokens", "cost"]
+    }'
+)
+
 struct ChatSSEHandlerRequest{
     1: string message(api.query="message",openapi.property='{
         title: "用户消息",
         description: "用户发送的消息内容",
         type: "string"
     }')
+    2: string model(api.query="model",openapi.property='{
+        title: "模型",
+        description: "provider/model 或模型名，为空时使用默认模型，可选值见 /api/v1/models",
+        type: "string"
+    }')
+    3: list<string> images(api.query="images",openapi.property='{
+        title: "图片",
+        description: "/api/v1/images 返回的引用或 http(s) 链接，可以重复传入",
+        type: "array",
+        items: {type: "string"}
+    }')
+    4: optional double temperature(api.query="temperature", openapi.property='{
+        title: "温度",
+        description: "覆盖服务商配置的 options.temperature，0 ~ 2",
+        type: "number"
+    }')
+    5: optional double top_p(api.query="top_p", openapi.property='{
+        title: "top_p",
+        description: "覆盖服务商配置的 options.top_p，0 ~ 1",
+        type: "number"
+    }')
+    6: optional i64 max_tokens(api.query="max_tokens", openapi.property='{
+        title: "最大输出 token 数",
+        description: "覆盖服务商配置的 options.max_tokens（ollama 为 num_predict）",
+        type: "integer"
+    }')
+    7: optional i64 seed(api.query="seed", openapi.property='{
+        title: "随机种子",
+        description: "覆盖服务商配置的 options.seed，服务商支持时用于复现结果",
+        type: "integer"
+    }')
+    8: list<string> stop(api.query="stop", openapi.property='{
+        title: "停止词",
+        description: "覆盖服务商配置的 options.stop，生成到其中任意一个时停止",
+        type: "array",
+        items: {type: "string"}
+    }')
 }(
      openapi.schema='{
          title: "流式聊天请求",
@@ -57,9 +172,334 @@ struct ChatSSEHandlerResponse{
     }'
 )
 
+struct ModelInfo{
+    1: string id(api.body="id", openapi.property='{
+        title: "模型 ID",
+        description: "provider/model，可直接作为对话请求的 model",
+        type: "string"
+    }')
+    2: string provider(api.body="provider", openapi.property='{
+        title: "服务商",
+        description: "ai_provider.providers 中的名称",
+        type: "string"
+    }')
+    3: string model(api.body="model", openapi.property='{
+        title: "模型名",
+        description: "服务商侧的模型名",
+        type: "string"
+    }')
+    4: string type(api.body="type", openapi.property='{
+        title: "服务商类型",
+        description: "ollama | openai | anthropic | gemini",
+        type: "string"
+    }')
+    5: bool is_default(api.body="is_default", openapi.property='{
+        title: "是否默认模型",
+        description: "未指定 model 时使用该模型",
+        type: "boolean"
+    }')
+}(
+    openapi.schema='{
+        title: "模型信息",
+        description: "一个可选择的模型",
+        required: ["id", "provider", "model", "type", "is_default"]
+    }'
+)
+
+struct ListModelsRequest{
+}
+
+struct ListModelsResponse{
+    1: list<ModelInfo> models(api.body="models", openapi.property='{
+        title: "模型列表",
+        description: "配置中可选择的模型",
+        type: "array"
+    }')
+}(
+    openapi.schema='{
+        title: "模型列表响应",
+        description: "可用的模型列表",
+        required: ["models"]
+    }'
+)
+
+struct UploadImagesRequest{
+}(
+    openapi.schema='{
+        title: "上传图片请求",
+        description: "multipart/form-data，文件字段为 images，可以有多个"
+    }'
+)
+
+struct UploadImagesResponse{
+    1: list<string> images(api.body="images", openapi.property='{
+        title: "图片引用",
+        description: "按上传顺序返回，可用于对话请求的 images",
+        type: "array",
+        items: {type: "string"}
+    }')
+}(
+    openapi.schema='{
+        title: "上传图片响应",
+        description: "上传后的图片引用",
+        required: ["images"]
+    }'
+)
+
+struct GetUsageRequest{
+    1: string from(api.query="from", openapi.property='{
+        title: "开始日期",
+        description: "YYYY-MM-DD（含），为空时不限制",
+        type: "string"
+    }')
+    2: string to(api.query="to", openapi.property='{
+        title: "结束日期",
+        description: "YYYY-MM-DD（含），为空时不限制",
+        type: "string"
+    }')
+}(
+    openapi.schema='{
+        title: "用量查询请求",
+        description: "按日期范围查询当前用户的用量"
+    }'
+)
+
+struct UsageRecord{
+    1: string day(api.body="day", openapi.property='{
+        title: "日期",
+        description: "YYYY-MM-DD，服务端本地时间",
+        type: "string"
+    }')
+    2: string model(api.body="model", openapi.property='{
+        title: "模型",
+        description: "实际提供服务的 provider/model",
+        type: "string"
+    }')
+    3: i64 calls(api.body="calls", openapi.property='{
+        title: "调用次数",
+        type: "integer"
+    }')
+    4: i64 prompt_tokens(api.body="prompt_tokens", openapi.property='{
+        title: "输入 token 数",
+        type: "integer"
+    }')
+    5: i64 completion_tokens(api.body="completion_tokens", openapi.property='{
+        title: "输出 token 数",
+        type: "integer"
+    }')
+    6: i64 total_tokens(api.body="total_tokens", openapi.property='{
+        title: "总 token 数",
+        type: "integer"
+    }')
+    7: double cost(api.body="cost", openapi.property='{
+        title: "费用",
+        description: "按 ai_provider.prices 估算的费用（美元）",
+        type: "number"
+    }')
+}(
+    openapi.schema='{
+        title: "用量记录",
+        description: "一个用户某天在某个模型上的累计用量",
+        required: ["day", "model", "calls", "prompt_tokens", "completion_tokens", "total_tokens", "cost"]
+    }'
+)
+
+struct GetUsageResponse{
+    1: list<UsageRecord> records(api.body="records", openapi.property='{
+        title: "用量记录",
+        description: "按日期、模型排序",
+        type: "array"
+    }')
+    2: Usage total(api.body="total", openapi.property='{
+        title: "合计",
+        description: "records 的合计",
+        type: "object"
+    }')
+}(
+    openapi.schema='{
+        title: "用量查询响应",
+        description: "当前用户按天、按模型汇总的用量",
+        required: ["records", "total"]
+    }'
+)
+
+struct LoginRequest{
+    1: string username(api.body="username", openapi.property='{
+        title: "用户名",
+        description: "配置文件 auth.users 中的用户名",
+        type: "string"
+    }')
+    2: string password(api.body="password", openapi.property='{
+        title: "密码",
+        description: "用户密码明文",
+        type: "string"
+    }')
+}(
+    openapi.schema='{
+        title: "登录请求",
+        description: "使用用户名密码换取访问令牌与刷新令牌",
+        required: ["username", "password"]
+    }'
+)
+
+struct RefreshTokenRequest{
+    1: string refresh_token(api.body="refresh_token", openapi.property='{
+        title: "刷新令牌",
+        description: "登录时返回的刷新令牌",
+        type: "string"
+    }')
+}(
+    openapi.schema='{
+        title: "刷新令牌请求",
+        description: "使用刷新令牌换取新的令牌对",
+        required: ["refresh_token"]
+    }'
+)
+
+struct TokenResponse{
+    1: string access_token(api.body="access_token", openapi.property='{
+        title: "访问令牌",
+        description: "请求时放在 Authorization: Bearer 头中",
+        type: "string"
+    }')
+    2: string refresh_token(api.body="refresh_token", openapi.property='{
+        title: "刷新令牌",
+        description: "访问令牌过期后用于换取新的令牌对",
+        type: "string"
+    }')
+    3: i64 expires_in(api.body="expires_in", openapi.property='{
+        title: "有效期",
+        description: "访问令牌的有效期，单位秒",
+        type: "integer"
+    }')
+}(
+    openapi.schema='{
+        title: "令牌响应",
+        description: "访问令牌与刷新令牌",
+        required: ["access_token", "refresh_token", "expires_in"]
+    }'
+)
+
+struct APIKey{
+    1: string id(api.body="id", openapi.property='{
+        title: "API Key ID",
+        description: "用于吊销 API Key",
+        type: "string"
+    }')
+    2: string name(api.body="name", openapi.property='{
+        title: "名称",
+        description: "创建时指定的备注名",
+        type: "string"
+    }')
+    3: string prefix(api.body="prefix", openapi.property='{
+        title: "前缀",
+        description: "API Key 的前几位，便于辨认",
+        type: "string"
+    }')
+    4: i64 created_at(api.body="created_at", openapi.property='{
+        title: "创建时间",
+        description: "Unix 时间戳，单位秒",
+        type: "integer"
+    }')
+    5: i64 last_used_at(api.body="last_used_at", openapi.property='{
+        title: "最近使用时间",
+        description: "Unix 时间戳，单位秒，未使用过为 0",
+        type: "integer"
+    }')
+}(
+    openapi.schema='{
+        title: "API Key",
+        description: "API Key 的元信息，不包含明文",
+        required: ["id", "name", "prefix", "created_at"]
+    }'
+)
+
+struct CreateAPIKeyRequest{
+    1: string name(api.body="name", openapi.property='{
+        title: "名称",
+        description: "API Key 的备注名",
+        type: "string"
+    }')
+}(
+    openapi.schema='{
+        title: "创建 API Key 请求",
+        description: "为当前用户创建 API Key",
+        required: ["name"]
+    }'
+)
+
+struct CreateAPIKeyResponse{
+    1: APIKey key(api.body="key", openapi.property='{
+        title: "API Key 信息",
+        description: "API Key 的元信息",
+        type: "object"
+    }')
+    2: string secret(api.body="secret", openapi.property='{
+        title: "API Key 明文",
+        description: "仅在创建时返回一次，请求时放在 X-API-Key 头中",
+        type: "string"
+    }')
+}(
+    openapi.schema='{
+        title: "创建 API Key 响应",
+        description: "包含 API Key 明文的响应",
+        required: ["key", "secret"]
+    }'
+)
+
+struct ListAPIKeysRequest{
+}
+
+struct ListAPIKeysResponse{
+    1: list<APIKey> keys(api.body="keys", openapi.property='{
+        title: "API Key 列表",
+        description: "当前用户的全部 API Key",
+        type: "array"
+    }')
+}(
+    openapi.schema='{
+        title: "API Key 列表响应",
+        description: "当前用户的 API Key 列表",
+        required: ["keys"]
+    }'
+)
+
+struct RevokeAPIKeyRequest{
+    1: string id(api.path="id", openapi.property='{
+        title: "API Key ID",
+        description: "要吊销的 API Key ID",
+        type: "string"
+    }')
+}(
+    openapi.schema='{
+        title: "吊销 API Key 请求",
+        description: "吊销当前用户的 API Key",
+        required: ["id"]
+    }'
+)
+
+struct RevokeAPIKeyResponse{
+}
+
 service ApiService {
     // 非流式对话
     ChatResponse Chat(1: ChatRequest req)(api.post="/api/v1/chat")
     // 流式对话
     ChatSSEHandlerResponse ChatSSE(1: ChatSSEHandlerRequest req)(api.get="/api/v1/chat/sse")
+    // 列出可用的模型
+    ListModelsResponse ListModels(1: ListModelsRequest req)(api.get="/api/v1/models")
+    // 上传对话图片
+    UploadImagesResponse UploadImages(1: UploadImagesRequest req)(api.post="/api/v1/images")
+    // 查询当前用户的 token 用量与费用
+    GetUsageResponse GetUsage(1: GetUsageRequest req)(api.get="/api/v1/usage")
+    // 登录，换取访问令牌与刷新令牌
+    TokenResponse Login(1: LoginRequest req)(api.post="/api/v1/auth/login")
+    // 刷新令牌
+    TokenResponse RefreshToken(1: RefreshTokenRequest req)(api.post="/api/v1/auth/refresh")
+    // 创建 API Key
+    CreateAPIKeyResponse CreateAPIKey(1: CreateAPIKeyRequest req)(api.post="/api/v1/auth/api-keys")
+    // 列出当前用户的 API Key
+    ListAPIKeysResponse ListAPIKeys(1: ListAPIKeysRequest req)(api.get="/api/v1/auth/api-keys")
+    // 吊销 API Key
+    RevokeAPIKeyResponse RevokeAPIKey(1: RevokeAPIKeyRequest req)(api.delete="/api/v1/auth/api-keys/:id")
 }
\ No newline at end of file
@@ -0,0 +1,175 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/FantasyRL/go-mcp-demo/pkg/constant"
+	"github.com/FantasyRL/go-mcp-demo/pkg/errno"
+	"github.com/FantasyRL/go-mcp-demo/pkg/utils"
+	"github.com/google/uuid"
+)
+
+// APIKey 只保存明文的 sha256，明文仅在创建时返回一次
+type APIKey struct {
+	ID         string `json:"id"`
+	UserID     int64  `json:"user_id"`
+	Name       string `json:"name"`
+	Prefix     string `json:"prefix"`
+	Hash       string `json:"hash"`
+	CreatedAt  int64  `json:"created_at"`
+	LastUsedAt int64  `json:"last_used_at"`
+}
+
+// APIKeyStore API Key 存储，path 非空时创建/吊销后落盘
+type APIKeyStore struct {
+	mu     sync.RWMutex
+	path   string
+	keys   map[string]*APIKey // id -> key
+	byHash map[string]*APIKey
+}
+
+// NewAPIKeyStore 创建存储并加载 path 中已有的 API Key，文件不存在时视为空
+func NewAPIKeyStore(path string) (*APIKeyStore, error) {
+	s := &APIKeyStore{
+		path:   path,
+		keys:   make(map[string]*APIKey),
+		byHash: make(map[string]*APIKey),
+	}
+	if path == "" {
+		return s, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("auth: read api key file: %w", err)
+	}
+	var keys []*APIKey
+	if err := json.Unmarshal(data, &keys); err != nil {
+		return nil, fmt.Errorf("auth: parse api key file %s: %w", path, err)
+	}
+	for _, k := range keys {
+		s.keys[k.ID] = k
+		s.byHash[k.Hash] = k
+	}
+	return s, nil
+}
+
+// Create 为用户创建 API Key，返回元信息与明文
+func (s *APIKeyStore) Create(userID int64, name string) (*APIKey, string, error) {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, "", err
+	}
+	secret := constant.AuthAPIKeyPrefix + base64.RawURLEncoding.EncodeToString(buf)
+	id, err := uuid.NewV7()
+	if err != nil {
+		return nil, "", err
+	}
+	k := &APIKey{
+		ID:        id.String(),
+		UserID:    userID,
+		Name:      name,
+		Prefix:    secret[:len(constant.AuthAPIKeyPrefix)+6],
+		Hash:      hashAPIKey(secret),
+		CreatedAt: time.Now().Unix(),
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys[k.ID] = k
+	s.byHash[k.Hash] = k
+	if err := s.save(); err != nil {
+		delete(s.keys, k.ID)
+		delete(s.byHash, k.Hash)
+		return nil, "", err
+	}
+	cp := *k
+	return &cp, secret, nil
+}
+
+// List 返回用户的全部 API Key，按创建时间排序
+func (s *APIKeyStore) List(userID int64) []APIKey {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out := make([]APIKey, 0)
+	for _, k := range s.keys {
+		if k.UserID == userID {
+			out = append(out, *k)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].CreatedAt != out[j].CreatedAt {
+			return out[i].CreatedAt < out[j].CreatedAt
+		}
+		return out[i].ID < out[j].ID
+	})
+	return out
+}
+
+// Revoke 吊销用户自己的 API Key
+func (s *APIKeyStore) Revoke(userID int64, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	k, ok := s.keys[id]
+	if !ok || k.UserID != userID {
+		return errno.NewErrNo(errno.BizNotExist, "API Key 不存在")
+	}
+	delete(s.keys, id)
+	delete(s.byHash, k.Hash)
+	if err := s.save(); err != nil {
+		s.keys[id] = k
+		s.byHash[k.Hash] = k
+		return err
+	}
+	return nil
+}
+
+// Lookup 按明文查找 API Key，并记录最近使用时间（只保存在内存中，下次落盘时一并写入）
+func (s *APIKeyStore) Lookup(secret string) (*APIKey, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	k, ok := s.byHash[hashAPIKey(secret)]
+	if !ok {
+		return nil, false
+	}
+	k.LastUsedAt = time.Now().Unix()
+	cp := *k
+	return &cp, true
+}
+
+// save 把全部 API Key 写回文件；调用方需持有写锁
+func (s *APIKeyStore) save() error {
+	if s.path == "" {
+		return nil
+	}
+	keys := make([]*APIKey, 0, len(s.keys))
+	for _, k := range s.keys {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].ID < keys[j].ID })
+	data, err := json.MarshalIndent(keys, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := utils.WriteFileAtomic(s.path, data, 0o600); err != nil {
+		return fmt.Errorf("auth: save api keys: %w", err)
+	}
+	return nil
+}
+
+func hashAPIKey(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
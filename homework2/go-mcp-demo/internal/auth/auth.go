@@ -0,0 +1,234 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/FantasyRL/go-mcp-demo/config"
+	"github.com/FantasyRL/go-mcp-demo/pkg/constant"
+	"github.com/FantasyRL/go-mcp-demo/pkg/errno"
+	"github.com/FantasyRL/go-mcp-demo/pkg/logger"
+	"github.com/FantasyRL/go-mcp-demo/pkg/utils"
+	"github.com/google/uuid"
+)
+
+// User 可以登录 host 的用户，来自配置文件
+type User struct {
+	ID           int64
+	Username     string
+	PasswordHash string
+}
+
+// Claims host 签发的访问/刷新令牌，sub 为用户 id
+type Claims struct {
+	utils.JWTClaims
+	Type string `json:"typ"`
+}
+
+// Identity 认证通过的调用方
+type Identity struct {
+	UserID   int64
+	APIKeyID string // 通过 API Key 认证时非空
+}
+
+// TokenPair 登录/刷新返回的令牌对
+type TokenPair struct {
+	AccessToken  string
+	RefreshToken string
+	ExpiresIn    int64 // 访问令牌有效期，单位秒
+}
+
+type Options struct {
+	Secret          string
+	Issuer          string
+	AccessTokenTTL  time.Duration
+	RefreshTokenTTL time.Duration
+	Users           []User
+	APIKeyFile      string
+}
+
+// Authenticator 负责登录、令牌签发校验以及 API Key 管理
+type Authenticator struct {
+	opts    Options
+	byName  map[string]*User
+	byID    map[int64]*User
+	apiKeys *APIKeyStore
+}
+
+func New(opts Options) (*Authenticator, error) {
+	if opts.Secret == "" {
+		return nil, fmt.Errorf("auth: server.private-key is required")
+	}
+	if opts.AccessTokenTTL <= 0 {
+		opts.AccessTokenTTL = constant.AuthDefaultAccessTokenTTL
+	}
+	if opts.RefreshTokenTTL <= 0 {
+		opts.RefreshTokenTTL = constant.AuthDefaultRefreshTokenTTL
+	}
+	a := &Authenticator{
+		opts:   opts,
+		byName: make(map[string]*User, len(opts.Users)),
+		byID:   make(map[int64]*User, len(opts.Users)),
+	}
+	for i := range opts.Users {
+		u := &opts.Users[i]
+		if u.ID <= 0 || u.Username == "" {
+			return nil, fmt.Errorf("auth: user #%d requires id and username", i)
+		}
+		if _, ok := a.byName[u.Username]; ok {
+			return nil, fmt.Errorf("auth: duplicate username %q", u.Username)
+		}
+		if _, ok := a.byID[u.ID]; ok {
+			return nil, fmt.Errorf("auth: duplicate user id %d", u.ID)
+		}
+		a.byName[u.Username] = u
+		a.byID[u.ID] = u
+	}
+	store, err := NewAPIKeyStore(opts.APIKeyFile)
+	if err != nil {
+		return nil, err
+	}
+	a.apiKeys = store
+	return a, nil
+}
+
+// Login 校验用户名密码并签发令牌对
+func (a *Authenticator) Login(username, password string) (*TokenPair, error) {
+	u, ok := a.byName[username]
+	if !ok || !VerifyPassword(password, u.PasswordHash) {
+		return nil, errno.AuthInvalid.WithMessage("用户名或密码错误")
+	}
+	return a.issue(u.ID)
+}
+
+// Refresh 使用刷新令牌换取新的令牌对
+func (a *Authenticator) Refresh(refreshToken string) (*TokenPair, error) {
+	uid, err := a.parse(refreshToken, constant.AuthTokenTypeRefresh)
+	if err != nil {
+		return nil, err
+	}
+	return a.issue(uid)
+}
+
+// Authenticate 依次尝试 Bearer 访问令牌与 API Key
+func (a *Authenticator) Authenticate(authorization, apiKey string) (*Identity, error) {
+	if token, ok := strings.CutPrefix(authorization, "Bearer "); ok && strings.TrimSpace(token) != "" {
+		uid, err := a.parse(strings.TrimSpace(token), constant.AuthTokenTypeAccess)
+		if err != nil {
+			return nil, err
+		}
+		return &Identity{UserID: uid}, nil
+	}
+	if apiKey != "" {
+		k, ok := a.apiKeys.Lookup(apiKey)
+		if !ok {
+			return nil, errno.AuthInvalid.WithMessage("API Key 无效")
+		}
+		if _, ok := a.byID[k.UserID]; !ok {
+			return nil, errno.AuthInvalid.WithMessage("用户不存在")
+		}
+		return &Identity{UserID: k.UserID, APIKeyID: k.ID}, nil
+	}
+	return nil, errno.AuthMissing
+}
+
+// APIKeys 返回 API Key 存储
+func (a *Authenticator) APIKeys() *APIKeyStore {
+	return a.apiKeys
+}
+
+func (a *Authenticator) issue(uid int64) (*TokenPair, error) {
+	now := time.Now()
+	access, err := a.sign(uid, constant.AuthTokenTypeAccess, now, a.opts.AccessTokenTTL)
+	if err != nil {
+		return nil, errno.AuthError.WithError(err)
+	}
+	refresh, err := a.sign(uid, constant.AuthTokenTypeRefresh, now, a.opts.RefreshTokenTTL)
+	if err != nil {
+		return nil, errno.AuthError.WithError(err)
+	}
+	return &TokenPair{
+		AccessToken:  access,
+		RefreshToken: refresh,
+		ExpiresIn:    int64(a.opts.AccessTokenTTL / time.Second),
+	}, nil
+}
+
+func (a *Authenticator) sign(uid int64, typ string, now time.Time, ttl time.Duration) (string, error) {
+	id, err := uuid.NewV7()
+	if err != nil {
+		return "", err
+	}
+	return utils.SignJWT(Claims{
+		JWTClaims: utils.JWTClaims{
+			Issuer:    a.opts.Issuer,
+			Subject:   strconv.FormatInt(uid, 10),
+			Audience:  constant.AuthTokenAudience,
+			ExpiresAt: now.Add(ttl).Unix(),
+			IssuedAt:  now.Unix(),
+			ID:        id.String(),
+		},
+		Type: typ,
+	}, a.opts.Secret)
+}
+
+// parse 校验令牌并返回用户 id，过期错误按令牌类型区分
+func (a *Authenticator) parse(token, typ string) (int64, error) {
+	var claims Claims
+	if err := utils.ParseJWT(token, a.opts.Secret, &claims); err != nil {
+		if errors.Is(err, utils.ErrJWTExpired) {
+			if typ == constant.AuthTokenTypeRefresh {
+				return 0, errno.AuthRefreshExpired
+			}
+			return 0, errno.AuthAccessExpired
+		}
+		return 0, errno.AuthInvalid
+	}
+	if claims.Type != typ || claims.Audience != constant.AuthTokenAudience || claims.Issuer != a.opts.Issuer {
+		return 0, errno.AuthInvalid
+	}
+	uid, err := strconv.ParseInt(claims.Subject, 10, 64)
+	if err != nil {
+		return 0, errno.AuthInvalid
+	}
+	// 用户从配置中删除后，已签发的令牌随之失效
+	if _, ok := a.byID[uid]; !ok {
+		return 0, errno.AuthInvalid.WithMessage("用户不存在")
+	}
+	return uid, nil
+}
+
+var std *Authenticator
+
+// Init 按 auth 配置初始化，未开启鉴权时为空操作
+func Init() {
+	if config.Auth == nil || !config.Auth.Enable {
+		logger.Warnf("auth: disabled, all requests are treated as user %d", constant.AuthDefaultUserID)
+		return
+	}
+	users := make([]User, 0, len(config.Auth.Users))
+	for _, u := range config.Auth.Users {
+		users = append(users, User{ID: u.ID, Username: u.Username, PasswordHash: u.Password})
+	}
+	srv := config.Server()
+	a, err := New(Options{
+		Secret:          srv.Secret,
+		Issuer:          srv.Name,
+		AccessTokenTTL:  config.Auth.AccessTokenTTL,
+		RefreshTokenTTL: config.Auth.RefreshTokenTTL,
+		Users:           users,
+		APIKeyFile:      config.Auth.APIKeyFile,
+	})
+	if err != nil {
+		logger.Fatalf("%v", err)
+	}
+	std = a
+}
+
+// Default 返回 Init 创建的实例，未开启鉴权时为 nil
+func Default() *Authenticator {
+	return std
+}
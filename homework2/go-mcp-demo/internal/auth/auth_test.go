@@ -0,0 +1,127 @@
+package auth
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/FantasyRL/go-mcp-demo/pkg/errno"
+)
+
+func newTestAuthenticator(t *testing.T, accessTTL time.Duration, keyFile string) *Authenticator {
+	t.Helper()
+	hash, err := HashPassword("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, err := New(Options{
+		Secret:         "private-key",
+		Issuer:         "go-mcp-demo",
+		AccessTokenTTL: accessTTL,
+		Users:          []User{{ID: 7, Username: "alice", PasswordHash: hash}},
+		APIKeyFile:     keyFile,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return a
+}
+
+func errCode(err error) int64 {
+	var e errno.ErrNo
+	if errors.As(err, &e) {
+		return e.ErrorCode
+	}
+	return -1
+}
+
+func TestLoginAndRefresh(t *testing.T) {
+	a := newTestAuthenticator(t, time.Hour, "")
+
+	if _, err := a.Login("alice", "wrong"); errCode(err) != errno.AuthInvalidCode {
+		t.Fatalf("wrong password: %v", err)
+	}
+	tokens, err := a.Login("alice", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, err := a.Authenticate("Bearer "+tokens.AccessToken, "")
+	if err != nil || id.UserID != 7 || id.APIKeyID != "" {
+		t.Fatalf("access token: id=%+v err=%v", id, err)
+	}
+	// 刷新令牌不能当作访问令牌使用，反之亦然
+	if _, err := a.Authenticate("Bearer "+tokens.RefreshToken, ""); errCode(err) != errno.AuthInvalidCode {
+		t.Fatalf("refresh token used as access token: %v", err)
+	}
+	if _, err := a.Refresh(tokens.AccessToken); errCode(err) != errno.AuthInvalidCode {
+		t.Fatalf("access token used as refresh token: %v", err)
+	}
+	next, err := a.Refresh(tokens.RefreshToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id, err := a.Authenticate("Bearer "+next.AccessToken, ""); err != nil || id.UserID != 7 {
+		t.Fatalf("refreshed token: id=%+v err=%v", id, err)
+	}
+	if _, err := a.Authenticate("", ""); errCode(err) != errno.AuthInvalidCode {
+		t.Fatalf("missing credentials: %v", err)
+	}
+}
+
+func TestExpiredAccessToken(t *testing.T) {
+	a := newTestAuthenticator(t, time.Hour, "")
+	token, err := a.sign(7, "access", time.Now().Add(-2*time.Hour), time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.Authenticate("Bearer "+token, ""); errCode(err) != errno.AuthAccessExpiredCode {
+		t.Fatalf("expected access expired, got %v", err)
+	}
+	refresh, err := a.sign(7, "refresh", time.Now().Add(-2*time.Hour), time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.Refresh(refresh); errCode(err) != errno.AuthRefreshExpiredCode {
+		t.Fatalf("expected refresh expired, got %v", err)
+	}
+}
+
+func TestAPIKeys(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "keys", "api_keys.json")
+	a := newTestAuthenticator(t, time.Hour, file)
+
+	key, secret, err := a.APIKeys().Create(7, "ci")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id, err := a.Authenticate("", secret); err != nil || id.UserID != 7 || id.APIKeyID != key.ID {
+		t.Fatalf("api key: id=%+v err=%v", id, err)
+	}
+	if _, err := a.Authenticate("", secret+"x"); errCode(err) != errno.AuthInvalidCode {
+		t.Fatalf("bad api key: %v", err)
+	}
+
+	// 重新加载后 API Key 仍然有效
+	b := newTestAuthenticator(t, time.Hour, file)
+	if keys := b.APIKeys().List(7); len(keys) != 1 || keys[0].ID != key.ID || keys[0].Prefix != key.Prefix {
+		t.Fatalf("reloaded keys: %+v", keys)
+	}
+	if id, err := b.Authenticate("", secret); err != nil || id.UserID != 7 {
+		t.Fatalf("reloaded api key: id=%+v err=%v", id, err)
+	}
+
+	// 不能吊销其他用户的 API Key
+	if err := b.APIKeys().Revoke(8, key.ID); err == nil {
+		t.Fatal("revoked another user's key")
+	}
+	if err := b.APIKeys().Revoke(7, key.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := b.Authenticate("", secret); err == nil {
+		t.Fatal("revoked key still works")
+	}
+	if keys := newTestAuthenticator(t, time.Hour, file).APIKeys().List(7); len(keys) != 0 {
+		t.Fatalf("revoked key persisted: %+v", keys)
+	}
+}
@@ -2,14 +2,56 @@ package host
 
 import (
 	"context"
-	"github.com/FantasyRL/go-mcp-demo/config"
 	"github.com/FantasyRL/go-mcp-demo/pkg/base/ai_provider"
+	"github.com/FantasyRL/go-mcp-demo/pkg/base/mcp_client"
 	"github.com/FantasyRL/go-mcp-demo/pkg/constant"
 	"github.com/FantasyRL/go-mcp-demo/pkg/errno"
 	"github.com/FantasyRL/go-mcp-demo/pkg/logger"
+	"strconv"
 )
 
+// Chat 非流式对话；设置了 response_schema 时校验最终回答，不符合时把校验错误发给模型修复一次，
+// 历史中只保存校验通过的回答，修复失败时本轮对话不写入历史
 func (h *Host) Chat(id int64, msg string) (string, error) {
+	if h.responseSchema == nil {
+		return h.chat(id, msg)
+	}
+	ollama := h.aiProviderCli.IsOllama(h.model)
+	before, beforeOpenAI := history[id], historyOpenAI[id]
+	rollback := func() {
+		if ollama {
+			history[id] = before
+		} else {
+			historyOpenAI[id] = beforeOpenAI
+		}
+	}
+	answer, err := h.chat(id, msg)
+	if err != nil {
+		return "", err
+	}
+	out, problems := checkStructured(h.responseSchema, answer)
+	if problems != "" {
+		logger.Warnf("host: reply does not match response_schema, repairing: %s", problems)
+		if answer, err = h.repair(id, ollama, problems); err != nil {
+			rollback()
+			return "", err
+		}
+		if out, problems = checkStructured(h.responseSchema, answer); problems != "" {
+			rollback()
+			return "", errno.Errorf(errno.BizLogicCode, "model reply does not match response_schema: %s", problems)
+		}
+	}
+	h.setAnswer(id, ollama, out)
+	return out, nil
+}
+
+func (h *Host) chat(id int64, msg string) (string, error) {
+	// 只有 ollama 支持 /api/chat，其他服务商走 OpenAI 兼容接口
+	if !h.aiProviderCli.IsOllama(h.model) {
+		return h.chatOpenAI(id, msg)
+	}
+	ctx := mcp_client.WithConversationID(h.ctx, strconv.FormatInt(id, 10))
+	prompt := systemPrompt()
 	// 获取当前用户的对话历史（如果没有则初始化为空切片）
 	userHistory := history[id]
 	if userHistory == nil {
@@ -17,23 +59,24 @@ func (h *Host) Chat(id int64, msg string) (string, error) {
 	}
 
 	// 将当前用户消息加入历史
-	userHistory = append(userHistory, ai_provider.Message{Role: "user", Content: msg})
+	userHistory = append(userHistory, ai_provider.Message{Role: "user", Content: msg, Images: h.images})
 
 	// 转换工具定义
 	ollamaTools := h.mcpCli.ConvertToolsToOllama()
-	ollamaOptions := ai_provider.BuildOptions()
 
 	// 第一次调用模型（带历史）
-	resp, err := h.aiProviderCli.Chat(h.ctx, ai_provider.ChatRequest{
-		Model:     config.AiProvider.Model,
-		Messages:  userHistory, // 使用完整历史
-		Options:   ollamaOptions,
+	resp, served, err := h.aiProviderCli.Chat(h.ctx, ai_provider.ChatRequest{
+		Model:     h.model,
+		Messages:  withImages(withSystemPrompt(prompt, userHistory)), // 使用完整历史
 		Tools:     ollamaTools,
-		KeepAlive: config.AiProvider.Options.KeepAlive,
+		Format:    h.ollamaFormat(),
+		Options:   ai_provider.BuildOptions(h.options),
+		KeepAlive: h.options.KeepAlive,
 	})
 	if err != nil {
 		return "", err
 	}
+	h.recordUsage(id, served, resp.PromptEvalCount, resp.EvalCount)
 
 	// 更新历史：添加模型回复
 	userHistory = append(userHistory, ai_provider.Message{Role: "assistant", Content: resp.Message.Content})
@@ -46,7 +89,7 @@ func (h *Host) Chat(id int64, msg string) (string, error) {
 				args = map[string]any{"_error": err.Error()}
 			}
 
-			out, err := h.mcpCli.CallTool(context.Background(), c.Function.Name, args)
+			out, err := h.mcpCli.CallTool(ctx, c.Function.Name, args)
 			if err != nil {
 				out = "tool error: " + err.Error()
 			}
@@ -60,16 +103,19 @@ func (h *Host) Chat(id int64, msg string) (string, error) {
 			logger.Infof("[tool] %s executed\n", c.Function.Name)
 		}
 
-		// 再次调用模型，传入完整历史（包含工具返回）
-		resp2, err := h.aiProviderCli.Chat(h.ctx, ai_provider.ChatRequest{
-			Model:    config.AiProvider.Model,
-			Messages: userHistory, // 包含工具返回的新历史
-			Options:  ollamaOptions,
-			Tools:    ollamaTools,
+		// 再次调用模型，传入完整历史（包含工具返回）；首次调用已经切换到 fallback 时继续使用同一个模型
+		resp2, served, err := h.aiProviderCli.Chat(h.ctx, ai_provider.ChatRequest{
+			Model:     served.ID,
+			Messages:  withImages(withSystemPrompt(prompt, userHistory)), // 包含工具返回的新历史
+			Tools:     ollamaTools,
+			Format:    h.ollamaFormat(),
+			Options:   ai_provider.BuildOptions(h.options),
+			KeepAlive: h.options.KeepAlive,
 		})
 		if err != nil {
 			return "", err
 		}
+		h.recordUsage(id, served, resp2.PromptEvalCount, resp2.EvalCount)
 
 		// 更新历史：添加最终模型回复
 		userHistory = append(userHistory, ai_provider.Message{Role: "assistant", Content: resp2.Message.Content})
@@ -91,27 +137,36 @@ func (h *Host) StreamChat(
 	userMsg string,
 	emit func(event string, v any) error, // SSE: event 名 + 任意 JSON 数据
 ) error {
+	if err := h.rejectResponseSchema(); err != nil {
+		return err
+	}
+	ctx = mcp_client.WithConversationID(ctx, strconv.FormatInt(id, 10))
+	prompt := systemPrompt()
 	// 历史
 	hist := history[id]
 	if hist == nil {
 		hist = []ai_provider.Message{}
 	}
 	// 加用户消息
-	hist = append(hist, ai_provider.Message{Role: "user", Content: userMsg})
+	hist = append(hist, ai_provider.Message{Role: "user", Content: userMsg, Images: h.images})
 
 	tools := h.mcpCli.ConvertToolsToOllama()
-	opts := ai_provider.BuildOptions()
 	// 首次流式：边生成边推，遇到 tool_calls 停止
 	var assistantBuf string
 	var toolCalls []ai_provider.ToolCall
+	var last ai_provider.ChatResponse // 最后一帧，带 token 用量
 
-	err := h.aiProviderCli.ChatStream(ctx, ai_provider.ChatRequest{
-		Model:     config.AiProvider.Model,
-		Messages:  hist,
+	served, err := h.aiProviderCli.ChatStream(ctx, ai_provider.ChatRequest{
+		Model:     h.model,
+		Messages:  withImages(withSystemPrompt(prompt, hist)),
 		Tools:     tools,
-		Options:   opts,
-		KeepAlive: config.AiProvider.Options.KeepAlive,
+		Options:   ai_provider.BuildOptions(h.options),
+		KeepAlive: h.options.KeepAlive,
 	}, func(chunk *ai_provider.ChatResponse) error {
+		// 推理过程只推给前端，不落历史
+		if s := chunk.Message.Thinking; s != "" {
+			_ = emit(constant.SSEEventReasoning, map[string]any{"text": s})
+		}
 		// 增量文本
 		if s := chunk.Message.Content; s != "" {
 			assistantBuf += s
@@ -122,10 +177,14 @@ func (h *Host) StreamChat(
 		if len(chunk.Message.ToolCalls) > 0 {
 			toolCalls = append(toolCalls, chunk.Message.ToolCalls...)
 			_ = emit(constant.SSEEventStartToolCall, map[string]any{"tool_calls": chunk.Message.ToolCalls})
-			return errno.OllamaInternalStopStream // 提前结束首次流
+		}
+		// 不提前结束首次流：token 用量在最后一帧返回
+		if chunk.Done {
+			last = *chunk
 		}
 		return nil
 	})
+	h.recordUsage(id, served, last.PromptEvalCount, last.EvalCount)
 	if err != nil {
 		return err
 	}
@@ -138,7 +197,7 @@ func (h *Host) StreamChat(
 	// 没有工具调用：直接完成
 	if len(toolCalls) == 0 {
 		history[id] = hist
-		_ = emit("done", map[string]any{"reason": "no_tool"})
+		_ = emit(constant.SSEEventDone, h.doneEvent(id, "no_tool", served))
 		return nil
 	}
 
@@ -175,19 +234,28 @@ func (h *Host) StreamChat(
 
 	// 6) 二次流式：带工具结果，让模型给最终回答
 	var finalBuf string
-	err = h.aiProviderCli.ChatStream(ctx, ai_provider.ChatRequest{
-		Model:     config.AiProvider.Model,
-		Messages:  hist,
+	last = ai_provider.ChatResponse{}
+	// 首次调用已经切换到 fallback 时继续使用同一个模型
+	served, err = h.aiProviderCli.ChatStream(ctx, ai_provider.ChatRequest{
+		Model:     served.ID,
+		Messages:  withImages(withSystemPrompt(prompt, hist)),
 		Tools:     tools,
-		Options:   opts,
-		KeepAlive: config.AiProvider.Options.KeepAlive,
+		Options:   ai_provider.BuildOptions(h.options),
+		KeepAlive: h.options.KeepAlive,
 	}, func(chunk *ai_provider.ChatResponse) error {
+		if s := chunk.Message.Thinking; s != "" {
+			_ = emit(constant.SSEEventReasoning, map[string]any{"text": s})
+		}
 		if s := chunk.Message.Content; s != "" {
 			finalBuf += s
 			_ = emit(constant.SSEEventDelta, map[string]any{"text": s})
 		}
+		if chunk.Done {
+			last = *chunk
+		}
 		return nil
 	})
+	h.recordUsage(id, served, last.PromptEvalCount, last.EvalCount)
 	if err != nil {
 		return err
 	}
@@ -197,6 +265,6 @@ func (h *Host) StreamChat(
 		hist = append(hist, ai_provider.Message{Role: "assistant", Content: finalBuf})
 	}
 	history[id] = hist
-	_ = emit(constant.SSEEventDone, map[string]any{"reason": "completed"})
+	_ = emit(constant.SSEEventDone, h.doneEvent(id, "completed", served))
 	return nil
 }
@@ -3,10 +3,12 @@ package host
 import (
 	"context"
 	"encoding/json"
-	"github.com/FantasyRL/go-mcp-demo/config"
+	"github.com/FantasyRL/go-mcp-demo/pkg/base/ai_provider"
+	"github.com/FantasyRL/go-mcp-demo/pkg/base/mcp_client"
 	"github.com/FantasyRL/go-mcp-demo/pkg/constant"
-	"github.com/FantasyRL/go-mcp-demo/pkg/errno"
 	openai "github.com/openai/openai-go/v2"
+	"strconv"
+	"strings"
 )
 
 // 将 OpenAI 的 tool_calls[].function.arguments (string) 解成 map[string]any（与原逻辑一致）
@@ -24,29 +26,68 @@ func parseOpenAIToolArgs(argStr string) map[string]any {
 
 const maxToolRounds = 10 // 防御性上限，避免死循环
 
+// chatOpenAI 非 ollama 模型的非流式对话：复用流式流程并拼接增量文本
+func (h *Host) chatOpenAI(id int64, msg string) (string, error) {
+	var buf strings.Builder
+	err := h.streamChatOpenAI(h.ctx, id, msg, func(event string, v any) error {
+		if event == constant.SSEEventDelta {
+			if m, ok := v.(map[string]any); ok {
+				s, _ := m["text"].(string)
+				buf.WriteString(s)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// StreamChatOpenAI 非 ollama 模型的流式对话，SSE 事件见 constant.SSEEvent*
 func (h *Host) StreamChatOpenAI(
 	ctx context.Context,
 	id int64,
 	userMsg string,
 	emit func(event string, v any) error,
 ) error {
+	if err := h.rejectResponseSchema(); err != nil {
+		return err
+	}
+	return h.streamChatOpenAI(ctx, id, userMsg, emit)
+}
+
+func (h *Host) streamChatOpenAI(
+	ctx context.Context,
+	id int64,
+	userMsg string,
+	emit func(event string, v any) error,
+) error {
+	ctx = mcp_client.WithConversationID(ctx, strconv.FormatInt(id, 10))
+	prompt := systemPrompt()
 	// 历史（OpenAI）
 	hist := historyOpenAI[id]
 	if hist == nil {
 		hist = []openai.ChatCompletionMessageParamUnion{}
 	}
-	// 用户消息
-	hist = append(hist, openai.UserMessage(userMsg))
+	// 用户消息，图片在历史中只保存引用
+	hist = append(hist, userMessageOpenAI(userMsg, h.images))
 
 	// 工具（OpenAI 版）
 	tools := h.mcpCli.ConvertToolsToOpenAI()
 
+	if h.aiProviderCli.UsesResponsesAPI(h.model) {
+		return h.streamChatResponses(ctx, id, prompt, hist, tools, emit)
+	}
+
+	model := h.model
+	var served ai_provider.ModelInfo
 	round := 0
 	for {
 		round++
 		if round > maxToolRounds {
 			historyOpenAI[id] = hist
-			_ = emit(constant.SSEEventDone, map[string]any{"reason": "tool_round_limit"})
+			_ = emit(constant.SSEEventDone, h.doneEvent(id, "tool_round_limit", served))
 			return nil
 		}
 
@@ -55,13 +96,23 @@ func (h *Host) StreamChatOpenAI(
 		var acc openai.ChatCompletionAccumulator
 		var needTools bool
 
-		err := h.aiProviderCli.ChatStreamOpenAI(ctx, openai.ChatCompletionNewParams{
-			Model:    openai.ChatModel(config.AiProvider.Model),
-			Messages: hist,
-			Tools:    tools,
-		}, func(chunk *openai.ChatCompletionChunk) error {
+		var err error
+		req := openai.ChatCompletionNewParams{
+			Model:          openai.ChatModel(model),
+			Messages:       withImagesOpenAI(withSystemPromptOpenAI(prompt, hist)),
+			Tools:          tools,
+			ResponseFormat: h.responseFormat(),
+			// 最后一帧返回本轮 token 用量，用于配额统计
+			StreamOptions: openai.ChatCompletionStreamOptionsParam{IncludeUsage: openai.Bool(true)},
+		}
+		ai_provider.ApplyOpenAIOptions(&req, h.options)
+		served, err = h.aiProviderCli.ChatStreamOpenAI(ctx, req, func(chunk *openai.ChatCompletionChunk) error {
 			acc.AddChunk(*chunk)
 			if len(chunk.Choices) > 0 {
+				// 推理过程（reasoning_content / <think>）只推给前端，不进入 acc 与历史
+				if s := ai_provider.ReasoningDelta(chunk.Choices[0].Delta); s != "" {
+					_ = emit(constant.SSEEventReasoning, map[string]any{"text": s})
+				}
 				if s := chunk.Choices[0].Delta.Content; s != "" {
 					assistantBuf += s
 					_ = emit(constant.SSEEventDelta, map[string]any{"text": s})
@@ -75,14 +126,17 @@ func (h *Host) StreamChatOpenAI(
 							"round":      round,
 						})
 					}
-					return errno.OllamaInternalStopStream
+					// 不中断流：finish_reason 之后还有一帧 usage
 				}
 			}
 			return nil
 		})
+		h.recordUsage(id, served, acc.Usage.PromptTokens, acc.Usage.CompletionTokens)
 		if err != nil {
 			return err
 		}
+		// 已经切换到 fallback 时，后续轮次继续使用同一个模型
+		model = served.ID
 
 		// 把已产生的 assistant 文本落历史
 		if assistantBuf != "" && !needTools {
@@ -92,7 +146,7 @@ func (h *Host) StreamChatOpenAI(
 		// 如果本轮不需要工具，说明模型已经给出最终答案
 		if !needTools {
 			historyOpenAI[id] = hist
-			_ = emit(constant.SSEEventDone, map[string]any{"reason": "completed"})
+			_ = emit(constant.SSEEventDone, h.doneEvent(id, "completed", served))
 			return nil
 		}
 
@@ -100,7 +154,7 @@ func (h *Host) StreamChatOpenAI(
 		if len(acc.Choices) == 0 || len(acc.Choices[0].Message.ToolCalls) == 0 {
 			// 偶发兜底：标记需要工具但没聚合到（理论上不会发生）
 			historyOpenAI[id] = hist
-			_ = emit(constant.SSEEventDone, map[string]any{"reason": "no_tool_details"})
+			_ = emit(constant.SSEEventDone, h.doneEvent(id, "no_tool_details", served))
 			return nil
 		}
 
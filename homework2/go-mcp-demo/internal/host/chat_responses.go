@@ -0,0 +1,139 @@
+package host
+
+import (
+	"context"
+
+	"github.com/FantasyRL/go-mcp-demo/pkg/base/ai_provider"
+	"github.com/FantasyRL/go-mcp-demo/pkg/constant"
+	openai "github.com/openai/openai-go/v2"
+	"github.com/openai/openai-go/v2/responses"
+)
+
+// streamChatResponses 服务商配置 api: responses 时的对话循环，SSE 事件与 StreamChatOpenAI 一致
+// 第一轮发送完整历史；工具轮次只发送 function_call_output，并用 previous_response_id 接上一轮的响应，
+// 推理条目与内置工具的结果留在服务端，不需要再传回。历史仍按 Chat Completions 格式保存，切换模型后可以继续对话
+func (h *Host) streamChatResponses(
+	ctx context.Context,
+	id int64,
+	prompt string,
+	hist []openai.ChatCompletionMessageParamUnion,
+	tools []openai.ChatCompletionToolUnionParam,
+	emit func(event string, v any) error,
+) error {
+	params := openai.ChatCompletionNewParams{
+		Model:    openai.ChatModel(h.model),
+		Messages: withImagesOpenAI(withSystemPromptOpenAI(prompt, hist)),
+		Tools:    tools,
+		// ResponsesRequest 会转换为 text.format
+		ResponseFormat: h.responseFormat(),
+	}
+	ai_provider.ApplyOpenAIOptions(&params, h.options)
+	req, err := ai_provider.ResponsesRequest(params)
+	if err != nil {
+		return err
+	}
+	req.Store = openai.Bool(true)
+
+	var served ai_provider.ModelInfo
+	round := 0
+	for {
+		round++
+		if round > maxToolRounds {
+			historyOpenAI[id] = hist
+			_ = emit(constant.SSEEventDone, h.doneEvent(id, "tool_round_limit", served))
+			return nil
+		}
+
+		var assistantBuf string
+		var calls []responses.ResponseOutputItemUnion
+		var resp responses.Response
+		served, err = h.aiProviderCli.ChatStreamResponses(ctx, req, func(ev *responses.ResponseStreamEventUnion) error {
+			switch ev.Type {
+			case "response.output_text.delta":
+				assistantBuf += ev.Delta
+				_ = emit(constant.SSEEventDelta, map[string]any{"text": ev.Delta})
+			case "response.reasoning_summary_text.delta", "response.reasoning_text.delta":
+				// 推理条目由服务端按 previous_response_id 保留，这里只推给前端
+				_ = emit(constant.SSEEventReasoning, map[string]any{"text": ev.Delta})
+			case "response.output_item.done":
+				// 内置工具（web_search_call 等）由服务端执行，这里只处理函数调用
+				if ev.Item.Type == "function_call" {
+					calls = append(calls, ev.Item)
+				}
+			case "response.completed", "response.incomplete":
+				resp = ev.Response
+			}
+			return nil
+		})
+		h.recordUsage(id, served, resp.Usage.InputTokens, resp.Usage.OutputTokens)
+		if err != nil {
+			return err
+		}
+
+		if len(calls) == 0 {
+			if assistantBuf != "" {
+				hist = append(hist, openai.AssistantMessage(assistantBuf))
+			}
+			historyOpenAI[id] = hist
+			reason := "completed"
+			if resp.Status == responses.ResponseStatusIncomplete {
+				reason = "incomplete"
+			}
+			_ = emit(constant.SSEEventDone, h.doneEvent(id, reason, served))
+			return nil
+		}
+		if resp.ID == "" {
+			// 没有收到 response.completed，无法接着上一轮继续
+			historyOpenAI[id] = hist
+			_ = emit(constant.SSEEventDone, h.doneEvent(id, "no_response_id", served))
+			return nil
+		}
+
+		toolCalls := make([]openai.ChatCompletionMessageToolCallUnionParam, 0, len(calls))
+		for _, c := range calls {
+			toolCalls = append(toolCalls, openai.ChatCompletionMessageToolCallUnionParam{
+				OfFunction: &openai.ChatCompletionMessageFunctionToolCallParam{
+					ID:       c.CallID,
+					Function: openai.ChatCompletionMessageFunctionToolCallFunctionParam{Name: c.Name, Arguments: c.Arguments},
+				},
+			})
+		}
+		_ = emit(constant.SSEEventStartToolCall, map[string]any{
+			"tool_calls": toolCalls,
+			"round":      round,
+		})
+		assistantWithCalls := openai.ChatCompletionAssistantMessageParam{ToolCalls: toolCalls}
+		if assistantBuf != "" {
+			assistantWithCalls.Content.OfString = openai.String(assistantBuf)
+		}
+		hist = append(hist, openai.ChatCompletionMessageParamUnion{OfAssistant: &assistantWithCalls})
+
+		outputs := make(responses.ResponseInputParam, 0, len(calls))
+		for _, c := range calls {
+			args := parseOpenAIToolArgs(c.Arguments)
+			_ = emit(constant.SSEEventToolCall, map[string]any{
+				"round": round,
+				"name":  c.Name,
+				"args":  args,
+			})
+
+			out, callErr := h.mcpCli.CallTool(ctx, c.Name, args)
+			if callErr != nil {
+				out = "tool error: " + callErr.Error()
+			}
+
+			_ = emit(constant.SSEEventToolResult, map[string]any{
+				"round":  round,
+				"name":   c.Name,
+				"result": out,
+			})
+			hist = append(hist, openai.ToolMessage(out, c.CallID))
+			outputs = append(outputs, responses.ResponseInputItemParamOfFunctionCallOutput(c.CallID, out))
+		}
+
+		// 下一轮只发送工具结果，上下文由服务端按 previous_response_id 取回（instructions 不会继承，保持原值）
+		req.Model = served.ID
+		req.PreviousResponseID = openai.String(resp.ID)
+		req.Input = responses.ResponseNewParamsInputUnion{OfInputItemList: outputs}
+	}
+}
@@ -2,9 +2,14 @@ package host
 
 import (
 	"context"
+	"slices"
+
+	"github.com/FantasyRL/go-mcp-demo/config"
+	"github.com/FantasyRL/go-mcp-demo/internal/usage"
 	"github.com/FantasyRL/go-mcp-demo/pkg/base"
 	"github.com/FantasyRL/go-mcp-demo/pkg/base/ai_provider"
 	"github.com/FantasyRL/go-mcp-demo/pkg/base/mcp_client"
+	"github.com/FantasyRL/go-mcp-demo/pkg/logger"
 	"github.com/openai/openai-go/v2"
 )
 
@@ -16,6 +21,21 @@ type Host struct {
 	ctx           context.Context
 	mcpCli        mcp_client.ToolClient
 	aiProviderCli *ai_provider.Client
+	usage         UsageRecorder
+	store         *usage.Store
+	model         string   // 模型引用，为空时使用 ai_provider.model
+	images        []string // 本轮用户消息附带的图片引用，见 ImageStore
+	// 要求最终回答符合的 JSON Schema，见 WithResponseSchema
+	responseSchema map[string]any
+	// 本次对话覆盖的生成参数，未设置的使用服务商配置的 options
+	options config.OllamaOptions
+	calls   []CallUsage           // 本次请求中每次模型调用的用量，见 Usage
+	served  ai_provider.ModelInfo // 最近一次实际提供服务的模型，结构化输出修复时沿用
+}
+
+// UsageRecorder 记录每次模型调用消耗的 token，用于每日配额统计
+type UsageRecorder interface {
+	RecordUsage(userID int64, promptTokens, completionTokens int64)
 }
 
 func NewHost(ctx context.Context, clientSet *base.ClientSet) *Host {
@@ -25,3 +45,121 @@ func NewHost(ctx context.Context, clientSet *base.ClientSet) *Host {
 		aiProviderCli: clientSet.AiProviderCli,
 	}
 }
+
+// WithUsageRecorder 设置 token 用量记录器
+func (h *Host) WithUsageRecorder(r UsageRecorder) *Host {
+	h.usage = r
+	return h
+}
+
+// WithModel 指定本次对话使用的模型，格式见 config.AiProviderConfig.ResolveModel
+func (h *Host) WithModel(model string) *Host {
+	h.model = model
+	return h
+}
+
+// WithImages 本轮用户消息附带的图片，refs 为 ImageStore.Normalize 返回的引用
+func (h *Host) WithImages(refs []string) *Host {
+	h.images = refs
+	return h
+}
+
+// WithOptions 覆盖本次对话的生成参数（temperature、max_tokens 等），未设置的字段使用服务商配置
+func (h *Host) WithOptions(o config.OllamaOptions) *Host {
+	h.options = o
+	return h
+}
+
+// doneEvent SSE done 事件，附带实际提供服务的服务商与模型（可能是 fallback）以及本轮的 token 用量
+func (h *Host) doneEvent(id int64, reason string, served ai_provider.ModelInfo) map[string]any {
+	return map[string]any{
+		"reason":   reason,
+		"provider": served.Provider,
+		"model":    served.Model,
+		"usage":    h.Usage(id),
+	}
+}
+
+// systemPrompt 当前的 cli.system_prompt，每个请求开始时读取一次，工具轮次沿用同一个提示词
+func systemPrompt() string {
+	if cli := config.CLI(); cli != nil {
+		return cli.SystemPrompt
+	}
+	return ""
+}
+
+// withSystemPrompt 每次请求时在历史前加上系统提示词，系统提示词不写入历史，热更新后对已有会话同样生效
+func withSystemPrompt(prompt string, hist []ai_provider.Message) []ai_provider.Message {
+	if prompt == "" {
+		return hist
+	}
+	return append([]ai_provider.Message{{Role: "system", Content: prompt}}, hist...)
+}
+
+func withSystemPromptOpenAI(prompt string, hist []openai.ChatCompletionMessageParamUnion) []openai.ChatCompletionMessageParamUnion {
+	if prompt == "" {
+		return hist
+	}
+	return append([]openai.ChatCompletionMessageParamUnion{openai.SystemMessage(prompt)}, hist...)
+}
+
+// userMessageOpenAI 用户消息，带图片时使用 text + image_url 内容块，image_url 中保存的是图片引用
+func userMessageOpenAI(msg string, refs []string) openai.ChatCompletionMessageParamUnion {
+	if len(refs) == 0 {
+		return openai.UserMessage(msg)
+	}
+	parts := []openai.ChatCompletionContentPartUnionParam{openai.TextContentPart(msg)}
+	for _, ref := range refs {
+		parts = append(parts, openai.ImageContentPart(openai.ChatCompletionContentPartImageImageURLParam{URL: ref}))
+	}
+	return openai.UserMessage(parts)
+}
+
+// withImages 发送前把历史中的图片引用换成 ollama 需要的 base64，读取失败的图片跳过，历史本身不变
+func withImages(hist []ai_provider.Message) []ai_provider.Message {
+	out := slices.Clone(hist)
+	for i, m := range hist {
+		if len(m.Images) == 0 {
+			continue
+		}
+		imgs := make([]string, 0, len(m.Images))
+		for _, ref := range m.Images {
+			b64, err := Images().Base64(ref)
+			if err != nil {
+				logger.Warnf("host: skip image %s: %v", ref, err)
+				continue
+			}
+			imgs = append(imgs, b64)
+		}
+		out[i].Images = imgs
+	}
+	return out
+}
+
+// withImagesOpenAI 发送前把历史中的图片引用换成 data URL，读取失败的图片跳过，历史本身不变
+func withImagesOpenAI(hist []openai.ChatCompletionMessageParamUnion) []openai.ChatCompletionMessageParamUnion {
+	out := slices.Clone(hist)
+	for i, m := range hist {
+		if m.OfUser == nil || len(m.OfUser.Content.OfArrayOfContentParts) == 0 {
+			continue
+		}
+		user := *m.OfUser
+		parts := make([]openai.ChatCompletionContentPartUnionParam, 0, len(user.Content.OfArrayOfContentParts))
+		for _, p := range user.Content.OfArrayOfContentParts {
+			if p.OfImageURL != nil {
+				url, err := Images().URL(p.OfImageURL.ImageURL.URL)
+				if err != nil {
+					logger.Warnf("host: skip image %s: %v", p.OfImageURL.ImageURL.URL, err)
+					continue
+				}
+				img := *p.OfImageURL
+				img.ImageURL.URL = url
+				p = openai.ChatCompletionContentPartUnionParam{OfImageURL: &img}
+			}
+			parts = append(parts, p)
+		}
+		user.Content.OfArrayOfContentParts = parts
+		out[i] = openai.ChatCompletionMessageParamUnion{OfUser: &user}
+	}
+	return out
+}
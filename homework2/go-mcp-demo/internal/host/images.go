@@ -0,0 +1,203 @@
+package host
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/FantasyRL/go-mcp-demo/config"
+	"github.com/FantasyRL/go-mcp-demo/pkg/constant"
+	"github.com/FantasyRL/go-mcp-demo/pkg/errno"
+	"github.com/FantasyRL/go-mcp-demo/pkg/utils"
+)
+
+// ImageStore 对话中的图片：上传的图片按内容的 sha256 保存，历史中只保存引用 "image:<sha256>"，调用模型时再读出
+// http(s) 链接不下载，原样作为引用交给支持链接的服务商
+type ImageStore struct {
+	dir     string // 为空时保存在内存中，重启后丢失
+	maxSize int64
+
+	// 内存存储总量不超过 memMax 字节，超出时淘汰最久未使用的图片，历史中引用它的消息会读不到图片
+	mu      sync.Mutex
+	memMax  int64
+	memUsed int64
+	lru     *list.List // 最近使用的在前，元素为 *memImage
+	mem     map[string]*list.Element
+}
+
+type memImage struct {
+	key  string
+	data []byte
+}
+
+var (
+	imagesOnce sync.Once
+	images     *ImageStore
+)
+
+// Images 按 cli.image_dir / cli.max_image_size 创建的全局图片存储，与对话历史一样在进程内共享
+func Images() *ImageStore {
+	imagesOnce.Do(func() {
+		var dir string
+		var maxSize int64
+		if cli := config.CLI(); cli != nil {
+			dir, maxSize = cli.ImageDir, cli.MaxImageSize
+		}
+		images = NewImageStore(dir, maxSize)
+	})
+	return images
+}
+
+// NewImageStore 创建图片存储，maxSize <= 0 时使用 constant.MaxImageSize
+func NewImageStore(dir string, maxSize int64) *ImageStore {
+	if maxSize <= 0 {
+		maxSize = constant.MaxImageSize
+	}
+	return &ImageStore{
+		dir:     dir,
+		maxSize: maxSize,
+		memMax:  constant.ImageMemMaxBytes,
+		lru:     list.New(),
+		mem:     map[string]*list.Element{},
+	}
+}
+
+// Save 保存一张图片，返回引用；相同内容的图片只保存一份
+func (s *ImageStore) Save(data []byte) (string, error) {
+	if int64(len(data)) > s.maxSize {
+		return "", errno.ParamError.WithMessage(fmt.Sprintf("image too large: %d bytes, max %d", len(data), s.maxSize))
+	}
+	if mime := http.DetectContentType(data); !strings.HasPrefix(mime, "image/") {
+		return "", errno.ParamError.WithMessage(fmt.Sprintf("unsupported image type %q", mime))
+	}
+	sum := sha256.Sum256(data)
+	key := hex.EncodeToString(sum[:])
+	if s.dir == "" {
+		s.memSave(key, data)
+		return constant.ImageRefPrefix + key, nil
+	}
+	path := filepath.Join(s.dir, key)
+	if _, err := os.Stat(path); err == nil {
+		return constant.ImageRefPrefix + key, nil
+	}
+	// 并发上传同一张图片时各自写临时文件，rename 结果相同
+	if err := utils.WriteFileAtomic(path, data, 0o600); err != nil {
+		return "", err
+	}
+	return constant.ImageRefPrefix + key, nil
+}
+
+func (s *ImageStore) memSave(key string, data []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if el, ok := s.mem[key]; ok {
+		s.lru.MoveToFront(el)
+		return
+	}
+	s.mem[key] = s.lru.PushFront(&memImage{key: key, data: data})
+	s.memUsed += int64(len(data))
+	// 至少保留刚保存的这一张
+	for s.memUsed > s.memMax && s.lru.Len() > 1 {
+		old := s.lru.Remove(s.lru.Back()).(*memImage)
+		delete(s.mem, old.key)
+		s.memUsed -= int64(len(old.data))
+	}
+}
+
+func (s *ImageStore) memLoad(key string) ([]byte, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	el, ok := s.mem[key]
+	if !ok {
+		return nil, false
+	}
+	s.lru.MoveToFront(el)
+	return el.Value.(*memImage).data, true
+}
+
+// Normalize 把请求中的图片转为引用，每一项可以是：
+// - Save 返回的引用 "image:<sha256>"
+// - http(s) 链接
+// - data:image/...;base64,... 或裸 base64
+func (s *ImageStore) Normalize(inputs []string) ([]string, error) {
+	refs := make([]string, 0, len(inputs))
+	for _, in := range inputs {
+		in = strings.TrimSpace(in)
+		switch {
+		case in == "":
+			continue
+		case strings.HasPrefix(in, constant.ImageRefPrefix):
+			if _, err := s.load(in); err != nil {
+				return nil, err
+			}
+			refs = append(refs, in)
+		case strings.HasPrefix(in, "http://") || strings.HasPrefix(in, "https://"):
+			refs = append(refs, in)
+		default:
+			if _, rest, ok := strings.Cut(in, ";base64,"); ok && strings.HasPrefix(in, "data:") {
+				in = rest
+			}
+			data, err := base64.StdEncoding.DecodeString(in)
+			if err != nil {
+				return nil, errno.ParamError.WithMessage("image must be an uploaded image reference, http(s) URL or base64 data")
+			}
+			ref, err := s.Save(data)
+			if err != nil {
+				return nil, err
+			}
+			refs = append(refs, ref)
+		}
+	}
+	return refs, nil
+}
+
+func (s *ImageStore) load(ref string) ([]byte, error) {
+	key := strings.TrimPrefix(ref, constant.ImageRefPrefix)
+	if len(key) != sha256.Size*2 {
+		return nil, errno.ParamError.WithMessage(fmt.Sprintf("invalid image reference %q", ref))
+	}
+	if s.dir == "" {
+		data, ok := s.memLoad(key)
+		if !ok {
+			return nil, errno.ParamError.WithMessage(fmt.Sprintf("image %q not found", ref))
+		}
+		return data, nil
+	}
+	data, err := os.ReadFile(filepath.Join(s.dir, key))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, errno.ParamError.WithMessage(fmt.Sprintf("image %q not found", ref))
+	}
+	return data, err
+}
+
+// URL 返回发送给 OpenAI 兼容接口的图片地址：上传的图片转为 data URL，链接原样返回
+func (s *ImageStore) URL(ref string) (string, error) {
+	if !strings.HasPrefix(ref, constant.ImageRefPrefix) {
+		return ref, nil
+	}
+	data, err := s.load(ref)
+	if err != nil {
+		return "", err
+	}
+	return "data:" + http.DetectContentType(data) + ";base64," + base64.StdEncoding.EncodeToString(data), nil
+}
+
+// Base64 返回发送给 ollama /api/chat 的图片（不带 data: 前缀），ollama 不支持链接
+func (s *ImageStore) Base64(ref string) (string, error) {
+	if !strings.HasPrefix(ref, constant.ImageRefPrefix) {
+		return "", errno.ParamError.WithMessage("ollama models only accept uploaded or base64 images, not URLs")
+	}
+	data, err := s.load(ref)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(data), nil
+}
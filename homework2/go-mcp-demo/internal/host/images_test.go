@@ -0,0 +1,112 @@
+package host
+
+import (
+	"encoding/base64"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/FantasyRL/go-mcp-demo/pkg/constant"
+	"github.com/openai/openai-go/v2"
+)
+
+// 1x1 PNG
+var pngData, _ = base64.StdEncoding.DecodeString("iVBORw0KGgoAAAANSUhEUgAAAAEAAAABCAYAAAAfFcSJAAAADUlEQVR42mP8z8BQDwAEhQGAhKmMIQAAAABJRU5ErkJggg==")
+
+func TestImageStore(t *testing.T) {
+	for _, dir := range []string{"", t.TempDir()} {
+		s := NewImageStore(dir, 0)
+		b64 := base64.StdEncoding.EncodeToString(pngData)
+		refs, err := s.Normalize([]string{b64, "data:image/png;base64," + b64, "https://example.com/a.png"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		// 相同内容得到相同引用，链接原样保留
+		if len(refs) != 3 || !strings.HasPrefix(refs[0], constant.ImageRefPrefix) || refs[0] != refs[1] || refs[2] != "https://example.com/a.png" {
+			t.Fatalf("refs = %v", refs)
+		}
+		if _, err := s.Normalize(refs[:1]); err != nil {
+			t.Fatal(err)
+		}
+		if url, _ := s.URL(refs[0]); url != "data:image/png;base64,"+b64 {
+			t.Fatalf("url = %q", url)
+		}
+		if got, _ := s.Base64(refs[0]); got != b64 {
+			t.Fatalf("base64 = %q", got)
+		}
+		if _, err := s.Base64(refs[2]); err == nil {
+			t.Fatal("ollama should reject URL images")
+		}
+		if _, err := s.Normalize([]string{constant.ImageRefPrefix + strings.Repeat("0", 64)}); err == nil {
+			t.Fatal("unknown reference should be rejected")
+		}
+		if _, err := s.Save([]byte("not an image")); err == nil {
+			t.Fatal("non-image data should be rejected")
+		}
+	}
+	if _, err := NewImageStore("", 10).Save(pngData); err == nil {
+		t.Fatal("oversized image should be rejected")
+	}
+}
+
+func TestWithImagesOpenAI(t *testing.T) {
+	ref, err := Images().Save(pngData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hist := []openai.ChatCompletionMessageParamUnion{userMessageOpenAI("what is this?", []string{ref})}
+	out := withImagesOpenAI(hist)
+	if url := out[0].OfUser.Content.OfArrayOfContentParts[1].OfImageURL.ImageURL.URL; !strings.HasPrefix(url, "data:image/png;base64,") {
+		t.Fatalf("url = %q", url)
+	}
+	// 历史中仍然只有引用
+	if url := hist[0].OfUser.Content.OfArrayOfContentParts[1].OfImageURL.ImageURL.URL; url != ref {
+		t.Fatalf("history url = %q", url)
+	}
+}
+
+func TestImageStoreLimits(t *testing.T) {
+	// 内存存储超过上限时淘汰最久未使用的图片
+	img := func(i byte) []byte { return append(append([]byte{}, pngData...), i) }
+	s := NewImageStore("", 0)
+	s.memMax = int64(len(pngData)+1) * 2
+	a, _ := s.Save(img(1))
+	b, _ := s.Save(img(2))
+	if _, err := s.URL(a); err != nil {
+		t.Fatal(err)
+	}
+	c, _ := s.Save(img(3))
+	if _, err := s.URL(b); err == nil {
+		t.Fatal("least recently used image should be evicted")
+	}
+	if _, err := s.URL(a); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.URL(c); err != nil {
+		t.Fatal(err)
+	}
+
+	// 并发上传同一张图片到磁盘
+	dir := t.TempDir()
+	s = NewImageStore(dir, 0)
+	var wg sync.WaitGroup
+	errs := make(chan error, 8)
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := s.Save(pngData); err != nil {
+				errs <- err
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatal(err)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Fatalf("entries = %v", entries)
+	}
+}
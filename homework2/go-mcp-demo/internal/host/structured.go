@@ -0,0 +1,150 @@
+package host
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/FantasyRL/go-mcp-demo/pkg/base/ai_provider"
+	"github.com/FantasyRL/go-mcp-demo/pkg/errno"
+	"github.com/FantasyRL/go-mcp-demo/pkg/utils/jsonschema"
+	"github.com/openai/openai-go/v2"
+)
+
+const maxSchemaErrors = 10 // 修复提示中最多列出的校验错误数
+
+// WithResponseSchema 要求最终回答为符合 schema 的 JSON，只支持非流式 Chat，流式对话会返回错误
+func (h *Host) WithResponseSchema(schema map[string]any) *Host {
+	h.responseSchema = schema
+	return h
+}
+
+// rejectResponseSchema 流式对话边生成边推送，无法在推送前校验与修复回答
+func (h *Host) rejectResponseSchema() error {
+	if h.responseSchema != nil {
+		return errno.ParamError.WithMessage("response_schema is only supported by the non-streaming chat API")
+	}
+	return nil
+}
+
+// ollamaFormat /api/chat 的 format，未设置 schema 时为 nil（避免把 nil map 序列化为 null）
+func (h *Host) ollamaFormat() any {
+	if h.responseSchema == nil {
+		return nil
+	}
+	return h.responseSchema
+}
+
+// responseFormat OpenAI 兼容接口的 response_format，未设置 schema 时为空
+func (h *Host) responseFormat() openai.ChatCompletionNewParamsResponseFormatUnion {
+	if h.responseSchema == nil {
+		return openai.ChatCompletionNewParamsResponseFormatUnion{}
+	}
+	return ai_provider.JSONSchemaFormat(h.responseSchema)
+}
+
+// checkStructured 按 schema 校验回答，返回去掉代码块标记后的 JSON 与错误描述，符合时错误描述为空
+func checkStructured(schema map[string]any, answer string) (string, string) {
+	answer = strings.TrimSpace(answer)
+	if s, ok := strings.CutPrefix(answer, "```"); ok {
+		// ```json ... ```
+		if i := strings.IndexByte(s, '\n'); i >= 0 {
+			s = s[i+1:]
+		}
+		answer = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(s), "```"))
+	}
+	var v any
+	if err := json.Unmarshal([]byte(answer), &v); err != nil {
+		return answer, "reply is not valid JSON: " + err.Error()
+	}
+	errs := jsonschema.New(schema).Validate(v)
+	if len(errs) == 0 {
+		return answer, ""
+	}
+	msgs := make([]string, 0, min(len(errs), maxSchemaErrors))
+	for _, e := range errs[:min(len(errs), maxSchemaErrors)] {
+		path := e.Path
+		if path == "" {
+			path = "(root)"
+		}
+		msgs = append(msgs, path+": "+e.Msg)
+	}
+	if len(errs) > maxSchemaErrors {
+		msgs = append(msgs, fmt.Sprintf("... and %d more", len(errs)-maxSchemaErrors))
+	}
+	return answer, strings.Join(msgs, "; ")
+}
+
+// repairPrompt 回答不符合 schema 时发给模型的修复请求
+func repairPrompt(problems string) string {
+	return "Your previous reply does not match the required JSON Schema: " + problems +
+		"\nReply again with only the corrected JSON, without any other text."
+}
+
+// repair 把本轮对话（历史中已经包含不符合的回答）加上修复提示单独请求一次，不带工具；
+// 修复提示与修复前的回答都不写入历史
+func (h *Host) repair(id int64, ollama bool, problems string) (string, error) {
+	prompt := systemPrompt()
+	// 继续使用本轮实际提供服务的模型（可能是 fallback）
+	model := h.model
+	if h.served.ID != "" {
+		model = h.served.ID
+	}
+	if ollama {
+		msgs := append(slices.Clone(history[id]), ai_provider.Message{Role: "user", Content: repairPrompt(problems)})
+		resp, served, err := h.aiProviderCli.Chat(h.ctx, ai_provider.ChatRequest{
+			Model:     model,
+			Messages:  withImages(withSystemPrompt(prompt, msgs)),
+			Format:    h.ollamaFormat(),
+			Options:   ai_provider.BuildOptions(h.options),
+			KeepAlive: h.options.KeepAlive,
+		})
+		if err != nil {
+			return "", err
+		}
+		h.recordUsage(id, served, resp.PromptEvalCount, resp.EvalCount)
+		return resp.Message.Content, nil
+	}
+
+	msgs := append(slices.Clone(historyOpenAI[id]), openai.UserMessage(repairPrompt(problems)))
+	req := openai.ChatCompletionNewParams{
+		Model:          openai.ChatModel(model),
+		Messages:       withImagesOpenAI(withSystemPromptOpenAI(prompt, msgs)),
+		ResponseFormat: h.responseFormat(),
+		StreamOptions:  openai.ChatCompletionStreamOptionsParam{IncludeUsage: openai.Bool(true)},
+	}
+	ai_provider.ApplyOpenAIOptions(&req, h.options)
+	var acc openai.ChatCompletionAccumulator
+	served, err := h.aiProviderCli.ChatStreamOpenAI(h.ctx, req, func(chunk *openai.ChatCompletionChunk) error {
+		acc.AddChunk(*chunk)
+		return nil
+	})
+	h.recordUsage(id, served, acc.Usage.PromptTokens, acc.Usage.CompletionTokens)
+	if err != nil {
+		return "", err
+	}
+	if len(acc.Choices) == 0 {
+		return "", nil
+	}
+	return acc.Choices[0].Message.Content, nil
+}
+
+// setAnswer 用校验通过的回答替换本轮写入历史的最终回答
+func (h *Host) setAnswer(id int64, ollama bool, answer string) {
+	if ollama {
+		hist := history[id]
+		if n := len(hist); n > 0 && hist[n-1].Role == "assistant" && len(hist[n-1].ToolCalls) == 0 {
+			hist[n-1].Content = answer
+			return
+		}
+		history[id] = append(hist, ai_provider.Message{Role: "assistant", Content: answer})
+		return
+	}
+	hist := historyOpenAI[id]
+	if n := len(hist); n > 0 && hist[n-1].OfAssistant != nil && len(hist[n-1].OfAssistant.ToolCalls) == 0 {
+		hist[n-1] = openai.AssistantMessage(answer)
+		return
+	}
+	historyOpenAI[id] = append(hist, openai.AssistantMessage(answer))
+}
@@ -0,0 +1,113 @@
+package host
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/FantasyRL/go-mcp-demo/config"
+	"github.com/FantasyRL/go-mcp-demo/pkg/base/ai_provider"
+	"github.com/FantasyRL/go-mcp-demo/pkg/constant"
+	"github.com/openai/openai-go/v2"
+)
+
+func TestCheckStructured(t *testing.T) {
+	schema := map[string]any{
+		"type":       "object",
+		"properties": map[string]any{"city": map[string]any{"type": "string"}},
+		"required":   []any{"city"},
+	}
+	// 模型常把 JSON 包在代码块里
+	out, problems := checkStructured(schema, "```json\n{\"city\": \"Fuzhou\"}\n```")
+	if problems != "" || out != `{"city": "Fuzhou"}` {
+		t.Fatalf("out = %q, problems = %q", out, problems)
+	}
+	if _, problems = checkStructured(schema, "the city is Fuzhou"); !strings.Contains(problems, "not valid JSON") {
+		t.Fatalf("problems = %q", problems)
+	}
+	if _, problems = checkStructured(schema, `{"city": 1}`); problems == "" {
+		t.Fatal("schema violation should be reported")
+	}
+}
+
+type noTools struct{}
+
+func (noTools) ConvertToolsToOllama() []map[string]any                      { return nil }
+func (noTools) ConvertToolsToOpenAI() []openai.ChatCompletionToolUnionParam { return nil }
+func (noTools) CallTool(context.Context, string, any) (string, error)       { return "", nil }
+func (noTools) Close()                                                      {}
+
+func TestChatRepair(t *testing.T) {
+	schema := map[string]any{
+		"type":       "object",
+		"properties": map[string]any{"city": map[string]any{"type": "string"}},
+		"required":   []any{"city"},
+	}
+	var replies []string
+	var requests [][]map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Messages []map[string]any `json:"messages"`
+			Tools    []any            `json:"tools"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		if len(requests) > 0 && len(req.Tools) != 0 {
+			http.Error(w, "repair request should not carry tools", http.StatusBadRequest)
+			return
+		}
+		requests = append(requests, req.Messages)
+		reply := replies[0]
+		replies = replies[1:]
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprintf(w, "data: {\"id\":\"1\",\"object\":\"chat.completion.chunk\",\"model\":\"m\",\"choices\":[{\"index\":0,\"delta\":{\"content\":%q},\"finish_reason\":\"stop\"}]}\n\n", reply)
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	defer srv.Close()
+	config.SetAiProvider(&config.AiProviderConfig{
+		Model: "remote/m",
+		Providers: map[string]config.AiProviderEndpoint{
+			"remote": {Type: constant.AiProviderTypeOpenAI, BaseURL: srv.URL, APIKey: "sk"},
+		},
+	})
+	defer func() { config.SetAiProvider(nil) }()
+	const id = -47
+	defer delete(historyOpenAI, id)
+	newHost := func() *Host {
+		return (&Host{ctx: context.Background(), mcpCli: noTools{}, aiProviderCli: ai_provider.NewAiProviderClient()}).WithResponseSchema(schema)
+	}
+
+	// 修复请求带上本轮的回答与修复提示，历史中只保存修复后的回答
+	replies = []string{"Fuzhou", `{"city": "Fuzhou"}`}
+	out, err := newHost().Chat(id, "where?")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != `{"city": "Fuzhou"}` || len(requests) != 2 {
+		t.Fatalf("out = %q, requests = %d", out, len(requests))
+	}
+	if msgs := requests[1]; len(msgs) != 3 || msgs[1]["content"] != "Fuzhou" || !strings.Contains(msgs[2]["content"].(string), "JSON Schema") {
+		t.Fatalf("repair request = %v", msgs)
+	}
+	hist := historyOpenAI[id]
+	if len(hist) != 2 || hist[1].OfAssistant == nil || hist[1].OfAssistant.Content.OfString.Value != out {
+		t.Fatalf("history = %+v", hist)
+	}
+
+	// 修复后仍不符合：返回错误，本轮不写入历史
+	replies = []string{"Fuzhou", "still Fuzhou"}
+	if _, err := newHost().Chat(id, "again?"); err == nil {
+		t.Fatal("expected error")
+	}
+	if len(historyOpenAI[id]) != 2 {
+		t.Fatalf("history = %+v", historyOpenAI[id])
+	}
+
+	// 流式对话不支持 response_schema
+	if err := newHost().StreamChatOpenAI(context.Background(), id, "hi", func(string, any) error { return nil }); err == nil {
+		t.Fatal("stream chat should reject response_schema")
+	}
+}
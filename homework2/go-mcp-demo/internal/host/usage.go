@@ -0,0 +1,73 @@
+package host
+
+import (
+	"sync"
+
+	"github.com/FantasyRL/go-mcp-demo/internal/usage"
+	"github.com/FantasyRL/go-mcp-demo/pkg/base/ai_provider"
+)
+
+// CallUsage 一次模型调用的用量
+type CallUsage struct {
+	Model string `json:"model"` // 实际提供服务的模型引用，可能是 fallback
+	usage.Usage
+}
+
+// TurnUsage 一次对话请求的用量：本轮合计、每次模型调用以及会话累计
+type TurnUsage struct {
+	usage.Usage
+	Calls        []CallUsage `json:"calls"`
+	Conversation usage.Usage `json:"conversation"`
+}
+
+// 会话（与对话历史一样按用户区分）累计用量
+var (
+	conversationMu    sync.Mutex
+	conversationUsage = make(map[int64]usage.Usage)
+)
+
+// WithUsageStore 设置按用户汇总用量的存储，为 nil 时不持久化
+func (h *Host) WithUsageStore(s *usage.Store) *Host {
+	h.store = s
+	return h
+}
+
+// recordUsage 记录一次模型调用的用量：本轮调用列表、会话累计、每日配额与持久化存储
+func (h *Host) recordUsage(id int64, served ai_provider.ModelInfo, promptTokens, completionTokens int64) {
+	if served.ID != "" {
+		h.served = served
+	}
+	if promptTokens+completionTokens <= 0 {
+		return
+	}
+	u := usage.New(served.ID, promptTokens, completionTokens)
+	h.calls = append(h.calls, CallUsage{Model: served.ID, Usage: u})
+
+	conversationMu.Lock()
+	total := conversationUsage[id]
+	total.Add(u)
+	conversationUsage[id] = total
+	conversationMu.Unlock()
+
+	if h.usage != nil {
+		h.usage.RecordUsage(id, promptTokens, completionTokens)
+	}
+	if h.store != nil {
+		h.store.Add(id, served.ID, u)
+	}
+}
+
+// Usage 本次请求（Host 创建以来）的用量，id 为会话 id
+func (h *Host) Usage(id int64) TurnUsage {
+	t := TurnUsage{Calls: h.calls}
+	if t.Calls == nil {
+		t.Calls = []CallUsage{}
+	}
+	for _, c := range h.calls {
+		t.Usage.Add(c.Usage)
+	}
+	conversationMu.Lock()
+	t.Conversation = conversationUsage[id]
+	conversationMu.Unlock()
+	return t
+}
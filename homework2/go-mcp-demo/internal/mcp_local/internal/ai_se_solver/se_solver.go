@@ -28,7 +28,8 @@ func AIScienceAndEngineeringBuildHtml(ctx context.Context, req mcp.CallToolReque
 		return mcp.NewToolResultError("missing required arg: question"), nil
 	}
 	resp, err := instance.aiProviderCli.ChatOpenAI(ctx, openai.ChatCompletionNewParams{
-		Model: openai.ChatModel(config.AiProvider.Model),
+		// 可以通过 ai_provider.tool_models 为该工具单独指定模型
+		Model: openai.ChatModel(config.AiProvider().ModelForTool(req.Params.Name)),
 		Messages: []openai.ChatCompletionMessageParamUnion{
 			openai.SystemMessage(systemPromptHTMLPrinter),
 			openai.UserMessage(question),
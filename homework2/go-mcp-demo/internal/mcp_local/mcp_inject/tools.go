@@ -28,6 +28,8 @@ func WithDevRunnerTools() tool_set.Option {
 			mcp.WithNumber("depth", mcp.Description("Max depth to traverse (default 4)")),
 			// ignore 如 node_modules, *.log
 			mcp.WithString("ignore", mcp.Description("Comma-separated glob patterns to ignore (optional)")),
+			// 只读工具，实例故障时 host 可以换实例重试
+			mcp.WithReadOnlyHintAnnotation(true),
 		)
 		toolSet.Tools = append(toolSet.Tools, &toolTree)
 		toolSet.HandlerFunc[toolTree.Name] = dev_runner.HandleFsTree
@@ -39,6 +41,7 @@ func WithDevRunnerTools() tool_set.Option {
 			mcp.WithString("path", mcp.Required(), mcp.Description("File path to read")),
 			// 最大读取字节数
 			mcp.WithNumber("max_bytes", mcp.Description("Max bytes to read (default 65536)")),
+			mcp.WithReadOnlyHintAnnotation(true),
 		)
 		toolSet.Tools = append(toolSet.Tools, &toolCat)
 		toolSet.HandlerFunc[toolCat.Name] = dev_runner.HandleFsCat
@@ -81,6 +84,7 @@ func WithTimeTool() tool_set.Option {
 		newTool := mcp.NewTool(
 			"time_now",
 			mcp.WithDescription("返回当前时间（RFC3339）"),
+			mcp.WithReadOnlyHintAnnotation(true),
 		)
 		toolFunc := func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 			now := time.Now().Format(time.RFC3339)
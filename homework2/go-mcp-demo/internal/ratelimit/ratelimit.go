@@ -0,0 +1,236 @@
+package ratelimit
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/FantasyRL/go-mcp-demo/config"
+	"github.com/FantasyRL/go-mcp-demo/pkg/errno"
+)
+
+// Rule 单个调用方（用户或 API Key）的限制，0 表示不限制
+type Rule struct {
+	RequestsPerMinute    int
+	MaxConcurrentStreams int
+}
+
+type Options struct {
+	User            Rule
+	APIKey          Rule
+	DailyTokenQuota int64 // 每个用户每天可消耗的 LLM token，0 表示不限制
+}
+
+// Status 本次请求命中的限额信息，用于填充 X-RateLimit-* 响应头
+type Status struct {
+	Limit     int
+	Remaining int
+	Reset     time.Time // 当前窗口结束时间
+
+	TokenLimit     int64 // 0 表示未配置 token 配额
+	TokenRemaining int64
+}
+
+var (
+	ErrTooManyRequests = errno.NewErrNo(errno.BizLimitCode, "请求过于频繁，请稍后再试")
+	ErrTooManyStreams  = errno.NewErrNo(errno.BizLimitCode, "并发流式请求过多，请等待已有请求结束")
+	ErrQuotaExceeded   = errno.NewErrNo(errno.BizLimitCode, "今日 token 配额已用完")
+)
+
+// Limiter 按用户与 API Key 做每分钟请求数、并发流数限制，并统计每日 token 消耗
+// 状态只保存在当前进程内存中，多实例部署时每个实例单独计数
+type Limiter struct {
+	opts Options
+	now  func() time.Time
+
+	mu      sync.Mutex
+	windows map[string]*window
+	streams map[string]int
+	tokens  map[int64]*dayUsage
+}
+
+// window 固定一分钟窗口的计数
+type window struct {
+	start time.Time
+	count int
+}
+
+type dayUsage struct {
+	day    string
+	tokens int64
+}
+
+func New(opts Options) *Limiter {
+	return &Limiter{
+		opts:    opts,
+		now:     time.Now,
+		windows: make(map[string]*window),
+		streams: make(map[string]int),
+		tokens:  make(map[int64]*dayUsage),
+	}
+}
+
+func userKey(uid int64) string          { return fmt.Sprintf("user:%d", uid) }
+func apiKeyKey(id string) string        { return "key:" + id }
+func (l *Limiter) day() string          { return l.now().Format(time.DateOnly) }
+func minuteStart(t time.Time) time.Time { return t.Truncate(time.Minute) }
+
+// Allow 检查并计入一次请求；apiKeyID 为空表示通过访问令牌认证
+// 返回的 Status 取用户与 API Key 中剩余次数更少的一方
+func (l *Limiter) Allow(uid int64, apiKeyID string) (Status, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	st := Status{Limit: -1, Reset: minuteStart(now).Add(time.Minute)}
+	if l.opts.DailyTokenQuota > 0 {
+		used := l.usedLocked(uid)
+		st.TokenLimit = l.opts.DailyTokenQuota
+		st.TokenRemaining = max(l.opts.DailyTokenQuota-used, 0)
+		if st.TokenRemaining == 0 {
+			return st, ErrQuotaExceeded
+		}
+	}
+
+	type check struct {
+		key   string
+		limit int
+	}
+	checks := []check{{userKey(uid), l.opts.User.RequestsPerMinute}}
+	if apiKeyID != "" {
+		checks = append(checks, check{apiKeyKey(apiKeyID), l.opts.APIKey.RequestsPerMinute})
+	}
+	// 先全部检查再计数，避免被拒绝的请求占用另一方的额度
+	for _, ck := range checks {
+		if ck.limit <= 0 {
+			continue
+		}
+		w := l.windowLocked(ck.key, now)
+		remaining := ck.limit - w.count
+		if st.Limit < 0 || remaining < st.Remaining {
+			st.Limit, st.Remaining = ck.limit, remaining
+		}
+		if remaining <= 0 {
+			st.Remaining = 0
+			return st, ErrTooManyRequests
+		}
+	}
+	for _, ck := range checks {
+		if ck.limit > 0 {
+			l.windowLocked(ck.key, now).count++
+		}
+	}
+	if st.Limit >= 0 {
+		st.Remaining--
+	}
+	return st, nil
+}
+
+func (l *Limiter) windowLocked(key string, now time.Time) *window {
+	start := minuteStart(now)
+	w, ok := l.windows[key]
+	if !ok || !w.start.Equal(start) {
+		if !ok && len(l.windows) > 0 {
+			l.pruneLocked(start)
+		}
+		w = &window{start: start}
+		l.windows[key] = w
+	}
+	return w
+}
+
+// pruneLocked 清理已经过期的窗口，避免长时间运行后 map 无限增长
+func (l *Limiter) pruneLocked(start time.Time) {
+	for k, w := range l.windows {
+		if w.start.Before(start) {
+			delete(l.windows, k)
+		}
+	}
+}
+
+// AcquireStream 占用一个并发流名额，返回的 release 必须在流结束后调用
+func (l *Limiter) AcquireStream(uid int64, apiKeyID string) (release func(), err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	keys := map[string]int{userKey(uid): l.opts.User.MaxConcurrentStreams}
+	if apiKeyID != "" {
+		keys[apiKeyKey(apiKeyID)] = l.opts.APIKey.MaxConcurrentStreams
+	}
+	for k, limit := range keys {
+		if limit > 0 && l.streams[k] >= limit {
+			return nil, ErrTooManyStreams
+		}
+	}
+	for k := range keys {
+		l.streams[k]++
+	}
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			l.mu.Lock()
+			defer l.mu.Unlock()
+			for k := range keys {
+				if l.streams[k]--; l.streams[k] <= 0 {
+					delete(l.streams, k)
+				}
+			}
+		})
+	}, nil
+}
+
+// RecordUsage 记录用户消耗的 token，数据来自模型返回的 usage
+// 请求开始前只检查配额是否已用完，因此单次请求可能使当天用量略微超出配额
+func (l *Limiter) RecordUsage(uid int64, promptTokens, completionTokens int64) {
+	if l == nil || promptTokens+completionTokens <= 0 {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	day := l.day()
+	u, ok := l.tokens[uid]
+	if !ok || u.day != day {
+		u = &dayUsage{day: day}
+		l.tokens[uid] = u
+	}
+	u.tokens += promptTokens + completionTokens
+}
+
+// UsedTokens 返回用户当天已消耗的 token
+func (l *Limiter) UsedTokens(uid int64) int64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.usedLocked(uid)
+}
+
+func (l *Limiter) usedLocked(uid int64) int64 {
+	if u, ok := l.tokens[uid]; ok && u.day == l.day() {
+		return u.tokens
+	}
+	return 0
+}
+
+var std *Limiter
+
+// Init 按 rate_limit 配置初始化，未开启时为空操作
+func Init() {
+	if config.RateLimit == nil || !config.RateLimit.Enable {
+		return
+	}
+	std = New(Options{
+		User: Rule{
+			RequestsPerMinute:    config.RateLimit.User.RequestsPerMinute,
+			MaxConcurrentStreams: config.RateLimit.User.MaxConcurrentStreams,
+		},
+		APIKey: Rule{
+			RequestsPerMinute:    config.RateLimit.APIKey.RequestsPerMinute,
+			MaxConcurrentStreams: config.RateLimit.APIKey.MaxConcurrentStreams,
+		},
+		DailyTokenQuota: config.RateLimit.DailyTokenQuota,
+	})
+}
+
+// Default 返回 Init 创建的实例，未开启限流时为 nil
+func Default() *Limiter {
+	return std
+}
@@ -0,0 +1,90 @@
+package ratelimit
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func newTestLimiter(opts Options, now *time.Time) *Limiter {
+	l := New(opts)
+	l.now = func() time.Time { return *now }
+	return l
+}
+
+func TestAllowPerMinute(t *testing.T) {
+	now := time.Date(2025, 1, 1, 10, 0, 30, 0, time.Local)
+	l := newTestLimiter(Options{
+		User:   Rule{RequestsPerMinute: 3},
+		APIKey: Rule{RequestsPerMinute: 1},
+	}, &now)
+
+	// API Key 的额度更少，Remaining 以它为准
+	st, err := l.Allow(1, "k1")
+	if err != nil || st.Limit != 1 || st.Remaining != 0 {
+		t.Fatalf("first key request: %+v %v", st, err)
+	}
+	if _, err := l.Allow(1, "k1"); !errors.Is(err, ErrTooManyRequests) {
+		t.Fatalf("expected key limit, got %v", err)
+	}
+	// 被 API Key 拒绝的请求不占用用户的额度
+	for i := 0; i < 2; i++ {
+		if st, err := l.Allow(1, ""); err != nil || st.Remaining != 1-i {
+			t.Fatalf("user request %d: %+v %v", i, st, err)
+		}
+	}
+	st, err = l.Allow(1, "")
+	if !errors.Is(err, ErrTooManyRequests) || st.Remaining != 0 {
+		t.Fatalf("expected user limit, got %+v %v", st, err)
+	}
+	if !st.Reset.Equal(time.Date(2025, 1, 1, 10, 1, 0, 0, time.Local)) {
+		t.Fatalf("reset = %v", st.Reset)
+	}
+	// 其他用户不受影响
+	if _, err := l.Allow(2, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	now = now.Add(time.Minute)
+	if _, err := l.Allow(1, "k1"); err != nil {
+		t.Fatalf("next window: %v", err)
+	}
+}
+
+func TestAcquireStream(t *testing.T) {
+	now := time.Now()
+	l := newTestLimiter(Options{User: Rule{MaxConcurrentStreams: 1}}, &now)
+	release, err := l.AcquireStream(1, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := l.AcquireStream(1, "k1"); !errors.Is(err, ErrTooManyStreams) {
+		t.Fatalf("expected stream limit, got %v", err)
+	}
+	release()
+	release() // 重复调用不会多释放
+	if _, err := l.AcquireStream(1, ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := l.AcquireStream(1, ""); !errors.Is(err, ErrTooManyStreams) {
+		t.Fatalf("expected stream limit after double release, got %v", err)
+	}
+}
+
+func TestDailyTokenQuota(t *testing.T) {
+	now := time.Date(2025, 1, 1, 23, 59, 0, 0, time.Local)
+	l := newTestLimiter(Options{DailyTokenQuota: 100}, &now)
+
+	st, err := l.Allow(1, "")
+	if err != nil || st.TokenLimit != 100 || st.TokenRemaining != 100 {
+		t.Fatalf("before usage: %+v %v", st, err)
+	}
+	l.RecordUsage(1, 60, 50)
+	if st, err := l.Allow(1, ""); !errors.Is(err, ErrQuotaExceeded) || st.TokenRemaining != 0 {
+		t.Fatalf("expected quota exceeded, got %+v %v", st, err)
+	}
+	now = now.Add(2 * time.Minute)
+	if _, err := l.Allow(1, ""); err != nil {
+		t.Fatalf("next day: %v", err)
+	}
+}
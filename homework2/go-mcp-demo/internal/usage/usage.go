@@ -0,0 +1,259 @@
+package usage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/FantasyRL/go-mcp-demo/config"
+	"github.com/FantasyRL/go-mcp-demo/pkg/constant"
+	"github.com/FantasyRL/go-mcp-demo/pkg/logger"
+	"github.com/FantasyRL/go-mcp-demo/pkg/utils"
+)
+
+// Usage token 用量与按 ai_provider.prices 估算的费用（美元）
+type Usage struct {
+	PromptTokens     int64   `json:"prompt_tokens"`
+	CompletionTokens int64   `json:"completion_tokens"`
+	TotalTokens      int64   `json:"total_tokens"`
+	Cost             float64 `json:"cost"`
+}
+
+// Add 累加另一份用量
+func (u *Usage) Add(o Usage) {
+	u.PromptTokens += o.PromptTokens
+	u.CompletionTokens += o.CompletionTokens
+	u.TotalTokens += o.TotalTokens
+	u.Cost += o.Cost
+}
+
+// New 一次模型调用的用量，model 为实际提供服务的模型引用 "服务商/模型名"
+func New(model string, promptTokens, completionTokens int64) Usage {
+	u := Usage{
+		PromptTokens:     promptTokens,
+		CompletionTokens: completionTokens,
+		TotalTokens:      promptTokens + completionTokens,
+	}
+	if p, ok := Price(model); ok {
+		u.Cost = (float64(promptTokens)*p.Input + float64(completionTokens)*p.Output) / 1e6
+	}
+	return u
+}
+
+// Price 按模型引用查找价格，没有时再按模型名查找
+func Price(model string) (config.ModelPrice, bool) {
+	cfg := config.AiProvider()
+	if cfg == nil {
+		return config.ModelPrice{}, false
+	}
+	_, name, _ := strings.Cut(model, constant.AiProviderModelSeparator)
+	var byName *config.ModelPrice
+	for i, p := range cfg.Prices {
+		switch p.Model {
+		case model:
+			return p, true
+		case name:
+			byName = &cfg.Prices[i]
+		}
+	}
+	if byName != nil {
+		return *byName, true
+	}
+	return config.ModelPrice{}, false
+}
+
+// Record 一个用户某天在某个模型上的累计用量
+type Record struct {
+	UserID int64  `json:"user_id"`
+	Day    string `json:"day"` // 2006-01-02，本地时间
+	Model  string `json:"model"`
+	Calls  int64  `json:"calls"`
+	Usage
+}
+
+type recordKey struct {
+	userID int64
+	day    string
+	model  string
+}
+
+// Store 按用户/天/模型汇总的用量，在内存中累加；path 非空时由 StartFlush 定时落盘，Close 时再写一次
+type Store struct {
+	now func() time.Time
+
+	mu      sync.Mutex
+	path    string
+	records map[recordKey]*Record
+	dirty   bool // 上次落盘后是否有新的记录
+
+	saveMu sync.Mutex // 串行化文件写入，写文件时不持有 mu
+	stopCh chan struct{}
+	done   chan struct{}
+}
+
+// NewStore 创建存储并加载 path 中已有的记录，文件不存在时视为空
+func NewStore(path string) (*Store, error) {
+	s := &Store{now: time.Now, path: path, records: make(map[recordKey]*Record)}
+	if path == "" {
+		return s, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("usage: read usage file: %w", err)
+	}
+	var records []*Record
+	if err := json.Unmarshal(data, &records); err != nil {
+		return nil, fmt.Errorf("usage: parse usage file %s: %w", path, err)
+	}
+	for _, r := range records {
+		s.records[recordKey{r.UserID, r.Day, r.Model}] = r
+	}
+	return s, nil
+}
+
+// Add 记录一次模型调用，只更新内存，落盘见 Flush
+func (s *Store) Add(userID int64, model string, u Usage) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	k := recordKey{userID, s.now().Format(time.DateOnly), model}
+	r, ok := s.records[k]
+	if !ok {
+		r = &Record{UserID: userID, Day: k.day, Model: model}
+		s.records[k] = r
+	}
+	r.Calls++
+	r.Usage.Add(u)
+	s.dirty = true
+}
+
+// Report 返回用户在 [from, to] 内的记录，按日期、模型排序；from/to 为 2006-01-02，为空表示不限制
+func (s *Store) Report(userID int64, from, to string) []Record {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]Record, 0)
+	for _, r := range s.records {
+		if r.UserID != userID || (from != "" && r.Day < from) || (to != "" && r.Day > to) {
+			continue
+		}
+		out = append(out, *r)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Day != out[j].Day {
+			return out[i].Day < out[j].Day
+		}
+		return out[i].Model < out[j].Model
+	})
+	return out
+}
+
+// StartFlush 每隔 interval 把有变化的记录写回文件，path 为空时不做任何事
+func (s *Store) StartFlush(interval time.Duration) {
+	if s.path == "" || s.stopCh != nil {
+		return
+	}
+	s.stopCh, s.done = make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(s.done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if err := s.Flush(); err != nil {
+					logger.Warnf("%v", err)
+				}
+			case <-s.stopCh:
+				return
+			}
+		}
+	}()
+}
+
+// Close 停止定时落盘并写入剩余的记录，进程退出前调用
+func (s *Store) Close() error {
+	if s.stopCh != nil {
+		close(s.stopCh)
+		<-s.done
+		s.stopCh = nil
+	}
+	return s.Flush()
+}
+
+// Flush 上次落盘后有新的记录时，把全部用量记录写回文件
+func (s *Store) Flush() error {
+	if s.path == "" {
+		return nil
+	}
+	s.saveMu.Lock()
+	defer s.saveMu.Unlock()
+	s.mu.Lock()
+	if !s.dirty {
+		s.mu.Unlock()
+		return nil
+	}
+	records := make([]Record, 0, len(s.records))
+	for _, r := range s.records {
+		records = append(records, *r)
+	}
+	s.dirty = false
+	s.mu.Unlock()
+
+	if err := save(s.path, records); err != nil {
+		// 下次再试
+		s.mu.Lock()
+		s.dirty = true
+		s.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+func save(path string, records []Record) error {
+	sort.Slice(records, func(i, j int) bool {
+		a, b := records[i], records[j]
+		if a.Day != b.Day {
+			return a.Day < b.Day
+		}
+		if a.UserID != b.UserID {
+			return a.UserID < b.UserID
+		}
+		return a.Model < b.Model
+	})
+	data, err := json.MarshalIndent(records, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := utils.WriteFileAtomic(path, data, 0o600); err != nil {
+		return fmt.Errorf("usage: save usage: %w", err)
+	}
+	return nil
+}
+
+var std *Store
+
+// Init 按 cli.usage_file 初始化全局存储，文件无法读取时直接退出
+func Init() {
+	var path string
+	if cli := config.CLI(); cli != nil {
+		path = cli.UsageFile
+	}
+	s, err := NewStore(path)
+	if err != nil {
+		logger.Fatalf("%v", err)
+	}
+	s.StartFlush(constant.UsageFlushInterval)
+	std = s
+}
+
+// Default 返回 Init 创建的存储，未初始化时为 nil
+func Default() *Store {
+	return std
+}
@@ -0,0 +1,88 @@
+package usage
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/FantasyRL/go-mcp-demo/config"
+)
+
+func TestNewUsagePrice(t *testing.T) {
+	config.SetAiProvider(&config.AiProviderConfig{Prices: []config.ModelPrice{
+		{Model: "deepseek-chat", Input: 1, Output: 2},
+		{Model: "openai/deepseek-chat", Input: 10, Output: 20},
+	}})
+	defer func() { config.SetAiProvider(nil) }()
+
+	// 模型引用优先于模型名
+	if u := New("openai/deepseek-chat", 1000, 500); math.Abs(u.Cost-0.02) > 1e-9 || u.TotalTokens != 1500 {
+		t.Fatalf("usage = %+v", u)
+	}
+	if u := New("deepseek/deepseek-chat", 1000, 500); math.Abs(u.Cost-0.002) > 1e-9 {
+		t.Fatalf("usage = %+v", u)
+	}
+	if u := New("ollama/qwen3:1.7b", 1000, 500); u.Cost != 0 {
+		t.Fatalf("unpriced model cost = %v", u.Cost)
+	}
+}
+
+func TestStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "usage.json")
+	s, err := NewStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2025, 1, 1, 10, 0, 0, 0, time.Local)
+	s.now = func() time.Time { return now }
+	for _, m := range []string{"a/x", "a/x", "b/y"} {
+		s.Add(1, m, Usage{PromptTokens: 10, CompletionTokens: 5, TotalTokens: 15, Cost: 0.5})
+	}
+	now = now.AddDate(0, 0, 1)
+	s.Add(1, "a/x", Usage{TotalTokens: 1})
+	s.Add(2, "a/x", Usage{TotalTokens: 1})
+
+	// 记录只在内存中累加，Close 时落盘
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("usage file written on Add: %v", err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// 重新加载后数据不丢失
+	s, err = NewStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := s.Report(1, "", "2025-01-01")
+	if len(got) != 2 || got[0].Model != "a/x" || got[0].Calls != 2 || got[0].TotalTokens != 30 || got[0].Cost != 1 || got[1].Model != "b/y" {
+		t.Fatalf("report = %+v", got)
+	}
+	if got := s.Report(1, "2025-01-02", ""); len(got) != 1 || got[0].Day != "2025-01-02" {
+		t.Fatalf("report = %+v", got)
+	}
+}
+
+func TestStoreStartFlush(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "usage.json")
+	s, err := NewStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.StartFlush(time.Millisecond)
+	defer s.Close()
+	s.Add(1, "a/x", Usage{TotalTokens: 1})
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if loaded, err := NewStore(path); err == nil && len(loaded.Report(1, "", "")) == 1 {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("usage was not flushed")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
@@ -0,0 +1,363 @@
+package ai_provider
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/FantasyRL/go-mcp-demo/config"
+	"github.com/FantasyRL/go-mcp-demo/pkg/constant"
+	"github.com/FantasyRL/go-mcp-demo/pkg/errno"
+	"github.com/FantasyRL/go-mcp-demo/pkg/logger"
+	"github.com/openai/openai-go/v2"
+)
+
+// AnthropicTool Anthropic Messages API 的工具定义
+type AnthropicTool struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description,omitempty"`
+	InputSchema map[string]any `json:"input_schema"`
+}
+
+// NewAnthropicTool 由 MCP 工具的 JSON Schema 生成 Anthropic 工具，input_schema 顶层必须是 object
+func NewAnthropicTool(name, description string, schema map[string]any) AnthropicTool {
+	return AnthropicTool{Name: name, Description: description, InputSchema: objectSchema(schema)}
+}
+
+// AnthropicContent 消息内容块
+type AnthropicContent struct {
+	Type      string                `json:"type"` // "text" | "image" | "tool_use" | "tool_result"
+	Text      string                `json:"text,omitempty"`
+	Source    *AnthropicImageSource `json:"source,omitempty"`      // image
+	ID        string                `json:"id,omitempty"`          // tool_use
+	Name      string                `json:"name,omitempty"`        // tool_use
+	Input     json.RawMessage       `json:"input,omitempty"`       // tool_use 的参数，JSON 对象
+	ToolUseID string                `json:"tool_use_id,omitempty"` // tool_result 对应的 tool_use.id
+	Content   string                `json:"content,omitempty"`     // tool_result 的内容
+}
+
+// AnthropicMessage 对话消息，role 只有 "user" | "assistant"，工具结果放在 user 消息中
+// AnthropicImageSource 图片内容，base64 或 url
+type AnthropicImageSource struct {
+	Type      string `json:"type"` // "base64" | "url"
+	MediaType string `json:"media_type,omitempty"`
+	Data      string `json:"data,omitempty"`
+	URL       string `json:"url,omitempty"`
+}
+
+type AnthropicMessage struct {
+	Role    string             `json:"role"`
+	Content []AnthropicContent `json:"content"`
+}
+
+// AnthropicRequest POST /v1/messages 的请求
+type AnthropicRequest struct {
+	Model         string             `json:"model"`
+	System        string             `json:"system,omitempty"` // 系统提示词是顶层字段，不在 messages 中
+	Messages      []AnthropicMessage `json:"messages"`
+	Tools         []AnthropicTool    `json:"tools,omitempty"`
+	MaxTokens     int64              `json:"max_tokens"` // 必填
+	Temperature   *float64           `json:"temperature,omitempty"`
+	TopP          *float64           `json:"top_p,omitempty"`
+	TopK          *int               `json:"top_k,omitempty"`
+	StopSequences []string           `json:"stop_sequences,omitempty"`
+	Stream        bool               `json:"stream"`
+}
+
+// AnthropicUsage token 用量
+type AnthropicUsage struct {
+	InputTokens  int64 `json:"input_tokens"`
+	OutputTokens int64 `json:"output_tokens"`
+}
+
+// AnthropicEvent 流式事件：message_start / content_block_start / content_block_delta /
+// content_block_stop / message_delta / message_stop / ping / error
+type AnthropicEvent struct {
+	Type    string `json:"type"`
+	Index   int    `json:"index"`
+	Message *struct {
+		ID    string         `json:"id"`
+		Model string         `json:"model"`
+		Usage AnthropicUsage `json:"usage"`
+	} `json:"message,omitempty"`
+	ContentBlock *AnthropicContent `json:"content_block,omitempty"`
+	Delta        *struct {
+		Type        string `json:"type"` // "text_delta" | "input_json_delta" | "thinking_delta"
+		Text        string `json:"text"`
+		Thinking    string `json:"thinking"`
+		PartialJSON string `json:"partial_json"`
+		StopReason  string `json:"stop_reason"` // message_delta: "end_turn" | "tool_use" | "max_tokens" | "stop_sequence"
+	} `json:"delta,omitempty"`
+	Usage *AnthropicUsage `json:"usage,omitempty"`
+	Error *struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error,omitempty"`
+}
+
+// ChatStreamAnthropic 使用 Anthropic Messages API 流式聊天，仅支持 anthropic 服务商，返回实际提供服务的模型
+// req.Model 为模型引用；max_tokens 为 0 时使用服务商 options.max_tokens
+func (c *Client) ChatStreamAnthropic(ctx context.Context, req AnthropicRequest, onEvent func(*AnthropicEvent) error) (ModelInfo, error) {
+	cfg := config.AiProvider()
+	list, err := c.candidates(cfg, req.Model, constant.AiProviderTypeAnthropic)
+	if err != nil {
+		return ModelInfo{}, err
+	}
+	started := false
+	return invoke(ctx, cfg, list, func() bool { return started }, func(cand candidate) error {
+		r := req
+		r.Model = cand.model
+		if r.MaxTokens == 0 {
+			r.MaxTokens = anthropicMaxTokens(cand.opts)
+		}
+		return cand.p.chatStreamAnthropic(ctx, r, func(ev *AnthropicEvent) error {
+			started = true
+			return onEvent(ev)
+		})
+	})
+}
+
+func anthropicMaxTokens(opts config.OllamaOptions) int64 {
+	if mt := opts.MaxTokens; mt != nil && *mt > 0 {
+		return int64(*mt)
+	}
+	return constant.AiProviderAnthropicMaxTokens
+}
+
+func (p *provider) chatStreamAnthropic(ctx context.Context, req AnthropicRequest, onEvent func(*AnthropicEvent) error) error {
+	endpoint := strings.TrimRight(p.baseURL, "/") + "/v1/messages"
+	req.Stream = true
+
+	b, _ := json.Marshal(req)
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(b))
+	if err != nil {
+		logger.Errorf("anthropic.ChatStream NewRequestWithContext error: %v", err)
+		return err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Accept", "text/event-stream")
+	httpReq.Header.Set("x-api-key", p.apiKey)
+	httpReq.Header.Set("anthropic-version", constant.AnthropicAPIVersion)
+
+	resp, err := p.httpClient.Do(httpReq)
+	if err != nil {
+		logger.Errorf("anthropic.ChatStream Do request error: %v", err)
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		se := newStatusError("anthropic messages", resp)
+		logger.Errorf("anthropic.ChatStream error response: %s", se.body)
+		return se
+	}
+
+	sc := bufio.NewScanner(resp.Body)
+	buf := make([]byte, 0, 64*1024)
+	sc.Buffer(buf, 10*1024*1024)
+	for sc.Scan() {
+		// SSE：只处理 data 行，事件类型同时在 JSON 的 type 字段中
+		data, ok := bytes.CutPrefix(sc.Bytes(), []byte("data:"))
+		if !ok {
+			continue
+		}
+		ev := new(AnthropicEvent)
+		if err := json.Unmarshal(bytes.TrimSpace(data), ev); err != nil {
+			logger.Errorf("anthropic.ChatStream json unmarshal event error: %v", err)
+			return err
+		}
+		if ev.Type == "error" && ev.Error != nil {
+			return anthropicStreamError(ev)
+		}
+		if err := onEvent(ev); err != nil {
+			if errors.Is(err, errno.OllamaInternalStopStream) {
+				return nil
+			}
+			return err
+		}
+		if ev.Type == "message_stop" {
+			break
+		}
+	}
+	return sc.Err()
+}
+
+// anthropicStreamError 流中的 error 事件，overloaded_error 与 api_error 按 5xx 处理以便重试
+func anthropicStreamError(ev *AnthropicEvent) error {
+	code := http.StatusBadRequest
+	switch ev.Error.Type {
+	case "overloaded_error":
+		code = 529
+	case "api_error":
+		code = http.StatusInternalServerError
+	case "rate_limit_error":
+		code = http.StatusTooManyRequests
+	}
+	return &statusError{op: "anthropic stream", code: code, status: ev.Error.Type, body: ev.Error.Message}
+}
+
+// anthropicRequest 将 OpenAI Chat Completions 请求转换为 Anthropic Messages 请求：
+// system/developer 消息合并为顶层 system，assistant 的 tool_calls 转为 tool_use 块，
+// tool 消息转为 user 消息中的 tool_result 块，相邻的同角色消息合并；
+// Anthropic 没有结构化输出参数，response_format 的 JSON Schema 写进 system
+func anthropicRequest(params *chatParams) AnthropicRequest {
+	req := AnthropicRequest{
+		Model:         params.Model,
+		MaxTokens:     params.maxTokens(),
+		Temperature:   params.Temperature,
+		TopP:          params.TopP,
+		StopSequences: params.stopSequences(),
+	}
+	var system []string
+	add := func(role string, blocks ...AnthropicContent) {
+		if len(blocks) == 0 {
+			return
+		}
+		if n := len(req.Messages); n > 0 && req.Messages[n-1].Role == role {
+			req.Messages[n-1].Content = append(req.Messages[n-1].Content, blocks...)
+			return
+		}
+		req.Messages = append(req.Messages, AnthropicMessage{Role: role, Content: blocks})
+	}
+	for _, m := range params.Messages {
+		switch m.Role {
+		case "system", "developer":
+			if s := m.text(); s != "" {
+				system = append(system, s)
+			}
+		case "user":
+			var blocks []AnthropicContent
+			for _, part := range m.parts() {
+				switch part.Type {
+				case "text":
+					blocks = append(blocks, AnthropicContent{Type: "text", Text: part.Text})
+				case "image_url":
+					src := &AnthropicImageSource{Type: "url", URL: part.ImageURL.URL}
+					if mime, data, ok := parseDataURL(part.ImageURL.URL); ok {
+						src = &AnthropicImageSource{Type: "base64", MediaType: mime, Data: data}
+					}
+					blocks = append(blocks, AnthropicContent{Type: "image", Source: src})
+				}
+			}
+			add("user", blocks...)
+		case "assistant":
+			var blocks []AnthropicContent
+			if s := m.text(); s != "" {
+				blocks = append(blocks, AnthropicContent{Type: "text", Text: s})
+			}
+			for _, tc := range m.ToolCalls {
+				blocks = append(blocks, AnthropicContent{
+					Type:  "tool_use",
+					ID:    tc.ID,
+					Name:  tc.Function.Name,
+					Input: toolArguments(tc.Function.Arguments),
+				})
+			}
+			add("assistant", blocks...)
+		case "tool":
+			add("user", AnthropicContent{Type: "tool_result", ToolUseID: m.ToolCallID, Content: m.text()})
+		}
+	}
+	if schema := params.jsonSchema(); schema != nil {
+		system = append(system, jsonSchemaInstruction(schema))
+	}
+	req.System = strings.Join(system, "\n\n")
+	for _, t := range params.Tools {
+		req.Tools = append(req.Tools, NewAnthropicTool(t.Function.Name, t.Function.Description, t.Function.Parameters))
+	}
+	return req
+}
+
+// anthropicFinishReason 将 stop_reason 转为 OpenAI 的 finish_reason
+func anthropicFinishReason(reason string) string {
+	switch reason {
+	case "tool_use":
+		return "tool_calls"
+	case "max_tokens":
+		return "length"
+	case "":
+		return ""
+	default:
+		return "stop"
+	}
+}
+
+// chatStreamAnthropicAsOpenAI 以 OpenAI 流式分片的形式返回 Anthropic 的流式结果，host 无需区分服务商
+func (p *provider) chatStreamAnthropicAsOpenAI(
+	ctx context.Context,
+	req openai.ChatCompletionNewParams,
+	onChunk func(*openai.ChatCompletionChunk) error,
+) error {
+	params, err := parseChatParams(req)
+	if err != nil {
+		return err
+	}
+	areq := anthropicRequest(params)
+	// 服务商 options.max_tokens 已经由 openaiRequest 写入 req
+	if areq.MaxTokens <= 0 {
+		areq.MaxTokens = constant.AiProviderAnthropicMaxTokens
+	}
+
+	b := newChunkBuilder(areq.Model)
+	toolIndex := map[int]int{} // content block 下标 -> tool_calls 下标
+	var usage AnthropicUsage
+	emit := func(chunk *openai.ChatCompletionChunk, err error) error {
+		if err != nil {
+			return err
+		}
+		return onChunk(chunk)
+	}
+	return p.chatStreamAnthropic(ctx, areq, func(ev *AnthropicEvent) error {
+		switch ev.Type {
+		case "message_start":
+			if ev.Message != nil {
+				b.id, b.model = ev.Message.ID, ev.Message.Model
+				usage.InputTokens = ev.Message.Usage.InputTokens
+			}
+		case "content_block_start":
+			if cb := ev.ContentBlock; cb != nil && cb.Type == "tool_use" {
+				idx := len(toolIndex)
+				toolIndex[ev.Index] = idx
+				return emit(b.delta(map[string]any{"tool_calls": []any{map[string]any{
+					"index":    idx,
+					"id":       cb.ID,
+					"type":     "function",
+					"function": map[string]any{"name": cb.Name, "arguments": ""},
+				}}}, ""))
+			}
+		case "content_block_delta":
+			if ev.Delta == nil {
+				return nil
+			}
+			switch ev.Delta.Type {
+			case "text_delta":
+				return emit(b.delta(map[string]any{"content": ev.Delta.Text}, ""))
+			case "thinking_delta":
+				return emit(b.delta(map[string]any{"reasoning_content": ev.Delta.Thinking}, ""))
+			case "input_json_delta":
+				idx, ok := toolIndex[ev.Index]
+				if !ok || ev.Delta.PartialJSON == "" {
+					return nil
+				}
+				return emit(b.delta(map[string]any{"tool_calls": []any{map[string]any{
+					"index":    idx,
+					"function": map[string]any{"arguments": ev.Delta.PartialJSON},
+				}}}, ""))
+			}
+		case "message_delta":
+			if ev.Usage != nil {
+				usage.OutputTokens = ev.Usage.OutputTokens
+			}
+			if ev.Delta != nil && ev.Delta.StopReason != "" {
+				return emit(b.delta(map[string]any{}, anthropicFinishReason(ev.Delta.StopReason)))
+			}
+		case "message_stop":
+			return emit(b.usage(usage.InputTokens, usage.OutputTokens))
+		}
+		return nil
+	})
+}
@@ -0,0 +1,143 @@
+package ai_provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/FantasyRL/go-mcp-demo/config"
+	"github.com/FantasyRL/go-mcp-demo/pkg/constant"
+	"github.com/openai/openai-go/v2"
+)
+
+var anthropicEvents = []string{
+	`{"type":"message_start","message":{"id":"msg_1","model":"claude-test","usage":{"input_tokens":12,"output_tokens":1}}}`,
+	`{"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}`,
+	`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Let me "}}`,
+	`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"check."}}`,
+	`{"type":"content_block_stop","index":0}`,
+	`{"type":"content_block_start","index":1,"content_block":{"type":"tool_use","id":"toolu_1","name":"weather","input":{}}}`,
+	`{"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"{\"city\":"}}`,
+	`{"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"\"Paris\"}"}}`,
+	`{"type":"content_block_stop","index":1}`,
+	`{"type":"message_delta","delta":{"stop_reason":"tool_use"},"usage":{"output_tokens":30}}`,
+	`{"type":"message_stop"}`,
+}
+
+func anthropicServer(t *testing.T, got *AnthropicRequest) *httptest.Server {
+	return stubServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/messages" || r.Header.Get("x-api-key") != "sk-ant" || r.Header.Get("anthropic-version") == "" {
+			http.Error(w, `{"type":"error","error":{"type":"authentication_error"}}`, http.StatusUnauthorized)
+			return
+		}
+		if err := json.NewDecoder(r.Body).Decode(got); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, ev := range anthropicEvents {
+			var typ struct {
+				Type string `json:"type"`
+			}
+			_ = json.Unmarshal([]byte(ev), &typ)
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", typ.Type, ev)
+		}
+	})
+}
+
+func TestChatStreamOpenAIAnthropic(t *testing.T) {
+	var got AnthropicRequest
+	srv := anthropicServer(t, &got)
+	cli := newTestClient(t, &config.AiProviderConfig{
+		Model: "claude/claude-test",
+		Providers: map[string]config.AiProviderEndpoint{
+			"claude": {Type: constant.AiProviderTypeAnthropic, BaseURL: srv.URL, APIKey: "sk-ant"},
+		},
+	})
+
+	req := openai.ChatCompletionNewParams{
+		Messages: []openai.ChatCompletionMessageParamUnion{
+			openai.SystemMessage("be brief"),
+			openai.UserMessage("weather?"),
+			{OfAssistant: &openai.ChatCompletionAssistantMessageParam{
+				ToolCalls: []openai.ChatCompletionMessageToolCallUnionParam{{
+					OfFunction: &openai.ChatCompletionMessageFunctionToolCallParam{
+						ID:       "toolu_0",
+						Function: openai.ChatCompletionMessageFunctionToolCallFunctionParam{Name: "weather", Arguments: `{"city":"Rome"}`},
+					},
+				}},
+			}},
+			openai.ToolMessage("sunny", "toolu_0"),
+			openai.UserMessage("and Paris?"),
+		},
+		Tools: []openai.ChatCompletionToolUnionParam{{
+			OfFunction: &openai.ChatCompletionFunctionToolParam{
+				Function: openai.FunctionDefinitionParam{
+					Name:       "weather",
+					Parameters: openai.FunctionParameters{"properties": map[string]any{"city": map[string]any{"type": "string"}}},
+				},
+			},
+		}},
+	}
+
+	var acc openai.ChatCompletionAccumulator
+	var finish string
+	served, err := cli.ChatStreamOpenAI(context.Background(), req, func(chunk *openai.ChatCompletionChunk) error {
+		acc.AddChunk(*chunk)
+		if len(chunk.Choices) > 0 && chunk.Choices[0].FinishReason != "" {
+			finish = chunk.Choices[0].FinishReason
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if served.Type != constant.AiProviderTypeAnthropic || served.Model != "claude-test" {
+		t.Fatalf("served = %+v", served)
+	}
+
+	// 请求：system 为顶层字段，tool 结果与随后的用户消息合并为一条 user 消息
+	if got.Model != "claude-test" || got.System != "be brief" || got.MaxTokens != constant.AiProviderAnthropicMaxTokens || !got.Stream {
+		t.Fatalf("request = %+v", got)
+	}
+	if len(got.Messages) != 3 || got.Messages[1].Content[0].Type != "tool_use" || string(got.Messages[1].Content[0].Input) != `{"city":"Rome"}` {
+		t.Fatalf("messages = %+v", got.Messages)
+	}
+	if last := got.Messages[2]; last.Role != "user" || len(last.Content) != 2 ||
+		last.Content[0].Type != "tool_result" || last.Content[0].ToolUseID != "toolu_0" || last.Content[1].Text != "and Paris?" {
+		t.Fatalf("last message = %+v", last)
+	}
+	if len(got.Tools) != 1 || got.Tools[0].InputSchema["type"] != "object" {
+		t.Fatalf("tools = %+v", got.Tools)
+	}
+
+	// 响应：文本、tool_use 与用量转换为 OpenAI 分片
+	msg := acc.Choices[0].Message
+	if msg.Content != "Let me check." || finish != "tool_calls" {
+		t.Fatalf("content = %q, finish = %q", msg.Content, finish)
+	}
+	if len(msg.ToolCalls) != 1 || msg.ToolCalls[0].ID != "toolu_1" || msg.ToolCalls[0].Function.Name != "weather" ||
+		msg.ToolCalls[0].Function.Arguments != `{"city":"Paris"}` {
+		t.Fatalf("tool calls = %+v", msg.ToolCalls)
+	}
+	if acc.Usage.PromptTokens != 12 || acc.Usage.CompletionTokens != 30 {
+		t.Fatalf("usage = %+v", acc.Usage)
+	}
+
+	// 原生接口
+	var events []string
+	if _, err := cli.ChatStreamAnthropic(context.Background(), AnthropicRequest{
+		Messages: []AnthropicMessage{{Role: "user", Content: []AnthropicContent{{Type: "text", Text: "hi"}}}},
+	}, func(ev *AnthropicEvent) error {
+		events = append(events, ev.Type)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != len(anthropicEvents) {
+		t.Fatalf("events = %v", events)
+	}
+}
@@ -13,42 +13,81 @@ import (
 	"github.com/FantasyRL/go-mcp-demo/pkg/logger"
 	"github.com/openai/openai-go/v2"
 	"github.com/openai/openai-go/v2/option"
-	"io"
 	"net"
 	"net/http"
+	"sort"
 	"strings"
 	"time"
 )
 
+// Client 按模型把请求路由到 config.AiProvider() 中对应的服务商
 type Client struct {
-	mode         string
+	providers map[string]*provider
+}
+
+// provider 一个模型服务商的连接
+type provider struct {
+	name         string
+	typ          string
 	baseURL      string
-	httpClient   *http.Client
-	openaiClient *openai.Client
+	apiKey       string
+	httpClient   *http.Client   // ollama 原生接口与 anthropic
+	openaiClient *openai.Client // anthropic、gemini 服务商为 nil
+	api          string         // openai 服务商使用的接口，见 constant.AiProviderAPI*
+	builtinTools []string       // Responses API 内置工具
 }
 
-type ClientOptions struct {
-	BaseURL       string
-	RequestTimout time.Duration
+// ModelInfo 可选择的模型
+type ModelInfo struct {
+	ID       string // "服务商/模型名"，可以直接作为请求中的 model
+	Provider string
+	Model    string
+	Type     string // "ollama" | "openai" | "anthropic" | "gemini"
+	Default  bool
 }
 
-// NewAiProviderClient 创建一个 AiProvider 客户端
+// NewAiProviderClient 创建一个 AiProvider 客户端，为每个服务商建立连接
 func NewAiProviderClient() *Client {
-	to := config.AiProvider.Options.RequestTimout
+	cfg := config.AiProvider()
+	endpoints := cfg.Endpoints()
+	if len(endpoints) == 0 {
+		logger.Errorf("unsupported mode: %s", cfg.Mode)
+		return nil
+	}
+	c := &Client{providers: make(map[string]*provider, len(endpoints))}
+	for name, ep := range endpoints {
+		p := newProvider(name, ep)
+		if p == nil {
+			logger.Errorf("ai_provider: unsupported type %q of provider %s", ep.Type, name)
+			continue
+		}
+		c.providers[name] = p
+	}
+	return c
+}
+
+func newProvider(name string, ep config.AiProviderEndpoint) *provider {
+	to := ep.Options.RequestTimout
 	if to <= 0 {
 		to = 60 * time.Second
 	}
-	switch config.AiProvider.Mode {
-	case constant.AiProviderModeLocal:
+	switch ep.Type {
+	case constant.AiProviderTypeOllama:
 		// 本地 AiProvider
-		base := strings.TrimRight(config.AiProvider.BaseURL, "/") + "/v1" // AiProvider 的 OpenAI 兼容层
+		base := strings.TrimRight(ep.BaseURL, "/") + "/v1" // AiProvider 的 OpenAI 兼容层
+		apiKey := ep.APIKey
+		if apiKey == "" {
+			apiKey = "ollama"
+		}
 		openaiCli := openai.NewClient(
-			option.WithAPIKey("ollama"),
+			option.WithAPIKey(apiKey),
 			option.WithBaseURL(base),
+			option.WithMaxRetries(0), // 重试由 invoke 按 ai_provider.retry 处理
 		)
-		return &Client{
-			mode:    constant.AiProviderModeLocal,
-			baseURL: config.AiProvider.BaseURL,
+		return &provider{
+			name:    name,
+			typ:     ep.Type,
+			baseURL: ep.BaseURL,
 			httpClient: &http.Client{
 				Timeout: to,
 				Transport: &http.Transport{
@@ -66,25 +105,140 @@ func NewAiProviderClient() *Client {
 			},
 			openaiClient: &openaiCli,
 		}
-	case constant.AiProviderModeRemote:
+	case constant.AiProviderTypeAnthropic, constant.AiProviderTypeGemini:
+		// Anthropic Messages API / Gemini generateContent，由 chatStream*AsOpenAI 转换为 OpenAI 格式
+		return &provider{
+			name:       name,
+			typ:        ep.Type,
+			baseURL:    ep.BaseURL,
+			apiKey:     ep.APIKey,
+			httpClient: &http.Client{Timeout: to},
+		}
+	case constant.AiProviderTypeOpenAI:
 		// 远程 openAI-API
 		openaiCli := openai.NewClient(
-			option.WithAPIKey(config.AiProvider.Remote.APIKey),
-			option.WithBaseURL(config.AiProvider.Remote.BaseURL))
-		return &Client{
-			mode:         constant.AiProviderModeRemote,
-			baseURL:      config.AiProvider.Remote.BaseURL,
+			option.WithAPIKey(ep.APIKey),
+			option.WithBaseURL(ep.BaseURL),
+			option.WithMaxRetries(0))
+		api := ep.API
+		if api == "" {
+			api = constant.AiProviderAPIChatCompletions
+		}
+		return &provider{
+			name:         name,
+			typ:          ep.Type,
+			baseURL:      ep.BaseURL,
 			openaiClient: &openaiCli,
+			api:          api,
+			builtinTools: ep.BuiltinTools,
 		}
-	default:
-		logger.Errorf("unsupported mode: %s", config.AiProvider.Mode)
-		return nil
 	}
+	return nil
+}
+
+// supports 服务商是否支持某个接口：服务商类型本身（如 ollama 原生接口），或 openai 服务商配置的 api
+func (p *provider) supports(api string) bool {
+	return p.typ == api || (p.api != "" && p.api == api)
+}
+
+// resolve 按配置快照 cfg 找到模型所属的服务商，返回服务商与实际的模型名
+func (c *Client) resolve(cfg *config.AiProviderConfig, model string) (*provider, string, error) {
+	name, m, err := cfg.ResolveModel(model)
+	if err != nil {
+		return nil, "", errno.ParamError.WithMessage(err.Error())
+	}
+	p, ok := c.providers[name]
+	if !ok {
+		return nil, "", errno.ParamError.WithMessage(fmt.Sprintf("provider %q is not loaded, restart to apply new providers", name))
+	}
+	return p, m, nil
+}
+
+// ResolveModel 校验模型引用，model 为空时返回默认模型
+func (c *Client) ResolveModel(model string) (ModelInfo, error) {
+	cfg := config.AiProvider()
+	p, m, err := c.resolve(cfg, model)
+	if err != nil {
+		return ModelInfo{}, err
+	}
+	return modelInfo(cfg, p, m), nil
+}
+
+// IsOllama 模型是否由 ollama 提供，只有 ollama 支持 Chat/ChatStream 使用的 /api/chat
+func (c *Client) IsOllama(model string) bool {
+	p, _, err := c.resolve(config.AiProvider(), model)
+	return err == nil && p.typ == constant.AiProviderTypeOllama
+}
+
+// UsesResponsesAPI 模型所属服务商是否配置为使用 Responses API（ai_provider.providers.*.api）
+func (c *Client) UsesResponsesAPI(model string) bool {
+	p, _, err := c.resolve(config.AiProvider(), model)
+	return err == nil && p.supports(constant.AiProviderAPIResponses)
+}
+
+// Models 列出可选择的模型；服务商未配置 models 时只列出指向它的默认模型
+func (c *Client) Models() []ModelInfo {
+	names := make([]string, 0, len(c.providers))
+	for name := range c.providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
 
+	cfg := config.AiProvider()
+	endpoints := cfg.Endpoints()
+	defProvider, defModel, _ := cfg.ResolveModel("")
+	var models []ModelInfo
+	for _, name := range names {
+		p := c.providers[name]
+		list := endpoints[name].Models
+		if len(list) == 0 && defProvider == name {
+			list = []string{defModel}
+		}
+		for _, m := range list {
+			models = append(models, modelInfo(cfg, p, m))
+		}
+	}
+	return models
 }
 
-// Chat 调用 /api/chat，非流式
-func (c *Client) Chat(ctx context.Context, req ChatRequest) (*ChatResponse, error) {
+func modelInfo(cfg *config.AiProviderConfig, p *provider, model string) ModelInfo {
+	defProvider, defModel, _ := cfg.ResolveModel("")
+	return ModelInfo{
+		ID:       p.name + constant.AiProviderModelSeparator + model,
+		Provider: p.name,
+		Model:    model,
+		Type:     p.typ,
+		Default:  p.name == defProvider && model == defModel,
+	}
+}
+
+// resources 调用服务商时经过的 Sentinel 资源
+func (p *provider) resources() []string {
+	return []string{constant.SentinelResourceAIProvider, constant.SentinelResourceAIProvider + ":" + p.name}
+}
+
+// Chat 调用 /api/chat，非流式，仅支持 ollama 服务商，同时返回实际提供服务的模型；失败时按 ai_provider.retry 重试并切换到 fallback 中的 ollama 模型
+func (c *Client) Chat(ctx context.Context, req ChatRequest) (*ChatResponse, ModelInfo, error) {
+	cfg := config.AiProvider()
+	list, err := c.candidates(cfg, req.Model, constant.AiProviderTypeOllama)
+	if err != nil {
+		return nil, ModelInfo{}, err
+	}
+	var resp *ChatResponse
+	served, err := invoke(ctx, cfg, list, func() bool { return false }, func(cand candidate) (err error) {
+		resp, err = cand.p.chat(ctx, cand.ollamaRequest(req))
+		return err
+	})
+	if err != nil {
+		return nil, ModelInfo{}, err
+	}
+	var split thinkSplitter
+	resp.Done = true
+	split.splitThinking(resp)
+	return resp, served, nil
+}
+
+func (c *provider) chat(ctx context.Context, req ChatRequest) (*ChatResponse, error) {
 	endpoint := fmt.Sprintf("%s/api/chat", c.baseURL)
 	req.Stream = false
 
@@ -104,9 +258,9 @@ func (c *Client) Chat(ctx context.Context, req ChatRequest) (*ChatResponse, erro
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		logger.Errorf("ollama.Chat error response: %s", string(body))
-		return nil, fmt.Errorf("ollama chat failed: %s - %s", resp.Status, string(body))
+		se := newStatusError("ollama chat", resp)
+		logger.Errorf("ollama.Chat error response: %s", se.body)
+		return nil, se
 	}
 	var cr ChatResponse
 	if err := json.NewDecoder(resp.Body).Decode(&cr); err != nil {
@@ -115,8 +269,26 @@ func (c *Client) Chat(ctx context.Context, req ChatRequest) (*ChatResponse, erro
 	return &cr, nil
 }
 
-// ChatStream api/chat，流式
-func (c *Client) ChatStream(ctx context.Context, req ChatRequest, onChunk func(*ChatResponse) error) error {
+// ChatStream api/chat，流式，仅支持 ollama 服务商，返回实际提供服务的模型；正文中的 <think> 块移到 Message.Thinking
+// 在输出第一个分片之前失败时会重试或切换到 fallback 中的 ollama 模型
+func (c *Client) ChatStream(ctx context.Context, req ChatRequest, onChunk func(*ChatResponse) error) (ModelInfo, error) {
+	cfg := config.AiProvider()
+	list, err := c.candidates(cfg, req.Model, constant.AiProviderTypeOllama)
+	if err != nil {
+		return ModelInfo{}, err
+	}
+	started := false
+	return invoke(ctx, cfg, list, func() bool { return started }, func(cand candidate) error {
+		var split thinkSplitter
+		return cand.p.chatStream(ctx, cand.ollamaRequest(req), func(chunk *ChatResponse) error {
+			started = true
+			split.splitThinking(chunk)
+			return onChunk(chunk)
+		})
+	})
+}
+
+func (c *provider) chatStream(ctx context.Context, req ChatRequest, onChunk func(*ChatResponse) error) error {
 	endpoint := fmt.Sprintf("%s/api/chat", c.baseURL)
 	req.Stream = true
 
@@ -136,9 +308,9 @@ func (c *Client) ChatStream(ctx context.Context, req ChatRequest, onChunk func(*
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		all, _ := io.ReadAll(resp.Body)
-		logger.Errorf("ollama chat stream error response: %s", string(all))
-		return fmt.Errorf("ollama chat stream failed: %s - %s", resp.Status, string(all))
+		se := newStatusError("ollama chat stream", resp)
+		logger.Errorf("ollama chat stream error response: %s", se.body)
+		return se
 	}
 
 	sc := bufio.NewScanner(resp.Body)
@@ -169,12 +341,41 @@ func (c *Client) ChatStream(ctx context.Context, req ChatRequest, onChunk func(*
 	return sc.Err()
 }
 
-// ChatStreamOpenAI 使用 OpenAI 兼容层流式聊天
+// ChatStreamOpenAI 使用 OpenAI 兼容层流式聊天，req.Model 为模型引用（见 config.AiProviderConfig.ResolveModel），返回实际提供服务的模型
+// 请求中未设置的生成参数使用服务商 options；推理增量用 ReasoningDelta 读取，正文中的 <think> 块会移到其中
+// 在输出第一个分片之前失败时会重试或切换到 ai_provider.fallback 中的模型
 func (c *Client) ChatStreamOpenAI(
 	ctx context.Context,
 	req openai.ChatCompletionNewParams,
 	onChunk func(*openai.ChatCompletionChunk) error,
+) (ModelInfo, error) {
+	cfg := config.AiProvider()
+	list, err := c.candidates(cfg, req.Model, "")
+	if err != nil {
+		return ModelInfo{}, err
+	}
+	started := false
+	return invoke(ctx, cfg, list, func() bool { return started }, func(cand candidate) error {
+		var split thinkSplitter
+		return cand.p.chatStreamOpenAI(ctx, cand.openaiRequest(req), func(chunk *openai.ChatCompletionChunk) error {
+			started = true
+			split.splitReasoning(chunk)
+			return onChunk(chunk)
+		})
+	})
+}
+
+func (c *provider) chatStreamOpenAI(
+	ctx context.Context,
+	req openai.ChatCompletionNewParams,
+	onChunk func(*openai.ChatCompletionChunk) error,
 ) error {
+	switch c.typ {
+	case constant.AiProviderTypeAnthropic:
+		return c.chatStreamAnthropicAsOpenAI(ctx, req, onChunk)
+	case constant.AiProviderTypeGemini:
+		return c.chatStreamGeminiAsOpenAI(ctx, req, onChunk)
+	}
 	stream := c.openaiClient.Chat.Completions.NewStreaming(ctx, req)
 	defer stream.Close()
 	for stream.Next() {
@@ -193,14 +394,47 @@ func (c *Client) ChatStreamOpenAI(
 	return nil
 }
 
+// ChatOpenAI 使用 OpenAI 兼容层非流式聊天，req.Model 为模型引用，未设置的生成参数使用服务商 options
 func (c *Client) ChatOpenAI(
 	ctx context.Context,
 	req openai.ChatCompletionNewParams,
 ) (*openai.ChatCompletion, error) {
-	resp, err := c.openaiClient.Chat.Completions.New(ctx, req)
+	cfg := config.AiProvider()
+	list, err := c.candidates(cfg, req.Model, "")
+	if err != nil {
+		return nil, err
+	}
+	var resp *openai.ChatCompletion
+	_, err = invoke(ctx, cfg, list, func() bool { return false }, func(cand candidate) (err error) {
+		resp, err = cand.p.chatOpenAI(ctx, cand.openaiRequest(req))
+		return err
+	})
 	if err != nil {
 		logger.Errorf("openai.ChatOpenAI error: %v", err)
 		return nil, err
 	}
+	// 去掉正文中的 <think> 块，调用方只需要最终回答
+	for i := range resp.Choices {
+		var split thinkSplitter
+		_, content := split.feed(resp.Choices[i].Message.Content)
+		_, rest := split.flush()
+		resp.Choices[i].Message.Content = content + rest
+	}
 	return resp, nil
 }
+
+func (c *provider) chatOpenAI(ctx context.Context, req openai.ChatCompletionNewParams) (*openai.ChatCompletion, error) {
+	if c.openaiClient != nil {
+		return c.openaiClient.Chat.Completions.New(ctx, req)
+	}
+	// anthropic、gemini 没有 OpenAI 兼容接口，使用流式结果聚合
+	var acc openai.ChatCompletionAccumulator
+	err := c.chatStreamOpenAI(ctx, req, func(chunk *openai.ChatCompletionChunk) error {
+		acc.AddChunk(*chunk)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &acc.ChatCompletion, nil
+}
@@ -0,0 +1,224 @@
+package ai_provider
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/openai/openai-go/v2"
+)
+
+// chatParams 按 OpenAI Chat Completions 的 JSON 结构读取请求，供非 OpenAI 协议的服务商转换使用
+// 直接复用 SDK 的序列化结果，避免逐个处理 SDK 中的 union 类型
+type chatParams struct {
+	Model               string          `json:"model"`
+	Messages            []chatMessage   `json:"messages"`
+	Tools               []chatTool      `json:"tools"`
+	MaxTokens           *int64          `json:"max_tokens"`
+	MaxCompletionTokens *int64          `json:"max_completion_tokens"`
+	Temperature         *float64        `json:"temperature"`
+	TopP                *float64        `json:"top_p"`
+	Seed                *int64          `json:"seed"`
+	Stop                json.RawMessage `json:"stop"` // 字符串或字符串数组
+	ResponseFormat      *struct {
+		Type       string `json:"type"` // "text" | "json_object" | "json_schema"
+		JSONSchema struct {
+			Name   string         `json:"name"`
+			Schema map[string]any `json:"schema"`
+		} `json:"json_schema"`
+	} `json:"response_format"`
+}
+
+type chatMessage struct {
+	Role       string          `json:"role"`
+	Content    json.RawMessage `json:"content"` // 字符串或内容块数组
+	ToolCalls  []chatToolCall  `json:"tool_calls"`
+	ToolCallID string          `json:"tool_call_id"`
+}
+
+type chatToolCall struct {
+	ID       string `json:"id"`
+	Function struct {
+		Name      string `json:"name"`
+		Arguments string `json:"arguments"`
+	} `json:"function"`
+}
+
+type chatTool struct {
+	Function struct {
+		Name        string         `json:"name"`
+		Description string         `json:"description"`
+		Parameters  map[string]any `json:"parameters"`
+	} `json:"function"`
+}
+
+// objectSchema 补全工具参数 schema 顶层的 type: object 与 properties，MCP 工具的 schema 可能省略它们
+func objectSchema(schema map[string]any) map[string]any {
+	if schema == nil {
+		schema = map[string]any{}
+	}
+	if _, ok := schema["type"]; !ok {
+		schema["type"] = "object"
+	}
+	if _, ok := schema["properties"]; !ok {
+		schema["properties"] = map[string]any{}
+	}
+	return schema
+}
+
+// contentPart 消息中的一个内容块
+type contentPart struct {
+	Type     string `json:"type"` // "text" | "image_url"
+	Text     string `json:"text"`
+	ImageURL struct {
+		URL string `json:"url"`
+	} `json:"image_url"`
+}
+
+func parseChatParams(req openai.ChatCompletionNewParams) (*chatParams, error) {
+	b, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	p := new(chatParams)
+	if err := json.Unmarshal(b, p); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// maxTokens 请求中的最大输出 token 数，未设置时为 0
+func (p *chatParams) maxTokens() int64 {
+	if p.MaxCompletionTokens != nil {
+		return *p.MaxCompletionTokens
+	}
+	if p.MaxTokens != nil {
+		return *p.MaxTokens
+	}
+	return 0
+}
+
+// jsonSchema 请求要求的输出 JSON Schema，没有时为 nil
+func (p *chatParams) jsonSchema() map[string]any {
+	if p.ResponseFormat == nil || p.ResponseFormat.Type != "json_schema" {
+		return nil
+	}
+	return p.ResponseFormat.JSONSchema.Schema
+}
+
+func (p *chatParams) stopSequences() []string {
+	if len(p.Stop) == 0 {
+		return nil
+	}
+	var one string
+	if json.Unmarshal(p.Stop, &one) == nil {
+		return []string{one}
+	}
+	var list []string
+	_ = json.Unmarshal(p.Stop, &list)
+	return list
+}
+
+// parts 返回消息的内容块，字符串内容视为一个 text 块
+func (m chatMessage) parts() []contentPart {
+	if len(m.Content) == 0 {
+		return nil
+	}
+	var s string
+	if json.Unmarshal(m.Content, &s) == nil {
+		if s == "" {
+			return nil
+		}
+		return []contentPart{{Type: "text", Text: s}}
+	}
+	var parts []contentPart
+	_ = json.Unmarshal(m.Content, &parts)
+	return parts
+}
+
+// parseDataURL 解析 data:<mime>;base64,<数据> 形式的图片，其他形式（http 链接）返回 false
+func parseDataURL(url string) (mime, data string, ok bool) {
+	rest, ok := strings.CutPrefix(url, "data:")
+	if !ok {
+		return "", "", false
+	}
+	meta, data, ok := strings.Cut(rest, ",")
+	if !ok {
+		return "", "", false
+	}
+	mime, ok = strings.CutSuffix(meta, ";base64")
+	return mime, data, ok
+}
+
+// text 拼接消息中的文本
+func (m chatMessage) text() string {
+	var sb strings.Builder
+	for _, p := range m.parts() {
+		if p.Type == "text" {
+			sb.WriteString(p.Text)
+		}
+	}
+	return sb.String()
+}
+
+// toolArguments 工具参数不是 JSON 对象时包装为 {"_": 原始字符串}，与 host 解析工具参数的方式一致
+func toolArguments(args string) json.RawMessage {
+	if args == "" {
+		return json.RawMessage("{}")
+	}
+	var obj map[string]any
+	if json.Unmarshal([]byte(args), &obj) == nil {
+		return json.RawMessage(args)
+	}
+	b, _ := json.Marshal(map[string]string{"_": args})
+	return b
+}
+
+// chunkBuilder 把其他协议的流式事件转换为 openai.ChatCompletionChunk
+// 通过 JSON 反序列化构造，保证 SDK 中 ChatCompletionAccumulator 依赖的字段元信息有效
+type chunkBuilder struct {
+	id      string
+	model   string
+	created int64
+}
+
+func newChunkBuilder(model string) *chunkBuilder {
+	return &chunkBuilder{id: "chatcmpl-" + model, model: model, created: time.Now().Unix()}
+}
+
+// delta 一个增量分片，finish 非空时为本轮结束原因（"stop" | "tool_calls" | "length"）
+func (b *chunkBuilder) delta(delta map[string]any, finish string) (*openai.ChatCompletionChunk, error) {
+	choice := map[string]any{"index": 0, "delta": delta}
+	if finish != "" {
+		choice["finish_reason"] = finish
+	}
+	return b.build(map[string]any{"choices": []any{choice}})
+}
+
+// usage 最后一帧 token 用量
+func (b *chunkBuilder) usage(prompt, completion int64) (*openai.ChatCompletionChunk, error) {
+	return b.build(map[string]any{
+		"choices": []any{},
+		"usage": map[string]any{
+			"prompt_tokens":     prompt,
+			"completion_tokens": completion,
+			"total_tokens":      prompt + completion,
+		},
+	})
+}
+
+func (b *chunkBuilder) build(fields map[string]any) (*openai.ChatCompletionChunk, error) {
+	fields["id"] = b.id
+	fields["object"] = "chat.completion.chunk"
+	fields["created"] = b.created
+	fields["model"] = b.model
+	raw, err := json.Marshal(fields)
+	if err != nil {
+		return nil, err
+	}
+	chunk := new(openai.ChatCompletionChunk)
+	if err := json.Unmarshal(raw, chunk); err != nil {
+		return nil, err
+	}
+	return chunk, nil
+}
@@ -0,0 +1,119 @@
+package ai_provider
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/FantasyRL/go-mcp-demo/config"
+	"github.com/FantasyRL/go-mcp-demo/pkg/constant"
+	"github.com/FantasyRL/go-mcp-demo/pkg/errno"
+	"github.com/FantasyRL/go-mcp-demo/pkg/logger"
+	"github.com/openai/openai-go/v2"
+)
+
+// EmbedRequest ollama /api/embed 的请求
+type EmbedRequest struct {
+	Model     string   `json:"model"`
+	Input     []string `json:"input"`
+	KeepAlive string   `json:"keep_alive,omitempty"`
+}
+
+// EmbedResponse ollama /api/embed 的响应，embeddings 与 input 一一对应
+type EmbedResponse struct {
+	Model           string      `json:"model"`
+	Embeddings      [][]float32 `json:"embeddings"`
+	PromptEvalCount int64       `json:"prompt_eval_count"`
+}
+
+// Embed 计算 input 中每段文本的向量，返回的向量与 input 一一对应，同时返回提供服务的模型
+// model 为模型引用，为空时使用 ai_provider.embedding_model；支持 ollama /api/embed 与 OpenAI 兼容接口的 /embeddings
+// 不同模型的向量不能混用，失败时只按 ai_provider.retry 重试，不切换到 fallback
+func (c *Client) Embed(ctx context.Context, model string, input []string) ([][]float32, ModelInfo, error) {
+	cfg := config.AiProvider()
+	if model == "" {
+		model = cfg.EmbeddingModel
+	}
+	if model == "" {
+		return nil, ModelInfo{}, errno.ParamError.WithMessage("embedding model is required, set ai_provider.embedding_model")
+	}
+	if len(input) == 0 {
+		return nil, ModelInfo{}, nil
+	}
+	p, m, err := c.resolve(cfg, model)
+	if err != nil {
+		return nil, ModelInfo{}, err
+	}
+	if p.openaiClient == nil {
+		return nil, ModelInfo{}, errno.ParamError.WithMessage(fmt.Sprintf("model %q is served by %s provider %q, embeddings are not supported", model, p.typ, p.name))
+	}
+	var vectors [][]float32
+	list := []candidate{{p: p, model: m, opts: cfg.Endpoints()[p.name].Options}}
+	served, err := invoke(ctx, cfg, list, func() bool { return false }, func(cand candidate) (err error) {
+		vectors, err = cand.embed(ctx, input)
+		return err
+	})
+	if err != nil {
+		return nil, ModelInfo{}, err
+	}
+	if len(vectors) != len(input) {
+		return nil, ModelInfo{}, fmt.Errorf("ai_provider: embed returned %d vectors for %d inputs", len(vectors), len(input))
+	}
+	return vectors, served, nil
+}
+
+func (cand candidate) embed(ctx context.Context, input []string) ([][]float32, error) {
+	p := cand.p
+	if p.typ != constant.AiProviderTypeOllama {
+		return p.embedOpenAI(ctx, cand.model, input)
+	}
+	b, _ := json.Marshal(EmbedRequest{Model: cand.model, Input: input, KeepAlive: cand.opts.KeepAlive})
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, p.baseURL+"/api/embed", bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.httpClient.Do(httpReq)
+	if err != nil {
+		logger.Errorf("ollama.Embed Do request error: %v", err)
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		se := newStatusError("ollama embed", resp)
+		logger.Errorf("ollama.Embed error response: %s", se.body)
+		return nil, se
+	}
+	var er EmbedResponse
+	if err := json.NewDecoder(resp.Body).Decode(&er); err != nil {
+		return nil, err
+	}
+	return er.Embeddings, nil
+}
+
+func (p *provider) embedOpenAI(ctx context.Context, model string, input []string) ([][]float32, error) {
+	resp, err := p.openaiClient.Embeddings.New(ctx, openai.EmbeddingNewParams{
+		Model: openai.EmbeddingModel(model),
+		Input: openai.EmbeddingNewParamsInputUnion{OfArrayOfStrings: input},
+	})
+	if err != nil {
+		logger.Errorf("openai.Embed error: %v", err)
+		return nil, err
+	}
+	out := make([][]float32, len(input))
+	for _, d := range resp.Data {
+		if d.Index < 0 || int(d.Index) >= len(out) {
+			return nil, fmt.Errorf("ai_provider: embedding index %d out of range", d.Index)
+		}
+		v := make([]float32, len(d.Embedding))
+		for i, x := range d.Embedding {
+			v[i] = float32(x)
+		}
+		out[d.Index] = v
+	}
+	return out, nil
+}
@@ -0,0 +1,61 @@
+package ai_provider
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"testing"
+
+	"github.com/FantasyRL/go-mcp-demo/config"
+	"github.com/FantasyRL/go-mcp-demo/pkg/constant"
+)
+
+func TestEmbed(t *testing.T) {
+	ollama := stubServer(t, func(w http.ResponseWriter, r *http.Request) {
+		var req EmbedRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		if r.URL.Path != "/api/embed" || req.Model != "nomic-embed-text" || len(req.Input) != 2 {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		_, _ = w.Write([]byte(`{"model":"nomic-embed-text","embeddings":[[1,0],[0,1]]}`))
+	})
+	// OpenAI 兼容接口可能乱序返回，按 index 对齐
+	remote := stubServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/embeddings" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"object":"list","model":"e","data":[{"object":"embedding","index":1,"embedding":[0.5,0.5]},{"object":"embedding","index":0,"embedding":[0.25,0.75]}]}`))
+	})
+
+	cli := newTestClient(t, &config.AiProviderConfig{
+		Model:          "remote/chat",
+		EmbeddingModel: "ollama/nomic-embed-text",
+		Providers: map[string]config.AiProviderEndpoint{
+			"ollama":    {Type: constant.AiProviderTypeOllama, BaseURL: ollama.URL},
+			"remote":    {Type: constant.AiProviderTypeOpenAI, BaseURL: remote.URL},
+			"anthropic": {Type: constant.AiProviderTypeAnthropic, BaseURL: remote.URL, APIKey: "k"},
+		},
+	})
+
+	vecs, served, err := cli.Embed(context.Background(), "", []string{"a", "b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if served.ID != "ollama/nomic-embed-text" || len(vecs) != 2 || !slices.Equal(vecs[1], []float32{0, 1}) {
+		t.Fatalf("ollama: %v from %+v", vecs, served)
+	}
+	vecs, _, err = cli.Embed(context.Background(), "remote/e", []string{"a", "b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(vecs[0], []float32{0.25, 0.75}) || !slices.Equal(vecs[1], []float32{0.5, 0.5}) {
+		t.Fatalf("openai: %v", vecs)
+	}
+	if _, _, err := cli.Embed(context.Background(), "anthropic/claude", []string{"a"}); err == nil {
+		t.Fatal("anthropic provider should not support embeddings")
+	}
+}
@@ -0,0 +1,185 @@
+package ai_provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/FantasyRL/go-mcp-demo/config"
+	"github.com/FantasyRL/go-mcp-demo/pkg/base/guard"
+	"github.com/FantasyRL/go-mcp-demo/pkg/constant"
+	"github.com/FantasyRL/go-mcp-demo/pkg/errno"
+	"github.com/FantasyRL/go-mcp-demo/pkg/logger"
+	"github.com/openai/openai-go/v2"
+)
+
+// candidate 一次请求可以使用的服务商与模型
+type candidate struct {
+	p     *provider
+	model string
+	opts  config.OllamaOptions // 请求开始时服务商配置的 options，整个请求（包括重试）使用同一份
+}
+
+// candidates 按配置快照 cfg 返回依次尝试的服务商与模型：请求的模型在前，之后是 ai_provider.fallback 中的模型
+// api 非空时只使用支持该接口的服务商（如 ollama 原生 /api/chat、responses），请求的模型不支持时返回错误
+func (c *Client) candidates(cfg *config.AiProviderConfig, model string, api string) ([]candidate, error) {
+	endpoints := cfg.Endpoints()
+	p, m, err := c.resolve(cfg, model)
+	if err != nil {
+		return nil, err
+	}
+	if api != "" && !p.supports(api) {
+		return nil, errno.ParamError.WithMessage(fmt.Sprintf("model %q is served by %s provider %q, %s API is not supported", model, p.typ, p.name, api))
+	}
+	list := []candidate{{p: p, model: m, opts: endpoints[p.name].Options}}
+	for _, ref := range cfg.Fallback {
+		fp, fm, err := c.resolve(cfg, ref)
+		if err != nil {
+			logger.Warnf("ai_provider: skip fallback %q: %v", ref, err)
+			continue
+		}
+		if api != "" && !fp.supports(api) {
+			continue
+		}
+		dup := false
+		for _, x := range list {
+			if x.p == fp && x.model == fm {
+				dup = true
+				break
+			}
+		}
+		if !dup {
+			list = append(list, candidate{p: fp, model: fm, opts: endpoints[fp.name].Options})
+		}
+	}
+	return list, nil
+}
+
+// invoke 按顺序在 list 上执行 call，返回实际提供服务的模型，cfg 为 candidates 使用的配置快照：
+// - 429/5xx/网络错误先在同一服务商上按 ai_provider.retry 退避重试，仍失败时切换到下一个模型
+// - 被 Sentinel 限流或熔断时直接切换到下一个模型
+// - 其他错误，或者 started 返回 true（已经向调用方输出过内容）时不再重试，直接返回
+func invoke(ctx context.Context, cfg *config.AiProviderConfig, list []candidate, started func() bool, call func(cand candidate) error) (ModelInfo, error) {
+	retry := cfg.Retry
+	var lastErr error
+	for i, cand := range list {
+		for attempt := 0; ; attempt++ {
+			err := guard.Do(func() error {
+				return call(cand)
+			}, cand.p.resources()...)
+			if err == nil {
+				return modelInfo(cfg, cand.p, cand.model), nil
+			}
+			lastErr = err
+			if started() || ctx.Err() != nil {
+				return ModelInfo{}, err
+			}
+			if guard.IsBlocked(err) {
+				break
+			}
+			if !retriable(err) {
+				return ModelInfo{}, err
+			}
+			if attempt >= retry.MaxRetries {
+				break
+			}
+			wait := backoff(retry, attempt, err)
+			logger.Warnf("ai_provider: %s/%s failed: %v, retry in %s", cand.p.name, cand.model, err, wait)
+			select {
+			case <-ctx.Done():
+				return ModelInfo{}, ctx.Err()
+			case <-time.After(wait):
+			}
+		}
+		if i+1 < len(list) {
+			next := list[i+1]
+			logger.Warnf("ai_provider: %s/%s unavailable: %v, fall back to %s/%s", cand.p.name, cand.model, lastErr, next.p.name, next.model)
+		}
+	}
+	return ModelInfo{}, lastErr
+}
+
+// statusError 服务商返回的非 200 响应
+type statusError struct {
+	op         string
+	code       int
+	status     string
+	body       string
+	retryAfter string
+}
+
+func newStatusError(op string, resp *http.Response) *statusError {
+	body, _ := io.ReadAll(resp.Body)
+	return &statusError{
+		op:         op,
+		code:       resp.StatusCode,
+		status:     resp.Status,
+		body:       string(body),
+		retryAfter: resp.Header.Get("Retry-After"),
+	}
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("%s failed: %s - %s", e.op, e.status, e.body)
+}
+
+// retriable 429、408、5xx 与网络错误可以重试
+func retriable(err error) bool {
+	if code := statusCode(err); code != 0 {
+		return code == http.StatusTooManyRequests || code == http.StatusRequestTimeout || code >= http.StatusInternalServerError
+	}
+	var ne net.Error
+	return errors.As(err, &ne) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+func statusCode(err error) int {
+	var se *statusError
+	if errors.As(err, &se) {
+		return se.code
+	}
+	var oe *openai.Error
+	if errors.As(err, &oe) {
+		return oe.StatusCode
+	}
+	return 0
+}
+
+// backoff 第 attempt 次重试前的等待时间，服务商返回 Retry-After（秒）时优先使用
+func backoff(retry config.AiProviderRetryConfig, attempt int, err error) time.Duration {
+	base, max := retry.Backoff, retry.MaxBackoff
+	if base <= 0 {
+		base = constant.AiProviderRetryBackoff
+	}
+	if max <= 0 {
+		max = constant.AiProviderRetryMaxBackoff
+	}
+	d := base << attempt
+	if ra := retryAfter(err); ra > 0 {
+		d = ra
+	}
+	if d <= 0 || d > max {
+		d = max
+	}
+	return d
+}
+
+func retryAfter(err error) time.Duration {
+	var v string
+	var se *statusError
+	var oe *openai.Error
+	switch {
+	case errors.As(err, &se):
+		v = se.retryAfter
+	case errors.As(err, &oe) && oe.Response != nil:
+		v = oe.Response.Header.Get("Retry-After")
+	}
+	if sec, e := strconv.Atoi(v); e == nil && sec > 0 {
+		return time.Duration(sec) * time.Second
+	}
+	return 0
+}
@@ -0,0 +1,75 @@
+package ai_provider
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/FantasyRL/go-mcp-demo/config"
+	"github.com/FantasyRL/go-mcp-demo/pkg/constant"
+	"github.com/openai/openai-go/v2"
+)
+
+// streamServer 返回一个 OpenAI 兼容的流式接口，前 fail 次请求返回 status
+func streamServer(t *testing.T, status, fail int, text string) (*httptest.Server, *atomic.Int32) {
+	var calls atomic.Int32
+	srv := stubServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if int(calls.Add(1)) <= fail {
+			http.Error(w, `{"error":{"message":"busy"}}`, status)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprintf(w, "data: {\"id\":\"1\",\"object\":\"chat.completion.chunk\",\"model\":\"m\",\"choices\":[{\"index\":0,\"delta\":{\"content\":%q}}]}\n\n", text)
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	})
+	return srv, &calls
+}
+
+func TestChatStreamOpenAIFallback(t *testing.T) {
+	primary, primaryCalls := streamServer(t, http.StatusServiceUnavailable, 100, "")
+	backup, backupCalls := streamServer(t, http.StatusTooManyRequests, 1, "hi")
+
+	cfg := &config.AiProviderConfig{
+		Model: "primary/a",
+		Providers: map[string]config.AiProviderEndpoint{
+			"primary": {Type: constant.AiProviderTypeOpenAI, BaseURL: primary.URL},
+			"backup":  {Type: constant.AiProviderTypeOpenAI, BaseURL: backup.URL},
+		},
+		Fallback: []string{"backup/b"},
+		Retry:    config.AiProviderRetryConfig{MaxRetries: 1, Backoff: time.Millisecond},
+	}
+	cli := newTestClient(t, cfg)
+
+	var got string
+	served, err := cli.ChatStreamOpenAI(context.Background(), openai.ChatCompletionNewParams{}, func(chunk *openai.ChatCompletionChunk) error {
+		if len(chunk.Choices) > 0 {
+			got += chunk.Choices[0].Delta.Content
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "hi" || served.ID != "backup/b" {
+		t.Fatalf("got %q from %+v", got, served)
+	}
+	// 主服务商重试一次后切换，备用服务商 429 后重试成功
+	if primaryCalls.Load() != 2 || backupCalls.Load() != 2 {
+		t.Fatalf("calls: primary=%d backup=%d", primaryCalls.Load(), backupCalls.Load())
+	}
+
+	// 4xx 不重试也不切换
+	bad, badCalls := streamServer(t, http.StatusBadRequest, 100, "")
+	cfg.Providers["primary"] = config.AiProviderEndpoint{Type: constant.AiProviderTypeOpenAI, BaseURL: bad.URL}
+	cli = newTestClient(t, cfg)
+	if _, err := cli.ChatStreamOpenAI(context.Background(), openai.ChatCompletionNewParams{}, func(*openai.ChatCompletionChunk) error { return nil }); err == nil {
+		t.Fatal("expected error")
+	}
+	if badCalls.Load() != 1 || backupCalls.Load() != 2 {
+		t.Fatalf("calls: bad=%d backup=%d", badCalls.Load(), backupCalls.Load())
+	}
+}
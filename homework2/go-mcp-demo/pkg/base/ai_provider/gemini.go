@@ -0,0 +1,460 @@
+package ai_provider
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/FantasyRL/go-mcp-demo/pkg/errno"
+	"github.com/FantasyRL/go-mcp-demo/pkg/logger"
+	"github.com/openai/openai-go/v2"
+)
+
+// GeminiPart 内容块，text / functionCall / functionResponse 只会出现一个
+type GeminiPart struct {
+	Text             string                  `json:"text,omitempty"`
+	Thought          bool                    `json:"thought,omitempty"`    // includeThoughts 时的思考摘要
+	InlineData       *GeminiBlob             `json:"inlineData,omitempty"` // base64 图片
+	FileData         *GeminiFileData         `json:"fileData,omitempty"`   // 图片链接
+	FunctionCall     *GeminiFunctionCall     `json:"functionCall,omitempty"`
+	FunctionResponse *GeminiFunctionResponse `json:"functionResponse,omitempty"`
+}
+
+type GeminiBlob struct {
+	MimeType string `json:"mimeType"`
+	Data     string `json:"data"`
+}
+
+type GeminiFileData struct {
+	MimeType string `json:"mimeType,omitempty"`
+	FileURI  string `json:"fileUri"`
+}
+
+type GeminiFunctionCall struct {
+	ID   string         `json:"id,omitempty"`
+	Name string         `json:"name"`
+	Args map[string]any `json:"args"`
+}
+
+type GeminiFunctionResponse struct {
+	ID       string         `json:"id,omitempty"`
+	Name     string         `json:"name"`
+	Response map[string]any `json:"response"`
+}
+
+// GeminiContent 对话消息，role 只有 "user" | "model"，工具结果放在 user 消息中
+type GeminiContent struct {
+	Role  string       `json:"role,omitempty"`
+	Parts []GeminiPart `json:"parts"`
+}
+
+// GeminiFunctionDeclaration 工具定义，parameters 只支持 OpenAPI Schema 的子集，见 SanitizeGeminiSchema
+type GeminiFunctionDeclaration struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description,omitempty"`
+	Parameters  map[string]any `json:"parameters,omitempty"`
+}
+
+type GeminiTool struct {
+	FunctionDeclarations []GeminiFunctionDeclaration `json:"functionDeclarations"`
+}
+
+type GeminiGenerationConfig struct {
+	Temperature     *float64 `json:"temperature,omitempty"`
+	TopP            *float64 `json:"topP,omitempty"`
+	TopK            *int     `json:"topK,omitempty"`
+	MaxOutputTokens int64    `json:"maxOutputTokens,omitempty"`
+	StopSequences   []string `json:"stopSequences,omitempty"`
+	Seed            *int64   `json:"seed,omitempty"`
+	// 结构化输出：responseMimeType 为 application/json 时按 responseSchema 生成
+	ResponseMimeType string         `json:"responseMimeType,omitempty"`
+	ResponseSchema   map[string]any `json:"responseSchema,omitempty"`
+}
+
+// GeminiRequest generateContent / streamGenerateContent 的请求
+type GeminiRequest struct {
+	SystemInstruction *GeminiContent          `json:"systemInstruction,omitempty"`
+	Contents          []GeminiContent         `json:"contents"`
+	Tools             []GeminiTool            `json:"tools,omitempty"`
+	GenerationConfig  *GeminiGenerationConfig `json:"generationConfig,omitempty"`
+}
+
+// GeminiResponse 一个流式分片
+type GeminiResponse struct {
+	Candidates []struct {
+		Content      GeminiContent `json:"content"`
+		FinishReason string        `json:"finishReason"` // "STOP" | "MAX_TOKENS" | "SAFETY" | ...
+	} `json:"candidates"`
+	UsageMetadata *struct {
+		PromptTokenCount     int64 `json:"promptTokenCount"`
+		CandidatesTokenCount int64 `json:"candidatesTokenCount"`
+	} `json:"usageMetadata"`
+	ModelVersion string `json:"modelVersion"`
+}
+
+// NewGeminiFunctionDeclaration 由 MCP 工具的 JSON Schema 生成 Gemini 工具定义
+func NewGeminiFunctionDeclaration(name, description string, schema map[string]any) GeminiFunctionDeclaration {
+	fd := GeminiFunctionDeclaration{Name: name, Description: description}
+	if params := SanitizeGeminiSchema(schema); len(params) > 0 {
+		// 没有参数的工具不能带 properties 为空的 object
+		if props, _ := params["properties"].(map[string]any); len(props) > 0 {
+			fd.Parameters = params
+		}
+	}
+	return fd
+}
+
+// geminiSchemaKeys Gemini 支持的 schema 关键字，其余关键字会被去掉
+var geminiSchemaKeys = map[string]bool{
+	"type": true, "format": true, "title": true, "description": true, "nullable": true, "enum": true,
+	"properties": true, "required": true, "items": true, "minItems": true, "maxItems": true,
+	"minimum": true, "maximum": true, "minLength": true, "maxLength": true, "pattern": true,
+	"anyOf": true, "propertyOrdering": true,
+}
+
+// SanitizeGeminiSchema 将 JSON Schema 转换为 Gemini 支持的子集：
+// 展开 $ref（#/$defs、#/definitions），["string","null"] 转为 nullable，const 转为 enum，oneOf 转为 anyOf，
+// 合并 allOf，去掉 additionalProperties、$schema、default、examples 等不支持的关键字
+func SanitizeGeminiSchema(schema map[string]any) map[string]any {
+	if schema == nil {
+		return nil
+	}
+	defs := map[string]any{}
+	for _, key := range []string{"$defs", "definitions"} {
+		if m, ok := schema[key].(map[string]any); ok {
+			for k, v := range m {
+				defs[k] = v
+			}
+		}
+	}
+	return sanitizeGemini(schema, defs, 0)
+}
+
+func sanitizeGemini(schema map[string]any, defs map[string]any, depth int) map[string]any {
+	if depth > 16 { // 防止循环引用
+		return map[string]any{"type": "object"}
+	}
+	if ref, ok := schema["$ref"].(string); ok {
+		name := ref[strings.LastIndex(ref, "/")+1:]
+		if target, ok := defs[name].(map[string]any); ok {
+			return sanitizeGemini(target, defs, depth+1)
+		}
+		return map[string]any{"type": "object"}
+	}
+
+	out := map[string]any{}
+	if all, ok := schema["allOf"].([]any); ok {
+		for _, sub := range all {
+			if m, ok := sub.(map[string]any); ok {
+				for k, v := range sanitizeGemini(m, defs, depth+1) {
+					out[k] = v
+				}
+			}
+		}
+	}
+	for k, v := range schema {
+		switch k {
+		case "type":
+			if list, ok := v.([]any); ok {
+				for _, t := range list {
+					if t == "null" {
+						out["nullable"] = true
+					} else if _, set := out["type"]; !set {
+						out["type"] = t
+					}
+				}
+				continue
+			}
+			out[k] = v
+		case "const":
+			out["enum"] = []any{v}
+		case "oneOf", "anyOf":
+			list, _ := v.([]any)
+			var subs []any
+			for _, sub := range list {
+				if m, ok := sub.(map[string]any); ok {
+					subs = append(subs, sanitizeGemini(m, defs, depth+1))
+				}
+			}
+			out["anyOf"] = subs
+		case "properties":
+			props := map[string]any{}
+			if m, ok := v.(map[string]any); ok {
+				for name, sub := range m {
+					if sm, ok := sub.(map[string]any); ok {
+						props[name] = sanitizeGemini(sm, defs, depth+1)
+					}
+				}
+			}
+			out[k] = props
+		case "items":
+			if m, ok := v.(map[string]any); ok {
+				out[k] = sanitizeGemini(m, defs, depth+1)
+			}
+		default:
+			if geminiSchemaKeys[k] {
+				out[k] = v
+			}
+		}
+	}
+
+	// 字符串的 format 只支持 enum 与 date-time
+	if f := out["format"]; out["type"] == "string" && f != "date-time" && f != "enum" {
+		delete(out, "format")
+	}
+	// enum 只支持字符串
+	if enum, ok := out["enum"].([]any); ok {
+		strs := make([]any, 0, len(enum))
+		for _, e := range enum {
+			if s, ok := e.(string); ok {
+				strs = append(strs, s)
+			}
+		}
+		if len(strs) != len(enum) {
+			delete(out, "enum")
+		} else if _, ok := out["type"]; !ok {
+			out["type"] = "string"
+		}
+	}
+	// required 只能引用存在的属性
+	if req, ok := out["required"].([]any); ok {
+		props, _ := out["properties"].(map[string]any)
+		kept := make([]any, 0, len(req))
+		for _, r := range req {
+			if name, ok := r.(string); ok && props[name] != nil {
+				kept = append(kept, name)
+			}
+		}
+		if len(kept) == 0 {
+			delete(out, "required")
+		} else {
+			out["required"] = kept
+		}
+	}
+	return out
+}
+
+func (p *provider) chatStreamGemini(ctx context.Context, model string, req GeminiRequest, onResp func(*GeminiResponse) error) error {
+	endpoint := fmt.Sprintf("%s/v1beta/models/%s:streamGenerateContent?alt=sse", strings.TrimRight(p.baseURL, "/"), url.PathEscape(model))
+
+	b, _ := json.Marshal(req)
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(b))
+	if err != nil {
+		logger.Errorf("gemini.ChatStream NewRequestWithContext error: %v", err)
+		return err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("x-goog-api-key", p.apiKey)
+
+	resp, err := p.httpClient.Do(httpReq)
+	if err != nil {
+		logger.Errorf("gemini.ChatStream Do request error: %v", err)
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		se := newStatusError("gemini generateContent", resp)
+		logger.Errorf("gemini.ChatStream error response: %s", se.body)
+		return se
+	}
+
+	sc := bufio.NewScanner(resp.Body)
+	buf := make([]byte, 0, 64*1024)
+	sc.Buffer(buf, 10*1024*1024)
+	for sc.Scan() {
+		data, ok := bytes.CutPrefix(sc.Bytes(), []byte("data:"))
+		if !ok {
+			continue
+		}
+		r := new(GeminiResponse)
+		if err := json.Unmarshal(bytes.TrimSpace(data), r); err != nil {
+			logger.Errorf("gemini.ChatStream json unmarshal chunk error: %v", err)
+			return err
+		}
+		if err := onResp(r); err != nil {
+			if errors.Is(err, errno.OllamaInternalStopStream) {
+				return nil
+			}
+			return err
+		}
+	}
+	return sc.Err()
+}
+
+// geminiRequest 将 OpenAI Chat Completions 请求转换为 Gemini 请求：
+// system/developer 消息合并为 systemInstruction，assistant 的 tool_calls 转为 functionCall，
+// tool 消息转为 user 消息中的 functionResponse（按 tool_call_id 找回工具名），相邻的同角色消息合并；
+// response_format 的 JSON Schema 清理后作为 responseSchema
+func geminiRequest(params *chatParams) GeminiRequest {
+	var req GeminiRequest
+	var system []GeminiPart
+	toolNames := map[string]string{} // tool_call_id -> 工具名
+	add := func(role string, parts ...GeminiPart) {
+		if len(parts) == 0 {
+			return
+		}
+		if n := len(req.Contents); n > 0 && req.Contents[n-1].Role == role {
+			req.Contents[n-1].Parts = append(req.Contents[n-1].Parts, parts...)
+			return
+		}
+		req.Contents = append(req.Contents, GeminiContent{Role: role, Parts: parts})
+	}
+	for _, m := range params.Messages {
+		switch m.Role {
+		case "system", "developer":
+			if s := m.text(); s != "" {
+				system = append(system, GeminiPart{Text: s})
+			}
+		case "user":
+			var parts []GeminiPart
+			for _, part := range m.parts() {
+				switch part.Type {
+				case "text":
+					parts = append(parts, GeminiPart{Text: part.Text})
+				case "image_url":
+					if mime, data, ok := parseDataURL(part.ImageURL.URL); ok {
+						parts = append(parts, GeminiPart{InlineData: &GeminiBlob{MimeType: mime, Data: data}})
+					} else {
+						parts = append(parts, GeminiPart{FileData: &GeminiFileData{FileURI: part.ImageURL.URL}})
+					}
+				}
+			}
+			add("user", parts...)
+		case "assistant":
+			var parts []GeminiPart
+			if s := m.text(); s != "" {
+				parts = append(parts, GeminiPart{Text: s})
+			}
+			for _, tc := range m.ToolCalls {
+				toolNames[tc.ID] = tc.Function.Name
+				var args map[string]any
+				_ = json.Unmarshal(toolArguments(tc.Function.Arguments), &args)
+				parts = append(parts, GeminiPart{FunctionCall: &GeminiFunctionCall{Name: tc.Function.Name, Args: args}})
+			}
+			add("model", parts...)
+		case "tool":
+			add("user", GeminiPart{FunctionResponse: &GeminiFunctionResponse{
+				Name:     toolNames[m.ToolCallID],
+				Response: map[string]any{"content": m.text()},
+			}})
+		}
+	}
+	if len(system) > 0 {
+		req.SystemInstruction = &GeminiContent{Parts: system}
+	}
+	if len(params.Tools) > 0 {
+		decls := make([]GeminiFunctionDeclaration, 0, len(params.Tools))
+		for _, t := range params.Tools {
+			decls = append(decls, NewGeminiFunctionDeclaration(t.Function.Name, t.Function.Description, t.Function.Parameters))
+		}
+		req.Tools = []GeminiTool{{FunctionDeclarations: decls}}
+	}
+	gc := &GeminiGenerationConfig{
+		Temperature:     params.Temperature,
+		TopP:            params.TopP,
+		MaxOutputTokens: params.maxTokens(),
+		StopSequences:   params.stopSequences(),
+		Seed:            params.Seed,
+	}
+	if schema := params.jsonSchema(); schema != nil {
+		gc.ResponseMimeType = "application/json"
+		gc.ResponseSchema = SanitizeGeminiSchema(schema)
+	}
+	if gc.Temperature != nil || gc.TopP != nil || gc.MaxOutputTokens > 0 || len(gc.StopSequences) > 0 || gc.Seed != nil || gc.ResponseMimeType != "" {
+		req.GenerationConfig = gc
+	}
+	return req
+}
+
+// geminiFinishReason 将 finishReason 转为 OpenAI 的 finish_reason
+func geminiFinishReason(reason string) string {
+	switch reason {
+	case "STOP", "":
+		return "stop"
+	case "MAX_TOKENS":
+		return "length"
+	default:
+		return "content_filter"
+	}
+}
+
+// chatStreamGeminiAsOpenAI 以 OpenAI 流式分片的形式返回 Gemini 的流式结果
+// Gemini 的 functionCall 一次返回完整参数且没有 id，这里按顺序生成 id；流结束后补发 finish_reason 与用量
+func (p *provider) chatStreamGeminiAsOpenAI(
+	ctx context.Context,
+	req openai.ChatCompletionNewParams,
+	onChunk func(*openai.ChatCompletionChunk) error,
+) error {
+	params, err := parseChatParams(req)
+	if err != nil {
+		return err
+	}
+	b := newChunkBuilder(params.Model)
+	var calls int
+	var finish string
+	var prompt, completion int64
+	emit := func(chunk *openai.ChatCompletionChunk, err error) error {
+		if err != nil {
+			return err
+		}
+		return onChunk(chunk)
+	}
+	err = p.chatStreamGemini(ctx, params.Model, geminiRequest(params), func(r *GeminiResponse) error {
+		if r.UsageMetadata != nil {
+			prompt, completion = r.UsageMetadata.PromptTokenCount, r.UsageMetadata.CandidatesTokenCount
+		}
+		if len(r.Candidates) == 0 {
+			return nil
+		}
+		cand := r.Candidates[0]
+		if cand.FinishReason != "" {
+			finish = cand.FinishReason
+		}
+		for _, part := range cand.Content.Parts {
+			switch {
+			case part.FunctionCall != nil:
+				args, _ := json.Marshal(part.FunctionCall.Args)
+				id := part.FunctionCall.ID
+				if id == "" {
+					id = fmt.Sprintf("call_%d", calls)
+				}
+				if err := emit(b.delta(map[string]any{"tool_calls": []any{map[string]any{
+					"index":    calls,
+					"id":       id,
+					"type":     "function",
+					"function": map[string]any{"name": part.FunctionCall.Name, "arguments": string(args)},
+				}}}, "")); err != nil {
+					return err
+				}
+				calls++
+			case part.Thought:
+				if err := emit(b.delta(map[string]any{"reasoning_content": part.Text}, "")); err != nil {
+					return err
+				}
+			case part.Text != "":
+				if err := emit(b.delta(map[string]any{"content": part.Text}, "")); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	reason := geminiFinishReason(finish)
+	if calls > 0 {
+		reason = "tool_calls"
+	}
+	if err := emit(b.delta(map[string]any{}, reason)); err != nil {
+		return err
+	}
+	return emit(b.usage(prompt, completion))
+}
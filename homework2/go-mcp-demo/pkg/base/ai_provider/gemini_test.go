@@ -0,0 +1,150 @@
+package ai_provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/FantasyRL/go-mcp-demo/config"
+	"github.com/FantasyRL/go-mcp-demo/pkg/constant"
+	"github.com/openai/openai-go/v2"
+)
+
+func TestSanitizeGeminiSchema(t *testing.T) {
+	var schema map[string]any
+	_ = json.Unmarshal([]byte(`{
+	  "$schema": "http://json-schema.org/draft-07/schema#",
+	  "type": "object",
+	  "additionalProperties": false,
+	  "$defs": {"unit": {"type": "string", "enum": ["c", "f"], "default": "c"}},
+	  "properties": {
+	    "city": {"type": ["string", "null"], "format": "hostname", "examples": ["Paris"]},
+	    "unit": {"$ref": "#/$defs/unit"},
+	    "days": {"type": "integer", "exclusiveMinimum": 0, "enum": [1, 3]},
+	    "mode": {"const": "fast"},
+	    "target": {"oneOf": [{"type": "string"}, {"type": "object", "additionalProperties": true}]}
+	  },
+	  "required": ["city", "missing"]
+	}`), &schema)
+
+	got := SanitizeGeminiSchema(schema)
+	var want map[string]any
+	_ = json.Unmarshal([]byte(`{
+	  "type": "object",
+	  "properties": {
+	    "city": {"type": "string", "nullable": true},
+	    "unit": {"type": "string", "enum": ["c", "f"]},
+	    "days": {"type": "integer"},
+	    "mode": {"type": "string", "enum": ["fast"]},
+	    "target": {"anyOf": [{"type": "string"}, {"type": "object"}]}
+	  },
+	  "required": ["city"]
+	}`), &want)
+	if !reflect.DeepEqual(got, want) {
+		g, _ := json.Marshal(got)
+		t.Fatalf("sanitized schema = %s", g)
+	}
+
+	// 没有参数的工具不带 parameters
+	if fd := NewGeminiFunctionDeclaration("ping", "", map[string]any{"type": "object", "properties": map[string]any{}}); fd.Parameters != nil {
+		t.Fatalf("parameters = %v", fd.Parameters)
+	}
+}
+
+func TestChatStreamOpenAIGemini(t *testing.T) {
+	var got GeminiRequest
+	srv := stubServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1beta/models/gemini-test:streamGenerateContent" || r.URL.Query().Get("alt") != "sse" || r.Header.Get("x-goog-api-key") != "g-key" {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, chunk := range []string{
+			`{"candidates":[{"content":{"role":"model","parts":[{"text":"Checking "}]}}]}`,
+			`{"candidates":[{"content":{"role":"model","parts":[{"text":"Paris."},{"functionCall":{"name":"weather","args":{"city":"Paris"}}}]},"finishReason":"STOP"}],"usageMetadata":{"promptTokenCount":20,"candidatesTokenCount":8}}`,
+		} {
+			fmt.Fprintf(w, "data: %s\r\n\r\n", chunk)
+		}
+	})
+
+	cli := newTestClient(t, &config.AiProviderConfig{
+		Model: "gemini/gemini-test",
+		Providers: map[string]config.AiProviderEndpoint{
+			"gemini": {Type: constant.AiProviderTypeGemini, BaseURL: srv.URL, APIKey: "g-key"},
+		},
+	})
+
+	req := openai.ChatCompletionNewParams{
+		Messages: []openai.ChatCompletionMessageParamUnion{
+			openai.SystemMessage("be brief"),
+			openai.UserMessage("weather in Rome?"),
+			{OfAssistant: &openai.ChatCompletionAssistantMessageParam{
+				ToolCalls: []openai.ChatCompletionMessageToolCallUnionParam{{
+					OfFunction: &openai.ChatCompletionMessageFunctionToolCallParam{
+						ID:       "call_0",
+						Function: openai.ChatCompletionMessageFunctionToolCallFunctionParam{Name: "weather", Arguments: `{"city":"Rome"}`},
+					},
+				}},
+			}},
+			openai.ToolMessage("sunny", "call_0"),
+		},
+		Tools: []openai.ChatCompletionToolUnionParam{{
+			OfFunction: &openai.ChatCompletionFunctionToolParam{
+				Function: openai.FunctionDefinitionParam{
+					Name: "weather",
+					Parameters: openai.FunctionParameters{
+						"type":                 "object",
+						"additionalProperties": false,
+						"properties":           map[string]any{"city": map[string]any{"type": "string"}},
+					},
+				},
+			},
+		}},
+	}
+
+	var acc openai.ChatCompletionAccumulator
+	var finish string
+	if _, err := cli.ChatStreamOpenAI(context.Background(), req, func(chunk *openai.ChatCompletionChunk) error {
+		acc.AddChunk(*chunk)
+		if len(chunk.Choices) > 0 && chunk.Choices[0].FinishReason != "" {
+			finish = chunk.Choices[0].FinishReason
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// 请求：systemInstruction、functionCall、按 tool_call_id 找回工具名的 functionResponse、清理后的 schema
+	if got.SystemInstruction == nil || got.SystemInstruction.Parts[0].Text != "be brief" {
+		t.Fatalf("system = %+v", got.SystemInstruction)
+	}
+	if len(got.Contents) != 3 || got.Contents[1].Role != "model" || got.Contents[1].Parts[0].FunctionCall.Args["city"] != "Rome" {
+		t.Fatalf("contents = %+v", got.Contents)
+	}
+	if fr := got.Contents[2].Parts[0].FunctionResponse; got.Contents[2].Role != "user" || fr == nil || fr.Name != "weather" || fr.Response["content"] != "sunny" {
+		t.Fatalf("function response = %+v", got.Contents[2])
+	}
+	params := got.Tools[0].FunctionDeclarations[0].Parameters
+	if _, ok := params["additionalProperties"]; ok || params["type"] != "object" {
+		t.Fatalf("parameters = %v", params)
+	}
+
+	// 响应
+	msg := acc.Choices[0].Message
+	if msg.Content != "Checking Paris." || finish != "tool_calls" {
+		t.Fatalf("content = %q, finish = %q", msg.Content, finish)
+	}
+	if len(msg.ToolCalls) != 1 || msg.ToolCalls[0].ID == "" || msg.ToolCalls[0].Function.Name != "weather" || msg.ToolCalls[0].Function.Arguments != `{"city":"Paris"}` {
+		t.Fatalf("tool calls = %+v", msg.ToolCalls)
+	}
+	if acc.Usage.PromptTokens != 20 || acc.Usage.CompletionTokens != 8 {
+		t.Fatalf("usage = %+v", acc.Usage)
+	}
+}
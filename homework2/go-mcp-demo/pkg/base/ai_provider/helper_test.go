@@ -0,0 +1,25 @@
+package ai_provider
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/FantasyRL/go-mcp-demo/config"
+)
+
+// stubServer 启动一个模拟的上游服务，测试结束时关闭
+func stubServer(t *testing.T, h http.HandlerFunc) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(h)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// newTestClient 以 cfg 作为当前 ai_provider 配置创建客户端，测试结束时还原配置
+func newTestClient(t *testing.T, cfg *config.AiProviderConfig) *Client {
+	t.Helper()
+	config.SetAiProvider(cfg)
+	t.Cleanup(func() { config.SetAiProvider(nil) })
+	return NewAiProviderClient()
+}
@@ -2,7 +2,6 @@ package ai_provider
 
 import (
 	"encoding/json"
-	"github.com/FantasyRL/go-mcp-demo/config"
 )
 
 // ToolFunction 工具调用函数
@@ -22,7 +21,8 @@ type ToolCall struct {
 type Message struct {
 	Role      string     `json:"role"`                 // "system"(初始化AI风格) | "user" | "assistant" | "tool"(工具结果回填给模型时使用)
 	Content   string     `json:"content,omitempty"`    // 对话文本 | 工具结果
-	Images    []string   `json:"images,omitempty"`     // 多模态... 后面再说
+	Thinking  string     `json:"thinking,omitempty"`   // 推理模型的思考过程（ollama 的 thinking 字段或正文中的 <think> 块），不写入历史
+	Images    []string   `json:"images,omitempty"`     // 图片 base64（不带 data: 前缀），host 历史中保存的是图片引用，发送前替换
 	ToolCalls []ToolCall `json:"tool_calls,omitempty"` // 模型(assistant)需要调用的工具列表
 	ToolName  string     `json:"tool_name,omitempty"`  // 回填工具执行结果时带上,对应 ToolCall.Function.Name,声明这是哪个工具的结果
 }
@@ -38,11 +38,13 @@ type ChatRequest struct {
 }
 
 type ChatResponse struct {
-	Model         string  `json:"model"`          // AiProvider 模型名 如 qwen3:4b
-	CreatedAt     string  `json:"created_at"`     // 响应时间
-	Message       Message `json:"message"`        // toolCalls不为空时则去执行工具
-	Done          bool    `json:"done"`           // 非流式时总是true，流式时表示是否结束
-	TotalDuration int64   `json:"total_duration"` // 整体耗时
+	Model           string  `json:"model"`             // AiProvider 模型名 如 qwen3:4b
+	CreatedAt       string  `json:"created_at"`        // 响应时间
+	Message         Message `json:"message"`           // toolCalls不为空时则去执行工具
+	Done            bool    `json:"done"`              // 非流式时总是true，流式时表示是否结束
+	TotalDuration   int64   `json:"total_duration"`    // 整体耗时
+	PromptEvalCount int64   `json:"prompt_eval_count"` // 输入 token 数，流式时只在最后一帧返回
+	EvalCount       int64   `json:"eval_count"`        // 输出 token 数，流式时只在最后一帧返回
 }
 
 // ParseToolArguments 解析ToolFunction
@@ -81,20 +83,3 @@ func ParseToolArguments(raw json.RawMessage) (any, error) {
 	// 再兜底：原样返回
 	return string(raw), nil
 }
-
-func BuildOptions() map[string]any {
-	opt := map[string]any{}
-	if config.AiProvider.Options.Temperature != nil {
-		opt["temperature"] = *config.AiProvider.Options.Temperature
-	}
-	if config.AiProvider.Options.TopP != nil {
-		opt["top_p"] = *config.AiProvider.Options.TopP
-	}
-	if config.AiProvider.Options.TopK != nil {
-		opt["top_k"] = *config.AiProvider.Options.TopK
-	}
-	for k, v := range config.AiProvider.Options.Extra {
-		opt[k] = v
-	}
-	return opt
-}
@@ -0,0 +1,95 @@
+package ai_provider
+
+import (
+	"maps"
+
+	"github.com/FantasyRL/go-mcp-demo/config"
+	"github.com/openai/openai-go/v2"
+	"github.com/openai/openai-go/v2/responses"
+)
+
+// 生成参数统一用 config.OllamaOptions 描述（服务商配置的 options 与单次请求的覆盖），按协议转换：
+// - ollama /api/chat：BuildOptions
+// - OpenAI 兼容接口：ApplyOpenAIOptions，anthropic、gemini 再从 Chat Completions 请求转换
+// - Responses API：applyResponsesOptions
+// 单次请求的参数优先，未设置的再用服务商配置补全：ollama 见 candidate.ollamaRequest，其他协议在 Apply*Options 中跳过请求已设置的字段
+
+// BuildOptions 将 options 转为 ollama /api/chat 的 options，max_tokens 对应 num_predict
+func BuildOptions(o config.OllamaOptions) map[string]any {
+	opt := map[string]any{}
+	if o.Temperature != nil {
+		opt["temperature"] = *o.Temperature
+	}
+	if o.TopP != nil {
+		opt["top_p"] = *o.TopP
+	}
+	if o.TopK != nil {
+		opt["top_k"] = *o.TopK
+	}
+	if o.MaxTokens != nil {
+		opt["num_predict"] = *o.MaxTokens
+	}
+	if o.Seed != nil {
+		opt["seed"] = *o.Seed
+	}
+	if len(o.Stop) > 0 {
+		opt["stop"] = o.Stop
+	}
+	for k, v := range o.Extra {
+		opt[k] = v
+	}
+	return opt
+}
+
+// ApplyOpenAIOptions 把 options 写入 OpenAI 兼容请求中尚未设置的 temperature、top_p、max_tokens、stop、seed
+// top_k、extra 只对 ollama 有效，OpenAI 兼容接口没有对应参数
+func ApplyOpenAIOptions(req *openai.ChatCompletionNewParams, o config.OllamaOptions) {
+	if o.Temperature != nil && !req.Temperature.Valid() {
+		req.Temperature = openai.Float(*o.Temperature)
+	}
+	if o.TopP != nil && !req.TopP.Valid() {
+		req.TopP = openai.Float(*o.TopP)
+	}
+	if o.MaxTokens != nil && !req.MaxTokens.Valid() && !req.MaxCompletionTokens.Valid() {
+		req.MaxTokens = openai.Int(int64(*o.MaxTokens))
+	}
+	if o.Seed != nil && !req.Seed.Valid() {
+		req.Seed = openai.Int(int64(*o.Seed))
+	}
+	if len(o.Stop) > 0 && !req.Stop.OfString.Valid() && len(req.Stop.OfStringArray) == 0 {
+		req.Stop.OfStringArray = o.Stop
+	}
+}
+
+// applyResponsesOptions 同 ApplyOpenAIOptions，Responses API 只支持 temperature、top_p、max_output_tokens
+func applyResponsesOptions(req *responses.ResponseNewParams, o config.OllamaOptions) {
+	if o.Temperature != nil && !req.Temperature.Valid() {
+		req.Temperature = openai.Float(*o.Temperature)
+	}
+	if o.TopP != nil && !req.TopP.Valid() {
+		req.TopP = openai.Float(*o.TopP)
+	}
+	if o.MaxTokens != nil && !req.MaxOutputTokens.Valid() {
+		req.MaxOutputTokens = openai.Int(int64(*o.MaxTokens))
+	}
+}
+
+// ollamaRequest 补全模型名，请求中的 options 逐项覆盖服务商配置
+func (cand candidate) ollamaRequest(req ChatRequest) ChatRequest {
+	req.Model = cand.model
+	opts := cand.opts
+	merged := BuildOptions(opts)
+	maps.Copy(merged, req.Options)
+	req.Options = merged
+	if req.KeepAlive == "" {
+		req.KeepAlive = opts.KeepAlive
+	}
+	return req
+}
+
+// openaiRequest 补全模型名，请求中未设置的生成参数使用服务商配置
+func (cand candidate) openaiRequest(req openai.ChatCompletionNewParams) openai.ChatCompletionNewParams {
+	req.Model = cand.model
+	ApplyOpenAIOptions(&req, cand.opts)
+	return req
+}
@@ -0,0 +1,41 @@
+package ai_provider
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/FantasyRL/go-mcp-demo/config"
+	"github.com/openai/openai-go/v2"
+)
+
+func ptr[T any](v T) *T { return &v }
+
+func TestOptionsMapping(t *testing.T) {
+	o := config.OllamaOptions{
+		Temperature: ptr(0.2),
+		TopK:        ptr(40),
+		MaxTokens:   ptr(1024),
+		Seed:        ptr(7),
+		Stop:        []string{"###"},
+		Extra:       map[string]any{"num_ctx": 4096},
+	}
+
+	opts := BuildOptions(o)
+	if opts["num_predict"] != 1024 || opts["seed"] != 7 || opts["top_k"] != 40 || opts["num_ctx"] != 4096 {
+		t.Fatalf("ollama options = %v", opts)
+	}
+
+	// 请求中已设置的参数不被覆盖
+	req := openai.ChatCompletionNewParams{Temperature: openai.Float(0)}
+	ApplyOpenAIOptions(&req, o)
+	if req.Temperature.Value != 0 || req.MaxTokens.Value != 1024 || req.Seed.Value != 7 || !slices.Equal(req.Stop.OfStringArray, []string{"###"}) {
+		t.Fatalf("openai params = %+v", req)
+	}
+	params, err := parseChatParams(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if params.maxTokens() != 1024 || *params.Seed != 7 || !slices.Equal(params.stopSequences(), []string{"###"}) {
+		t.Fatalf("chat params = %+v", params)
+	}
+}
@@ -0,0 +1,141 @@
+package ai_provider
+
+import (
+	"encoding/json"
+	"strings"
+	"unicode"
+
+	"github.com/openai/openai-go/v2"
+	"github.com/openai/openai-go/v2/packages/respjson"
+)
+
+const (
+	thinkOpen  = "<think>"
+	thinkClose = "</think>"
+)
+
+// reasoningFields OpenAI 兼容分片中携带推理增量的字段：DeepSeek 为 reasoning_content，ollama 兼容层、OpenRouter 为 reasoning
+var reasoningFields = []string{"reasoning_content", "reasoning"}
+
+// ReasoningDelta 返回 OpenAI 兼容分片中的推理增量，没有时为空
+// ChatStreamOpenAI 已经把正文中的 <think> 块移到 reasoning_content，调用方不需要再处理
+func ReasoningDelta(delta openai.ChatCompletionChunkChoiceDelta) string {
+	for _, k := range reasoningFields {
+		// SDK 未声明的字段 Valid() 总是 false，直接读原始 JSON
+		f, ok := delta.JSON.ExtraFields[k]
+		if !ok {
+			continue
+		}
+		var s string
+		if json.Unmarshal([]byte(f.Raw()), &s) == nil && s != "" {
+			return s
+		}
+	}
+	return ""
+}
+
+// setReasoning 把推理增量写入分片的 reasoning_content
+func setReasoning(delta *openai.ChatCompletionChunkChoiceDelta, s string) {
+	b, _ := json.Marshal(s)
+	fields := make(map[string]respjson.Field, len(delta.JSON.ExtraFields)+1)
+	for k, v := range delta.JSON.ExtraFields {
+		if k != "reasoning" {
+			fields[k] = v
+		}
+	}
+	fields["reasoning_content"] = respjson.NewField(string(b))
+	delta.JSON.ExtraFields = fields
+}
+
+// thinkSplitter 从流式正文中分离 <think>...</think> 推理块（qwen3 等模型直接写在正文里），标签可能被拆在相邻的分片中
+type thinkSplitter struct {
+	inThink bool
+	trim    bool   // 推理块之后的正文去掉开头的空白
+	pending string // 可能是被拆开的标签，等下一个分片再判断
+}
+
+// feed 处理一个分片，返回其中的推理与正文
+func (s *thinkSplitter) feed(text string) (reasoning, content string) {
+	text = s.pending + text
+	s.pending = ""
+	var r, c strings.Builder
+	for text != "" {
+		tag := thinkOpen
+		if s.inThink {
+			tag = thinkClose
+		}
+		if i := strings.Index(text, tag); i >= 0 {
+			s.write(&r, &c, text[:i])
+			text = text[i+len(tag):]
+			s.inThink = !s.inThink
+			s.trim = !s.inThink
+			continue
+		}
+		n := partialSuffix(text, tag)
+		s.write(&r, &c, text[:len(text)-n])
+		s.pending = text[len(text)-n:]
+		break
+	}
+	return r.String(), c.String()
+}
+
+// flush 流结束时输出剩余的内容
+func (s *thinkSplitter) flush() (reasoning, content string) {
+	var r, c strings.Builder
+	s.write(&r, &c, s.pending)
+	s.pending = ""
+	return r.String(), c.String()
+}
+
+func (s *thinkSplitter) write(r, c *strings.Builder, text string) {
+	if s.inThink {
+		r.WriteString(text)
+		return
+	}
+	if s.trim {
+		text = strings.TrimLeftFunc(text, unicode.IsSpace)
+		if text == "" {
+			return
+		}
+		s.trim = false
+	}
+	c.WriteString(text)
+}
+
+// partialSuffix text 结尾与 tag 开头重合的长度
+func partialSuffix(text, tag string) int {
+	for n := min(len(text), len(tag)-1); n > 0; n-- {
+		if strings.HasSuffix(text, tag[:n]) {
+			return n
+		}
+	}
+	return 0
+}
+
+// splitThinking 把 ollama 分片正文中的 <think> 块移到 Message.Thinking
+func (s *thinkSplitter) splitThinking(chunk *ChatResponse) {
+	r, c := s.feed(chunk.Message.Content)
+	if chunk.Done {
+		fr, fc := s.flush()
+		r, c = r+fr, c+fc
+	}
+	chunk.Message.Thinking += r
+	chunk.Message.Content = c
+}
+
+// splitReasoning 把 OpenAI 兼容分片正文中的 <think> 块移到 reasoning_content
+func (s *thinkSplitter) splitReasoning(chunk *openai.ChatCompletionChunk) {
+	if len(chunk.Choices) == 0 {
+		return
+	}
+	delta := &chunk.Choices[0].Delta
+	r, c := s.feed(delta.Content)
+	if chunk.Choices[0].FinishReason != "" {
+		fr, fc := s.flush()
+		r, c = r+fr, c+fc
+	}
+	delta.Content = c
+	if r != "" {
+		setReasoning(delta, ReasoningDelta(*delta)+r)
+	}
+}
@@ -0,0 +1,67 @@
+package ai_provider
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/FantasyRL/go-mcp-demo/config"
+	"github.com/FantasyRL/go-mcp-demo/pkg/constant"
+	"github.com/openai/openai-go/v2"
+)
+
+func TestThinkSplitter(t *testing.T) {
+	// 标签被拆在不同分片中
+	var s thinkSplitter
+	var reasoning, content string
+	for _, chunk := range []string{"<thi", "nk>let me ", "think</th", "ink>\n\nHello", " <", "b>world"} {
+		r, c := s.feed(chunk)
+		reasoning += r
+		content += c
+	}
+	r, c := s.flush()
+	reasoning += r
+	content += c
+	if reasoning != "let me think" || content != "Hello <b>world" {
+		t.Fatalf("reasoning = %q, content = %q", reasoning, content)
+	}
+}
+
+func TestChatStreamOpenAIReasoning(t *testing.T) {
+	srv := stubServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, chunk := range []string{
+			// DeepSeek：reasoning_content 字段
+			`{"id":"1","object":"chat.completion.chunk","created":1,"model":"m","choices":[{"index":0,"delta":{"role":"assistant","content":null,"reasoning_content":"step 1; "}}]}`,
+			// qwen3：正文中的 <think> 块
+			`{"id":"1","object":"chat.completion.chunk","created":1,"model":"m","choices":[{"index":0,"delta":{"content":"<think>step 2</think>"}}]}`,
+			`{"id":"1","object":"chat.completion.chunk","created":1,"model":"m","choices":[{"index":0,"delta":{"content":"\nAnswer."},"finish_reason":"stop"}]}`,
+		} {
+			fmt.Fprintf(w, "data: %s\n\n", chunk)
+		}
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	})
+
+	cli := newTestClient(t, &config.AiProviderConfig{
+		Model: "ds/m",
+		Providers: map[string]config.AiProviderEndpoint{
+			"ds": {Type: constant.AiProviderTypeOpenAI, BaseURL: srv.URL, APIKey: "sk"},
+		},
+	})
+
+	var acc openai.ChatCompletionAccumulator
+	var reasoning string
+	if _, err := cli.ChatStreamOpenAI(context.Background(), openai.ChatCompletionNewParams{
+		Messages: []openai.ChatCompletionMessageParamUnion{openai.UserMessage("hi")},
+	}, func(chunk *openai.ChatCompletionChunk) error {
+		acc.AddChunk(*chunk)
+		reasoning += ReasoningDelta(chunk.Choices[0].Delta)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if reasoning != "step 1; step 2" || acc.Choices[0].Message.Content != "Answer." {
+		t.Fatalf("reasoning = %q, content = %q", reasoning, acc.Choices[0].Message.Content)
+	}
+}
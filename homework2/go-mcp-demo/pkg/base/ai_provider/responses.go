@@ -0,0 +1,170 @@
+package ai_provider
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/FantasyRL/go-mcp-demo/config"
+	"github.com/FantasyRL/go-mcp-demo/pkg/constant"
+	"github.com/FantasyRL/go-mcp-demo/pkg/errno"
+	"github.com/FantasyRL/go-mcp-demo/pkg/logger"
+	"github.com/openai/openai-go/v2"
+	"github.com/openai/openai-go/v2/responses"
+)
+
+// ChatStreamResponses 使用 OpenAI Responses API 流式对话，仅支持 api 为 responses 的服务商，返回实际提供服务的模型
+// req.Model 为模型引用；服务商配置的 builtin_tools 会追加到 req.Tools，未设置的生成参数使用服务商 options
+// 在输出第一个事件之前失败时会重试或切换到 fallback 中同样使用 Responses API 的模型，
+// 设置了 PreviousResponseID 时上下文保存在原服务商，只重试不切换
+func (c *Client) ChatStreamResponses(
+	ctx context.Context,
+	req responses.ResponseNewParams,
+	onEvent func(*responses.ResponseStreamEventUnion) error,
+) (ModelInfo, error) {
+	cfg := config.AiProvider()
+	list, err := c.candidates(cfg, req.Model, constant.AiProviderAPIResponses)
+	if err != nil {
+		return ModelInfo{}, err
+	}
+	if req.PreviousResponseID.Valid() {
+		list = list[:1]
+	}
+	started := false
+	return invoke(ctx, cfg, list, func() bool { return started }, func(cand candidate) error {
+		r := req
+		r.Model = cand.model
+		applyResponsesOptions(&r, cand.opts)
+		r.Tools = append(append([]responses.ToolUnionParam(nil), req.Tools...), cand.p.responsesBuiltinTools()...)
+		return cand.p.chatStreamResponses(ctx, r, func(ev *responses.ResponseStreamEventUnion) error {
+			// created/in_progress 只是状态通知，之后失败仍然可以切换
+			if ev.Type != "response.created" && ev.Type != "response.in_progress" {
+				started = true
+			}
+			return onEvent(ev)
+		})
+	})
+}
+
+func (p *provider) chatStreamResponses(
+	ctx context.Context,
+	req responses.ResponseNewParams,
+	onEvent func(*responses.ResponseStreamEventUnion) error,
+) error {
+	stream := p.openaiClient.Responses.NewStreaming(ctx, req)
+	defer stream.Close()
+	for stream.Next() {
+		ev := stream.Current()
+		switch ev.Type {
+		case "error":
+			return responsesStreamError(ev.Code, ev.Message)
+		case "response.failed":
+			return responsesStreamError(string(ev.Response.Error.Code), ev.Response.Error.Message)
+		}
+		if err := onEvent(&ev); err != nil {
+			if errors.Is(err, errno.OllamaInternalStopStream) {
+				return nil
+			}
+			return err
+		}
+	}
+	if err := stream.Err(); err != nil {
+		logger.Errorf("openai.ChatStreamResponses stream error: %v", err)
+		return err
+	}
+	return nil
+}
+
+// responsesStreamError 流中的 error / response.failed 事件，按错误码映射为 HTTP 状态以便判断是否重试
+func responsesStreamError(code, message string) error {
+	status := http.StatusBadRequest
+	switch code {
+	case "rate_limit_exceeded":
+		status = http.StatusTooManyRequests
+	case "server_error", "vector_store_timeout":
+		status = http.StatusInternalServerError
+	}
+	return &statusError{op: "responses stream", code: status, status: code, body: message}
+}
+
+func (p *provider) responsesBuiltinTools() []responses.ToolUnionParam {
+	tools := make([]responses.ToolUnionParam, 0, len(p.builtinTools))
+	for _, t := range p.builtinTools {
+		switch t {
+		case constant.AiProviderBuiltinToolWebSearch:
+			tools = append(tools, responses.ToolParamOfWebSearch(responses.WebSearchToolTypeWebSearch))
+		case constant.AiProviderBuiltinToolCodeInterpreter:
+			tools = append(tools, responses.ToolParamOfCodeInterpreter(responses.ToolCodeInterpreterContainerCodeInterpreterContainerAutoParam{}))
+		}
+	}
+	return tools
+}
+
+// ResponsesRequest 将 OpenAI Chat Completions 请求转换为 Responses API 请求：
+// system/developer 消息合并为 instructions，assistant 的 tool_calls 转为 function_call 条目，
+// tool 消息转为 function_call_output 条目，用户消息中的图片转为 input_image，response_format 转为 text.format，函数工具按原样转换
+func ResponsesRequest(req openai.ChatCompletionNewParams) (responses.ResponseNewParams, error) {
+	params, err := parseChatParams(req)
+	if err != nil {
+		return responses.ResponseNewParams{}, err
+	}
+	out := responses.ResponseNewParams{Model: params.Model}
+	if mt := params.maxTokens(); mt > 0 {
+		out.MaxOutputTokens = openai.Int(mt)
+	}
+	if params.Temperature != nil {
+		out.Temperature = openai.Float(*params.Temperature)
+	}
+	if params.TopP != nil {
+		out.TopP = openai.Float(*params.TopP)
+	}
+
+	var system []string
+	var input responses.ResponseInputParam
+	for _, m := range params.Messages {
+		switch m.Role {
+		case "system", "developer":
+			if s := m.text(); s != "" {
+				system = append(system, s)
+			}
+		case "tool":
+			input = append(input, responses.ResponseInputItemParamOfFunctionCallOutput(m.ToolCallID, m.text()))
+		case "assistant":
+			if s := m.text(); s != "" {
+				input = append(input, responses.ResponseInputItemParamOfMessage(s, responses.EasyInputMessageRoleAssistant))
+			}
+			for _, tc := range m.ToolCalls {
+				input = append(input, responses.ResponseInputItemParamOfFunctionCall(tc.Function.Arguments, tc.ID, tc.Function.Name))
+			}
+		default:
+			var content responses.ResponseInputMessageContentListParam
+			for _, part := range m.parts() {
+				switch part.Type {
+				case "text":
+					content = append(content, responses.ResponseInputContentParamOfInputText(part.Text))
+				case "image_url":
+					img := responses.ResponseInputImageParam{Detail: responses.ResponseInputImageDetailAuto, ImageURL: openai.String(part.ImageURL.URL)}
+					content = append(content, responses.ResponseInputContentUnionParam{OfInputImage: &img})
+				}
+			}
+			input = append(input, responses.ResponseInputItemParamOfMessage(content, responses.EasyInputMessageRoleUser))
+		}
+	}
+	if schema := params.jsonSchema(); schema != nil {
+		out.Text.Format = responses.ResponseFormatTextConfigParamOfJSONSchema(params.ResponseFormat.JSONSchema.Name, schema)
+	}
+	if len(system) > 0 {
+		out.Instructions = openai.String(strings.Join(system, "\n\n"))
+	}
+	out.Input = responses.ResponseNewParamsInputUnion{OfInputItemList: input}
+
+	for _, t := range params.Tools {
+		tool := responses.ToolParamOfFunction(t.Function.Name, objectSchema(t.Function.Parameters), false)
+		if t.Function.Description != "" {
+			tool.OfFunction.Description = openai.String(t.Function.Description)
+		}
+		out.Tools = append(out.Tools, tool)
+	}
+	return out, nil
+}
@@ -0,0 +1,126 @@
+package ai_provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/FantasyRL/go-mcp-demo/config"
+	"github.com/FantasyRL/go-mcp-demo/pkg/constant"
+	"github.com/openai/openai-go/v2"
+	"github.com/openai/openai-go/v2/responses"
+)
+
+func TestChatStreamResponses(t *testing.T) {
+	var got map[string]any
+	srv := stubServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/responses" {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, ev := range []string{
+			`{"type":"response.created","sequence_number":0,"response":{"id":"resp_1","status":"in_progress"}}`,
+			`{"type":"response.output_text.delta","sequence_number":1,"item_id":"msg_1","delta":"Checking."}`,
+			`{"type":"response.output_item.done","sequence_number":2,"item":{"type":"function_call","id":"fc_1","call_id":"call_1","name":"weather","arguments":"{\"city\":\"Paris\"}"}}`,
+			`{"type":"response.completed","sequence_number":3,"response":{"id":"resp_1","status":"completed","usage":{"input_tokens":10,"output_tokens":5,"total_tokens":15}}}`,
+		} {
+			fmt.Fprintf(w, "data: %s\n\n", ev)
+		}
+	})
+
+	cli := newTestClient(t, &config.AiProviderConfig{
+		Model: "openai/gpt-test",
+		Providers: map[string]config.AiProviderEndpoint{
+			"openai": {Type: constant.AiProviderTypeOpenAI, BaseURL: srv.URL, APIKey: "sk", API: constant.AiProviderAPIResponses, BuiltinTools: []string{"web_search"}},
+			"chat":   {Type: constant.AiProviderTypeOpenAI, BaseURL: srv.URL, APIKey: "sk"},
+		},
+	})
+	if !cli.UsesResponsesAPI("") || cli.UsesResponsesAPI("chat/gpt-test") {
+		t.Fatal("UsesResponsesAPI")
+	}
+	if _, err := cli.ChatStreamResponses(context.Background(), responses.ResponseNewParams{Model: "chat/gpt-test"}, nil); err == nil {
+		t.Fatal("chat_completions provider should be rejected")
+	}
+
+	req, err := ResponsesRequest(openai.ChatCompletionNewParams{
+		Messages: []openai.ChatCompletionMessageParamUnion{
+			openai.SystemMessage("be brief"),
+			openai.UserMessage("weather in Rome?"),
+			{OfAssistant: &openai.ChatCompletionAssistantMessageParam{
+				ToolCalls: []openai.ChatCompletionMessageToolCallUnionParam{{
+					OfFunction: &openai.ChatCompletionMessageFunctionToolCallParam{
+						ID:       "call_0",
+						Function: openai.ChatCompletionMessageFunctionToolCallFunctionParam{Name: "weather", Arguments: `{"city":"Rome"}`},
+					},
+				}},
+			}},
+			openai.ToolMessage("sunny", "call_0"),
+		},
+		Tools: []openai.ChatCompletionToolUnionParam{{
+			OfFunction: &openai.ChatCompletionFunctionToolParam{
+				Function: openai.FunctionDefinitionParam{Name: "weather", Description: openai.String("get weather")},
+			},
+		}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var text string
+	var calls []responses.ResponseOutputItemUnion
+	var completed responses.Response
+	served, err := cli.ChatStreamResponses(context.Background(), req, func(ev *responses.ResponseStreamEventUnion) error {
+		switch ev.Type {
+		case "response.output_text.delta":
+			text += ev.Delta
+		case "response.output_item.done":
+			calls = append(calls, ev.Item)
+		case "response.completed":
+			completed = ev.Response
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if served.Provider != "openai" || served.Model != "gpt-test" {
+		t.Fatalf("served = %+v", served)
+	}
+
+	// 请求：system 转为 instructions，工具调用与结果转为 function_call / function_call_output，追加内置工具
+	if got["model"] != "gpt-test" || got["instructions"] != "be brief" || got["stream"] != true {
+		t.Fatalf("request = %v", got)
+	}
+	input, _ := got["input"].([]any)
+	if len(input) != 3 {
+		t.Fatalf("input = %v", got["input"])
+	}
+	if fc := input[1].(map[string]any); fc["type"] != "function_call" || fc["call_id"] != "call_0" || fc["arguments"] != `{"city":"Rome"}` {
+		t.Fatalf("function_call = %v", fc)
+	}
+	if out := input[2].(map[string]any); out["type"] != "function_call_output" || out["call_id"] != "call_0" || out["output"] != "sunny" {
+		t.Fatalf("function_call_output = %v", out)
+	}
+	tools, _ := got["tools"].([]any)
+	if len(tools) != 2 || tools[0].(map[string]any)["type"] != "function" || tools[1].(map[string]any)["type"] != "web_search" {
+		t.Fatalf("tools = %v", got["tools"])
+	}
+	if params := tools[0].(map[string]any)["parameters"].(map[string]any); params["type"] != "object" {
+		t.Fatalf("parameters = %v", params)
+	}
+
+	// 响应
+	if text != "Checking." || len(calls) != 1 || calls[0].CallID != "call_1" || calls[0].Name != "weather" {
+		t.Fatalf("text = %q, calls = %+v", text, calls)
+	}
+	if completed.ID != "resp_1" || completed.Usage.InputTokens != 10 || completed.Usage.OutputTokens != 5 {
+		t.Fatalf("completed = %+v", completed)
+	}
+}
@@ -0,0 +1,27 @@
+package ai_provider
+
+import (
+	"encoding/json"
+
+	"github.com/openai/openai-go/v2"
+	"github.com/openai/openai-go/v2/shared"
+)
+
+// JSONSchemaFormat 要求模型按 schema 输出 JSON 的 response_format
+// 不开启 strict：strict 只支持 JSON Schema 的子集，是否符合由调用方自行校验
+func JSONSchemaFormat(schema map[string]any) openai.ChatCompletionNewParamsResponseFormatUnion {
+	return openai.ChatCompletionNewParamsResponseFormatUnion{
+		OfJSONSchema: &shared.ResponseFormatJSONSchemaParam{
+			JSONSchema: shared.ResponseFormatJSONSchemaJSONSchemaParam{
+				Name:   "response",
+				Schema: schema,
+			},
+		},
+	}
+}
+
+// jsonSchemaInstruction 没有结构化输出参数的服务商（anthropic）在 system 中说明输出格式
+func jsonSchemaInstruction(schema map[string]any) string {
+	b, _ := json.Marshal(schema)
+	return "Reply with a single JSON value that conforms to the following JSON Schema, without any other text or code fences:\n" + string(b)
+}
@@ -0,0 +1,60 @@
+package guard
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/FantasyRL/go-mcp-demo/pkg/errno"
+	sentinel "github.com/alibaba/sentinel-golang/api"
+	"github.com/alibaba/sentinel-golang/core/base"
+)
+
+// ErrBlocked 调用被 Sentinel 限流或熔断
+var ErrBlocked = errno.NewErrNo(errno.BizLimitCode, "服务繁忙或暂时不可用，请稍后再试")
+
+// Do 依次进入 resources 对应的出站 Sentinel 资源后执行 fn，任一资源被拦截时不执行 fn 并返回 ErrBlocked
+// fn 返回的错误（ctx 取消除外）计入熔断统计
+func Do(fn func() error, resources ...string) error {
+	entries := make([]*base.SentinelEntry, 0, len(resources))
+	defer func() {
+		// 后进入的先退出
+		for i := len(entries) - 1; i >= 0; i-- {
+			entries[i].Exit()
+		}
+	}()
+	for _, res := range resources {
+		e, blockErr := sentinel.Entry(res,
+			sentinel.WithResourceType(base.ResTypeRPC),
+			sentinel.WithTrafficType(base.Outbound))
+		if blockErr != nil {
+			return ErrBlocked.WithMessage(fmt.Sprintf("%s 已被%s，请稍后再试", res, blockTypeName(blockErr.BlockType())))
+		}
+		entries = append(entries, e)
+	}
+
+	err := fn()
+	if err != nil && !errors.Is(err, context.Canceled) {
+		for _, e := range entries {
+			sentinel.TraceError(e, err)
+		}
+	}
+	return err
+}
+
+// IsBlocked err 是否为 Sentinel 拦截（限流、熔断、并发限制）
+func IsBlocked(err error) bool {
+	var e errno.ErrNo
+	return errors.As(err, &e) && e.ErrorCode == ErrBlocked.ErrorCode
+}
+
+func blockTypeName(t base.BlockType) string {
+	switch t {
+	case base.BlockTypeCircuitBreaking:
+		return "熔断"
+	case base.BlockTypeIsolation:
+		return "限制并发"
+	default:
+		return "限流"
+	}
+}
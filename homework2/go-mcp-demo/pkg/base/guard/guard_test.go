@@ -0,0 +1,94 @@
+package guard
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/FantasyRL/go-mcp-demo/config"
+	"github.com/FantasyRL/go-mcp-demo/pkg/constant"
+	"github.com/FantasyRL/go-mcp-demo/pkg/errno"
+	"github.com/alibaba/sentinel-golang/core/flow"
+)
+
+func TestBuildRules(t *testing.T) {
+	config.SetSentinel(nil)
+	flows, isolations, breakers, err := buildRules()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(flows) != 1 || flows[0].Resource != constant.SentinelResourceAPI || len(isolations) != 0 || len(breakers) != 0 {
+		t.Fatalf("default rules: %v %v %v", flows, isolations, breakers)
+	}
+
+	cfg := &config.Config{}
+	cfg.Sentinel.Flow = []config.SentinelFlowRule{{Resource: "POST:/api/v1/chat", Threshold: 5, ControlBehavior: "throttling", MaxQueueingTime: time.Second}}
+	cfg.Sentinel.Isolation = []config.SentinelIsolationRule{{Resource: "GET:/api/v1/chat/sse", MaxConcurrency: 3}}
+	cfg.Sentinel.CircuitBreaker = []config.SentinelCircuitBreakerRule{{Resource: constant.SentinelResourceAIProvider, Strategy: "slow_request_ratio", Threshold: 0.5, MaxAllowedRT: 2 * time.Second}}
+	config.SetSentinel(&cfg.Sentinel)
+	defer func() { config.SetSentinel(nil) }()
+
+	flows, isolations, breakers, err = buildRules()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(flows) != 1 || flows[0].ControlBehavior != flow.Throttling || flows[0].MaxQueueingTimeMs != 1000 {
+		t.Fatalf("flow rules: %v", flows)
+	}
+	if len(isolations) != 1 || isolations[0].Threshold != 3 {
+		t.Fatalf("isolation rules: %v", isolations)
+	}
+	if len(breakers) != 1 || breakers[0].MaxAllowedRtMs != 2000 || breakers[0].RetryTimeoutMs != 30000 {
+		t.Fatalf("circuit breaker rules: %v", breakers)
+	}
+
+	cfg.Sentinel.CircuitBreaker[0].Strategy = "unknown"
+	if _, _, _, err := buildRules(); err == nil {
+		t.Fatal("expected error for unknown strategy")
+	}
+}
+
+func TestDoCircuitBreaking(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Sentinel.CircuitBreaker = []config.SentinelCircuitBreakerRule{{
+		Resource:         "test:breaker",
+		Strategy:         "error_count",
+		Threshold:        2,
+		MinRequestAmount: 1,
+		StatInterval:     time.Minute,
+		RetryTimeout:     time.Minute,
+	}}
+	config.SetSentinel(&cfg.Sentinel)
+	defer func() {
+		config.SetSentinel(nil)
+		_ = LoadRules()
+	}()
+	if err := LoadRules(); err != nil {
+		t.Fatal(err)
+	}
+
+	failure := errors.New("upstream failed")
+	calls := 0
+	fn := func() error {
+		calls++
+		return failure
+	}
+	for i := 0; i < 2; i++ {
+		if err := Do(fn, "test:breaker"); !errors.Is(err, failure) {
+			t.Fatalf("call %d: %v", i, err)
+		}
+	}
+	// 连续两次失败后熔断，不再执行 fn
+	err := Do(fn, "test:breaker")
+	var e errno.ErrNo
+	if !errors.As(err, &e) || e.ErrorCode != ErrBlocked.ErrorCode {
+		t.Fatalf("expected blocked, got %v", err)
+	}
+	if calls != 2 {
+		t.Fatalf("fn called %d times", calls)
+	}
+	// 其他资源不受影响
+	if err := Do(func() error { return nil }, "test:other"); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,154 @@
+package guard
+
+import (
+	"fmt"
+
+	"github.com/FantasyRL/go-mcp-demo/config"
+	"github.com/FantasyRL/go-mcp-demo/pkg/constant"
+	"github.com/FantasyRL/go-mcp-demo/pkg/logger"
+	"github.com/alibaba/sentinel-golang/core/circuitbreaker"
+	"github.com/alibaba/sentinel-golang/core/flow"
+	"github.com/alibaba/sentinel-golang/core/isolation"
+)
+
+// LoadRules 按 sentinel 配置加载流控、并发隔离与熔断规则，会整体替换之前加载的规则
+func LoadRules() error {
+	flowRules, isolationRules, breakerRules, err := buildRules()
+	if err != nil {
+		return err
+	}
+	if _, err := flow.LoadRules(flowRules); err != nil {
+		return fmt.Errorf("sentinel: load flow rules: %w", err)
+	}
+	if _, err := isolation.LoadRules(isolationRules); err != nil {
+		return fmt.Errorf("sentinel: load isolation rules: %w", err)
+	}
+	if _, err := circuitbreaker.LoadRules(breakerRules); err != nil {
+		return fmt.Errorf("sentinel: load circuit breaker rules: %w", err)
+	}
+	logger.Infof("sentinel: loaded %d flow, %d isolation, %d circuit breaker rules",
+		len(flowRules), len(isolationRules), len(breakerRules))
+	return nil
+}
+
+// WatchRules 配置文件变化时重新加载规则；新规则有误时保留旧规则
+func WatchRules() {
+	config.Subscribe(func() {
+		if err := LoadRules(); err != nil {
+			logger.Errorf("sentinel: reload rules failed, keep previous rules: %v", err)
+		}
+	})
+}
+
+func buildRules() ([]*flow.Rule, []*isolation.Rule, []*circuitbreaker.Rule, error) {
+	cfg := config.Sentinel()
+	if cfg == nil {
+		return defaultFlowRules(), nil, nil, nil
+	}
+	flowRules, err := buildFlowRules(cfg.Flow)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	isolationRules, err := buildIsolationRules(cfg.Isolation)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	breakerRules, err := buildCircuitBreakerRules(cfg.CircuitBreaker)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return flowRules, isolationRules, breakerRules, nil
+}
+
+// defaultFlowRules 未配置 flow 规则时限制整体 QPS 为 100
+func defaultFlowRules() []*flow.Rule {
+	return []*flow.Rule{{
+		Resource:               constant.SentinelResourceAPI,
+		Threshold:              constant.SentinelDefaultThreshold,
+		TokenCalculateStrategy: flow.Direct,
+		ControlBehavior:        flow.Reject,
+		StatIntervalInMs:       1000,
+	}}
+}
+
+func buildFlowRules(cfg []config.SentinelFlowRule) ([]*flow.Rule, error) {
+	if len(cfg) == 0 {
+		return defaultFlowRules(), nil
+	}
+	rules := make([]*flow.Rule, 0, len(cfg))
+	for i, r := range cfg {
+		if r.Resource == "" {
+			return nil, fmt.Errorf("sentinel.flow[%d]: resource is required", i)
+		}
+		rule := &flow.Rule{
+			Resource:               r.Resource,
+			Threshold:              r.Threshold,
+			TokenCalculateStrategy: flow.Direct,
+			ControlBehavior:        flow.Reject,
+			StatIntervalInMs:       1000,
+		}
+		if r.StatInterval > 0 {
+			rule.StatIntervalInMs = uint32(r.StatInterval.Milliseconds())
+		}
+		switch r.ControlBehavior {
+		case "", "reject":
+		case "throttling":
+			rule.ControlBehavior = flow.Throttling
+			rule.MaxQueueingTimeMs = uint32(r.MaxQueueingTime.Milliseconds())
+		default:
+			return nil, fmt.Errorf("sentinel.flow[%d]: unknown control_behavior %q", i, r.ControlBehavior)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+func buildIsolationRules(cfg []config.SentinelIsolationRule) ([]*isolation.Rule, error) {
+	rules := make([]*isolation.Rule, 0, len(cfg))
+	for i, r := range cfg {
+		if r.Resource == "" || r.MaxConcurrency == 0 {
+			return nil, fmt.Errorf("sentinel.isolation[%d]: resource and max_concurrency are required", i)
+		}
+		rules = append(rules, &isolation.Rule{
+			Resource:   r.Resource,
+			MetricType: isolation.Concurrency,
+			Threshold:  r.MaxConcurrency,
+		})
+	}
+	return rules, nil
+}
+
+func buildCircuitBreakerRules(cfg []config.SentinelCircuitBreakerRule) ([]*circuitbreaker.Rule, error) {
+	rules := make([]*circuitbreaker.Rule, 0, len(cfg))
+	for i, r := range cfg {
+		if r.Resource == "" {
+			return nil, fmt.Errorf("sentinel.circuit_breaker[%d]: resource is required", i)
+		}
+		rule := &circuitbreaker.Rule{
+			Resource:         r.Resource,
+			Strategy:         circuitbreaker.ErrorRatio,
+			Threshold:        r.Threshold,
+			MinRequestAmount: r.MinRequestAmount,
+			StatIntervalMs:   uint32(constant.SentinelBreakerDefaultStatInterval.Milliseconds()),
+			RetryTimeoutMs:   uint32(constant.SentinelBreakerDefaultRetryTimeout.Milliseconds()),
+		}
+		if r.StatInterval > 0 {
+			rule.StatIntervalMs = uint32(r.StatInterval.Milliseconds())
+		}
+		if r.RetryTimeout > 0 {
+			rule.RetryTimeoutMs = uint32(r.RetryTimeout.Milliseconds())
+		}
+		switch r.Strategy {
+		case "", "error_ratio":
+		case "error_count":
+			rule.Strategy = circuitbreaker.ErrorCount
+		case "slow_request_ratio":
+			rule.Strategy = circuitbreaker.SlowRequestRatio
+			rule.MaxAllowedRtMs = uint64(r.MaxAllowedRT.Milliseconds())
+		default:
+			return nil, fmt.Errorf("sentinel.circuit_breaker[%d]: unknown strategy %q", i, r.Strategy)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
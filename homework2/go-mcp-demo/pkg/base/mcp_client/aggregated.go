@@ -6,8 +6,11 @@ import (
 	"fmt"
 	"github.com/FantasyRL/go-mcp-demo/config"
 	"github.com/FantasyRL/go-mcp-demo/pkg/constant"
+	"slices"
 	"sort"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/FantasyRL/go-mcp-demo/pkg/base/registry"
@@ -24,14 +27,39 @@ type AggregatedClient struct {
 
 	mu               sync.RWMutex
 	discoverServices []string
+	known            map[string]string     // 注册中心当前给出的实例 url -> 服务名（期望状态）
 	clients          map[string]*MCPClient // url -> client
-	toolIndex        map[string]string     // toolName -> url
-	toolSnapshot     map[string]mcp.Tool   // 聚合后的 tool 定义
+	urlService       map[string]string     // url -> 服务名
+	toolIndex        map[string]toolRoute  // 暴露给模型的工具名 -> 路由信息
+	toolSnapshot     map[string]mcp.Tool   // 聚合后的 tool 定义（Name 已替换为暴露名）
+	states           sync.Map              // url -> *instanceState 并发数/熔断状态
+
+	balancer    Balancer
+	reconcileMu sync.Mutex    // 串行化 reconcile，避免重复建连
+	reconcileCh chan struct{} // watch 事件触发的后台 reconcile，容量为 1，合并连续的事件
+
+	collisions     atomic.Int64            // 工具名冲突计数
+	collisionsSeen map[string]struct{}     // 已告警过的冲突，避免每次刷新重复告警
+	assigned       map[string]assignedName // "服务名__工具名" -> auto 模式下已分配的暴露名，保证暴露名不随实例上下线变化
 
 	stopCh   chan struct{}
 	stopOnce sync.Once
 }
 
+// assignedName 已分配的暴露名及其服务最后一次在线的时间，下线超过 constant.MCPToolNameRetention 后释放
+type assignedName struct {
+	name     string
+	lastSeen time.Time
+}
+
+// toolRoute 暴露名到实际 MCP 工具的映射
+type toolRoute struct {
+	Service    string   // 提供该工具的服务名
+	Name       string   // MCP Server 上的原始工具名
+	URLs       []string // 提供该工具的实例
+	Idempotent bool     // 幂等工具失败后可以换实例重试
+}
+
 func NewAggregatedClient(resolver registry.Resolver, services []string) *AggregatedClient {
 	if config.Registry.RefreshInterval <= constant.RegistryResolverDefaultRefreshInterval {
 		config.Registry.RefreshInterval = constant.RegistryResolverDefaultRefreshInterval
@@ -40,13 +68,23 @@ func NewAggregatedClient(resolver registry.Resolver, services []string) *Aggrega
 		resolver:         resolver,
 		discoverServices: services,
 		refreshInterval:  config.Registry.RefreshInterval,
+		known:            make(map[string]string),
 		clients:          make(map[string]*MCPClient),
-		toolIndex:        make(map[string]string),
+		urlService:       make(map[string]string),
+		toolIndex:        make(map[string]toolRoute),
 		toolSnapshot:     make(map[string]mcp.Tool),
+		collisionsSeen:   make(map[string]struct{}),
+		assigned:         make(map[string]assignedName),
+		reconcileCh:      make(chan struct{}, 1),
 		stopCh:           make(chan struct{}),
 	}
-	// 启动定时刷新goroutine
-	go ac.updateAggregatedClient()
+	ac.balancer = NewBalancer(config.Registry.LoadBalance.Strategy, ac.inflight)
+	// 注册中心支持推送时使用 watch，否则定时轮询
+	if w, ok := resolver.(registry.Watcher); ok && config.Registry.Watch {
+		go ac.watchAggregatedClient(w)
+	} else {
+		go ac.updateAggregatedClient()
+	}
 	return ac
 }
 
@@ -64,6 +102,53 @@ func (a *AggregatedClient) updateAggregatedClient() {
 	}
 }
 
+// watchAggregatedClient 订阅注册中心的增量事件，实例上下线时通知后台立即建连/断连
+// 事件回调只更新期望状态，建连在 reconcileLoop 中异步进行，慢实例不会阻塞 watch
+func (a *AggregatedClient) watchAggregatedClient(w registry.Watcher) {
+	ctx, cancel := context.WithCancel(context.Background())
+	go a.reconcileLoop(cancel)
+
+	err := w.Watch(ctx, a.discoverServices, func(ev registry.Event) {
+		a.mu.Lock()
+		switch ev.Type {
+		case registry.EventAdd:
+			a.known[ev.URL] = ev.Service
+		case registry.EventRemove:
+			delete(a.known, ev.URL)
+		}
+		a.mu.Unlock()
+		a.triggerReconcile()
+	})
+	if err != nil && ctx.Err() == nil {
+		logger.Errorf("registry watch stopped: %v", err)
+	}
+}
+
+// reconcileLoop 收到 watch 事件时执行 reconcile，同时定时 reconcile 以重试之前建连失败的实例
+func (a *AggregatedClient) reconcileLoop(cancel context.CancelFunc) {
+	ticker := time.NewTicker(a.refreshInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-a.reconcileCh:
+			a.reconcile()
+		case <-ticker.C:
+			a.reconcile()
+		case <-a.stopCh:
+			cancel()
+			return
+		}
+	}
+}
+
+// triggerReconcile 通知后台 reconcile，已有待处理的通知时直接返回
+func (a *AggregatedClient) triggerReconcile() {
+	select {
+	case a.reconcileCh <- struct{}{}:
+	default:
+	}
+}
+
 // refresh 刷新registryCli与注册中心的连接，来更新可用的MCP服务实例列表
 func (a *AggregatedClient) refresh() {
 	if a.resolver == nil {
@@ -76,75 +161,305 @@ func (a *AggregatedClient) refresh() {
 		logger.Errorf("registry resolve: %v", err)
 		return
 	}
-	// 转化为set
-	target := make(map[string]struct{})
-	for _, urls := range serviceToUrls {
+	// 转化为 url -> 服务名
+	target := make(map[string]string)
+	for svc, urls := range serviceToUrls {
 		for _, url := range urls {
-			target[url] = struct{}{}
+			target[url] = svc
 		}
 	}
 
 	a.mu.Lock()
-	defer a.mu.Unlock()
+	a.known = target
+	a.mu.Unlock()
+	a.reconcile()
+}
 
-	// 删除已关闭的连接
-	for u, cli := range a.clients {
-		if _, ok := target[u]; ok {
-			continue
+// reconcile 让已建立的连接与 known 保持一致：关闭已下线实例，连接新实例，然后重建索引
+// 建连在锁外并发进行，避免慢实例阻塞 CallTool 和其他实例
+func (a *AggregatedClient) reconcile() {
+	a.reconcileMu.Lock()
+	defer a.reconcileMu.Unlock()
+
+	a.mu.RLock()
+	toAdd := make(map[string]string)
+	for u, svc := range a.known {
+		if _, ok := a.clients[u]; !ok {
+			toAdd[u] = svc
 		}
-		cli.Close()
+	}
+	var toRemove []string
+	for u := range a.clients {
+		if _, ok := a.known[u]; !ok {
+			toRemove = append(toRemove, u)
+		}
+	}
+	a.mu.RUnlock()
+	if len(toAdd) == 0 && len(toRemove) == 0 {
+		return
+	}
+
+	// 新增连接
+	var (
+		wg       sync.WaitGroup
+		dialedMu sync.Mutex
+		dialed   = make(map[string]*MCPClient, len(toAdd))
+	)
+	for u := range toAdd {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			cli, err := NewMCPClient(u) // u 为 Resolver 返回的完整 URL
+			if err != nil {
+				logger.Errorf("mcp dial %s: %v", u, err)
+				return
+			}
+			dialedMu.Lock()
+			dialed[u] = cli
+			dialedMu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	a.mu.Lock()
+	// 删除已关闭的连接
+	closed := make([]*MCPClient, 0, len(toRemove))
+	for _, u := range toRemove {
+		closed = append(closed, a.clients[u])
 		delete(a.clients, u)
+		delete(a.urlService, u)
+		a.states.Delete(u)
 		logger.Infof("mcp disconnected: %s", u)
 	}
-	// 新增连接
-	for u := range target {
-		if _, ok := a.clients[u]; ok {
-			continue
-		}
-		cli, err := NewMCPClient("http://" + u + "/mcp")
-		if err != nil {
-			logger.Errorf("mcp dial %s: %v", u, err)
+	for u, cli := range dialed {
+		// 建连期间实例已经下线
+		if _, ok := a.known[u]; !ok {
+			closed = append(closed, cli)
 			continue
 		}
 		a.clients[u] = cli
+		a.urlService[u] = toAdd[u]
 		logger.Infof("mcp connected: %s (tools=%d)", u, len(cli.Tools))
 	}
 	a.rebuildIndex()
+	a.mu.Unlock()
+
+	for _, cli := range closed {
+		cli.Close()
+	}
 }
 
 // rebuildIndex 重建MCPClient映射
+// 同一服务的多个实例视为副本；不同服务暴露同名工具视为冲突，按 config.Registry.ToolNamespace 处理
+// auto 模式下暴露名一旦分配就保持不变：已有工具保留原名，后加入的冲突服务使用 "服务名__工具名"，
+// 冲突服务下线后剩余服务也不会改回原名，避免模型在会话中途看到工具改名；服务下线超过 constant.MCPToolNameRetention 后释放其暴露名
 func (a *AggregatedClient) rebuildIndex() {
-	// tool -> 候选 url 列表
-	candidates := map[string][]string{}
-	toolDef := map[string]mcp.Tool{}
+	ns := config.Registry.ToolNamespace
+	mode := namespaceMode(ns.Mode)
+	sep := ns.Separator
+	if sep == "" {
+		sep = constant.MCPToolNamespaceSeparator
+	}
+
+	// tool -> service -> 候选实例
+	type instance struct {
+		url  string
+		tool mcp.Tool
+	}
+	candidates := map[string]map[string][]instance{}
 	for url, cli := range a.clients {
+		svc := a.urlService[url]
 		for _, t := range cli.Tools {
-			candidates[t.Name] = append(candidates[t.Name], url)
-			// 记录一个定义（相同名称一般结构一致）
-			if _, ok := toolDef[t.Name]; !ok {
-				toolDef[t.Name] = t
+			if candidates[t.Name] == nil {
+				candidates[t.Name] = map[string][]instance{}
+			}
+			candidates[t.Name][svc] = append(candidates[t.Name][svc], instance{url: url, tool: t})
+		}
+	}
+
+	// entry 一个服务提供的一个工具
+	type entry struct {
+		svc, name string
+		collided  bool
+		insts     []instance
+	}
+	var entries []entry
+	for name, byService := range candidates {
+		services := make([]string, 0, len(byService))
+		for svc, insts := range byService {
+			services = append(services, svc)
+			sort.Slice(insts, func(i, j int) bool { return insts[i].url < insts[j].url })
+			// 同一服务的副本之间 schema 应当一致，不一致说明发布了不同版本
+			for _, in := range insts[1:] {
+				if !sameSchema(insts[0].tool, in.tool) {
+					a.warnCollision(fmt.Sprintf("schema:%s:%s:%s", svc, name, in.url),
+						"mcp tool %q of service %q has mismatched schema between %s and %s", name, svc, insts[0].url, in.url)
+				}
+			}
+		}
+		sort.Strings(services)
+
+		collided := len(services) > 1
+		if collided {
+			for _, svc := range services[1:] {
+				if !sameSchema(byService[services[0]][0].tool, byService[svc][0].tool) {
+					a.warnCollision(fmt.Sprintf("schema:%s:%s:%s", name, services[0], svc),
+						"mcp tool %q has different schemas in services %q and %q", name, services[0], svc)
+				}
+			}
+			a.warnCollision(fmt.Sprintf("name:%s:%v", name, services),
+				"mcp tool %q is provided by multiple services %v (namespace mode %q)", name, services, mode)
+		}
+		for i, svc := range services {
+			// 不加前缀时只保留排序后的第一个服务
+			if mode == constant.MCPToolNamespaceModeNever && i > 0 {
+				continue
+			}
+			entries = append(entries, entry{svc: svc, name: name, collided: collided, insts: byService[svc]})
+		}
+	}
+	// 仍在线的工具刷新分配时间，下线太久的释放暴露名，避免服务反复变更时 assigned 无限增长
+	now := time.Now()
+	for _, e := range entries {
+		key := e.svc + sep + e.name
+		if as, ok := a.assigned[key]; ok {
+			as.lastSeen = now
+			a.assigned[key] = as
+		}
+	}
+	for key, as := range a.assigned {
+		if now.Sub(as.lastSeen) > constant.MCPToolNameRetention {
+			delete(a.assigned, key)
+		}
+	}
+
+	// 已分配过暴露名的工具优先占用名字，其余按名称排序，保证结果与 map 遍历顺序无关
+	sort.Slice(entries, func(i, j int) bool {
+		_, oi := a.assigned[entries[i].svc+sep+entries[i].name]
+		_, oj := a.assigned[entries[j].svc+sep+entries[j].name]
+		if oi != oj {
+			return oi
+		}
+		if entries[i].name != entries[j].name {
+			return entries[i].name < entries[j].name
+		}
+		return entries[i].svc < entries[j].svc
+	})
+
+	index := make(map[string]toolRoute, len(entries))
+	toolDef := make(map[string]mcp.Tool, len(entries))
+	for _, e := range entries {
+		key := e.svc + sep + e.name
+		exposed := e.name
+		switch mode {
+		case constant.MCPToolNamespaceModeAlways:
+			exposed = key
+		case constant.MCPToolNamespaceModeAuto:
+			if prev, ok := a.assigned[key]; ok {
+				exposed = prev.name
+			} else if _, taken := index[e.name]; e.collided || taken {
+				exposed = key
 			}
 		}
+		if alias := lookupAlias(ns.Aliases, key, e.name); alias != "" {
+			exposed = alias
+		}
+		if prev, ok := index[exposed]; ok {
+			a.warnCollision("alias:"+exposed,
+				"mcp tool name %q is already used by %s%s%s, skip %s", exposed, prev.Service, sep, prev.Name, key)
+			continue
+		}
+		if mode == constant.MCPToolNamespaceModeAuto {
+			a.assigned[key] = assignedName{name: exposed, lastSeen: now}
+		}
+
+		urls := make([]string, 0, len(e.insts))
+		for _, in := range e.insts {
+			urls = append(urls, in.url)
+		}
+		index[exposed] = toolRoute{
+			Service:    e.svc,
+			Name:       e.name,
+			URLs:       urls,
+			Idempotent: isIdempotent(e.insts[0].tool, exposed),
+		}
+		def := e.insts[0].tool
+		def.Name = exposed
+		toolDef[exposed] = def
 	}
-	index := make(map[string]string, len(candidates))
-	for name, urls := range candidates {
-		//sort.Slice(urls, func(i, j int) bool {
-		//	si := scoreURL(urls[i])
-		//	sj := scoreURL(urls[j])
-		//	if si != sj {
-		//		return si > sj
-		//	}
-		//	return urls[i] < urls[j]
-		//})
-		index[name] = urls[0]
+	// 只有工具集合变化时才按 Info 输出，实例增减但工具不变（如副本扩缩容）时按 Debug 输出
+	changed := len(index) != len(a.toolIndex)
+	for name := range index {
+		if _, ok := a.toolIndex[name]; !ok {
+			changed = true
+			break
+		}
 	}
 	a.toolIndex = index
 	a.toolSnapshot = toolDef
+	if changed {
+		logger.Infof("mcp tool index rebuilt: tools=%d collisions=%d", len(index), a.collisions.Load())
+	} else {
+		logger.Debugf("mcp tool index rebuilt: tools=%d collisions=%d", len(index), a.collisions.Load())
+	}
 }
 
-// scoreURL URL权重，不是很必要
-func scoreURL(u string) int {
-	return 0
+// warnCollision 记录一次冲突；同一个 key 只告警一次
+func (a *AggregatedClient) warnCollision(key, template string, args ...any) {
+	if _, ok := a.collisionsSeen[key]; ok {
+		return
+	}
+	a.collisionsSeen[key] = struct{}{}
+	n := a.collisions.Add(1)
+	logger.Warnf(template+" (collisions=%d)", append(args, n)...)
+}
+
+// Collisions 返回至今检测到的工具名/schema 冲突次数
+func (a *AggregatedClient) Collisions() int64 {
+	return a.collisions.Load()
+}
+
+func namespaceMode(mode string) string {
+	switch mode {
+	case constant.MCPToolNamespaceModeAlways, constant.MCPToolNamespaceModeNever:
+		return mode
+	default:
+		return constant.MCPToolNamespaceModeAuto
+	}
+}
+
+// lookupAlias 依次按 "服务名__工具名"、原始工具名查找别名
+func lookupAlias(aliases map[string]string, keys ...string) string {
+	for _, k := range keys {
+		// viper 会把 map key 转成小写
+		if v := aliases[strings.ToLower(k)]; v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// isIdempotent 工具是否可以安全重试：MCP 注解声明只读/幂等，或在配置中显式列出
+func isIdempotent(t mcp.Tool, exposed string) bool {
+	if h := t.Annotations.ReadOnlyHint; h != nil && *h {
+		return true
+	}
+	if h := t.Annotations.IdempotentHint; h != nil && *h {
+		return true
+	}
+	for _, name := range config.Registry.LoadBalance.IdempotentTools {
+		if name == t.Name || name == exposed {
+			return true
+		}
+	}
+	return false
+}
+
+// sameSchema 比较两个工具定义的输入 schema 是否一致
+func sameSchema(a, b mcp.Tool) bool {
+	ba, _ := json.Marshal(a.InputSchema)
+	bb, _ := json.Marshal(b.InputSchema)
+	return string(ba) == string(bb)
 }
 
 func (a *AggregatedClient) ConvertToolsToOllama() []map[string]any {
@@ -202,15 +517,102 @@ func (a *AggregatedClient) ConvertToolsToOpenAI() []openai.ChatCompletionToolUni
 	return out
 }
 
+// CallTool 选择一个实例调用工具；幂等工具失败时换实例重试，连续失败的实例会被熔断
 func (a *AggregatedClient) CallTool(ctx context.Context, name string, args any) (string, error) {
 	a.mu.RLock()
-	url, ok := a.toolIndex[name]
-	cli := a.clients[url]
+	route, ok := a.toolIndex[name]
 	a.mu.RUnlock()
-	if !ok || cli == nil {
+	if !ok || len(route.URLs) == 0 {
 		return "", fmt.Errorf("tool %q not found (no connected MCP server provides it)", name)
 	}
-	return cli.CallTool(ctx, name, args)
+
+	lb := config.Registry.LoadBalance
+	attempts := 1
+	if route.Idempotent && lb.MaxRetries > 0 {
+		attempts += lb.MaxRetries
+	}
+	threshold, openTimeout := lb.CircuitBreaker.FailureThreshold, lb.CircuitBreaker.OpenTimeout
+	if threshold <= 0 {
+		threshold = constant.MCPBreakerDefaultThreshold
+	}
+	if openTimeout <= 0 {
+		openTimeout = constant.MCPBreakerDefaultOpenDuration
+	}
+
+	tried := make(map[string]struct{}, attempts)
+	var lastErr error
+	for i := 0; i < attempts; i++ {
+		url, cli, state := a.pick(ctx, name, route.URLs, tried)
+		if cli == nil {
+			break
+		}
+		tried[url] = struct{}{}
+
+		state.inflight.Add(1)
+		out, err := cli.CallTool(ctx, route.Name, args)
+		state.inflight.Add(-1)
+		if err == nil {
+			state.onSuccess()
+			return out, nil
+		}
+		lastErr = err
+		if ctx.Err() != nil {
+			state.onCanceled()
+			break
+		}
+		if state.onFailure(threshold, openTimeout) {
+			logger.Warnf("mcp instance %s circuit opened for %s after %d failures", url, openTimeout, threshold)
+		}
+		if i+1 < attempts {
+			logger.Warnf("mcp call tool %s on %s failed, retrying on another instance: %v", name, url, err)
+		}
+	}
+	if lastErr == nil {
+		lastErr = fmt.Errorf("tool %q: no available MCP instance (all instances are circuit open)", name)
+	}
+	return "", lastErr
+}
+
+// pick 从未尝试过且未熔断的实例中选择一个；熔断到期的实例只放行一个试探请求
+func (a *AggregatedClient) pick(ctx context.Context, tool string, urls []string, tried map[string]struct{}) (string, *MCPClient, *instanceState) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	now := time.Now()
+	var healthy []string
+	for _, u := range urls {
+		if _, ok := tried[u]; ok {
+			continue
+		}
+		if a.clients[u] == nil {
+			continue
+		}
+		if a.state(u).available(now) {
+			healthy = append(healthy, u)
+		}
+	}
+	for len(healthy) > 0 {
+		u := a.balancer.Pick(ctx, tool, healthy)
+		if st := a.state(u); st.acquire(now) {
+			return u, a.clients[u], st
+		}
+		// 试探资格被其他请求抢先拿到，换一个实例
+		healthy = slices.DeleteFunc(healthy, func(s string) bool { return s == u })
+	}
+	return "", nil, nil
+}
+
+// state 返回实例的并发数/熔断状态，不存在时创建
+func (a *AggregatedClient) state(url string) *instanceState {
+	v, _ := a.states.LoadOrStore(url, new(instanceState))
+	return v.(*instanceState)
+}
+
+// inflight 返回实例当前的并发调用数，供 least_inflight 策略使用
+func (a *AggregatedClient) inflight(url string) int64 {
+	if v, ok := a.states.Load(url); ok {
+		return v.(*instanceState).inflight.Load()
+	}
+	return 0
 }
 
 func (a *AggregatedClient) Close() {
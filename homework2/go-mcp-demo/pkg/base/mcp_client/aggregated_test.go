@@ -0,0 +1,363 @@
+package mcp_client
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/FantasyRL/go-mcp-demo/config"
+	"github.com/FantasyRL/go-mcp-demo/pkg/base/registry"
+	"github.com/FantasyRL/go-mcp-demo/pkg/constant"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+func newTestAggregated(t *testing.T, ns config.Config) *AggregatedClient {
+	t.Helper()
+	prev := config.Registry
+	config.Registry = &ns.Registry
+	t.Cleanup(func() { config.Registry = prev })
+	return &AggregatedClient{
+		clients:        make(map[string]*MCPClient),
+		urlService:     make(map[string]string),
+		collisionsSeen: make(map[string]struct{}),
+		assigned:       make(map[string]assignedName),
+		known:          make(map[string]string),
+		balancer:       NewBalancer("", nil),
+		stopCh:         make(chan struct{}),
+	}
+}
+
+// addInstance 模拟一个实例上线
+func (a *AggregatedClient) addInstance(url, svc string, tools ...string) {
+	cli := &MCPClient{}
+	for _, name := range tools {
+		cli.Tools = append(cli.Tools, mcp.NewTool(name))
+	}
+	a.clients[url] = cli
+	a.urlService[url] = svc
+	a.rebuildIndex()
+}
+
+func (a *AggregatedClient) removeInstance(url string) {
+	delete(a.clients, url)
+	delete(a.urlService, url)
+	a.rebuildIndex()
+}
+
+func (a *AggregatedClient) exposedNames() []string {
+	names := make([]string, 0, len(a.toolIndex))
+	for name := range a.toolIndex {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestRebuildIndexAuto(t *testing.T) {
+	a := newTestAggregated(t, config.Config{})
+
+	a.addInstance("http://a1", "a", "search", "cat")
+	a.addInstance("http://a2", "a", "search", "cat")
+	if got := a.exposedNames(); !slices.Equal(got, []string{"cat", "search"}) {
+		t.Fatalf("replicas: %v", got)
+	}
+	if urls := a.toolIndex["search"].URLs; !slices.Equal(urls, []string{"http://a1", "http://a2"}) {
+		t.Fatalf("replica urls: %v", urls)
+	}
+	if a.Collisions() != 0 {
+		t.Fatalf("collisions: %d", a.Collisions())
+	}
+
+	// 冲突服务加入：已有工具保留原名，新服务加前缀
+	a.addInstance("http://b1", "b", "search")
+	if got := a.exposedNames(); !slices.Equal(got, []string{"b__search", "cat", "search"}) {
+		t.Fatalf("after join: %v", got)
+	}
+	if r := a.toolIndex["b__search"]; r.Service != "b" || r.Name != "search" {
+		t.Fatalf("route: %+v", r)
+	}
+	if a.Collisions() != 1 {
+		t.Fatalf("collisions: %d", a.Collisions())
+	}
+
+	// 冲突服务下线、重新上线，暴露名都不变
+	a.removeInstance("http://b1")
+	if got := a.exposedNames(); !slices.Equal(got, []string{"cat", "search"}) {
+		t.Fatalf("after leave: %v", got)
+	}
+	a.addInstance("http://b1", "b", "search")
+	if got := a.exposedNames(); !slices.Equal(got, []string{"b__search", "cat", "search"}) {
+		t.Fatalf("after rejoin: %v", got)
+	}
+	if a.toolIndex["search"].Service != "a" {
+		t.Fatalf("search route: %+v", a.toolIndex["search"])
+	}
+	if a.Collisions() != 1 {
+		t.Fatalf("same collision should be counted once, got %d", a.Collisions())
+	}
+
+	// 原服务下线后，冲突服务保持前缀名
+	a.removeInstance("http://a1")
+	a.removeInstance("http://a2")
+	if got := a.exposedNames(); !slices.Equal(got, []string{"b__search"}) {
+		t.Fatalf("after owner leave: %v", got)
+	}
+}
+
+func TestRebuildIndexAssignedRetention(t *testing.T) {
+	a := newTestAggregated(t, config.Config{})
+	a.addInstance("http://a1", "a", "search")
+	a.addInstance("http://b1", "b", "search")
+	a.removeInstance("http://b1")
+	if _, ok := a.assigned["b__search"]; !ok {
+		t.Fatalf("assigned name released too early: %v", a.assigned)
+	}
+
+	// 下线超过保留时长后释放，仍在线的服务不受影响
+	for key, as := range a.assigned {
+		as.lastSeen = time.Now().Add(-constant.MCPToolNameRetention - time.Minute)
+		a.assigned[key] = as
+	}
+	a.addInstance("http://a2", "a", "search")
+	if _, ok := a.assigned["b__search"]; ok {
+		t.Fatalf("stale assigned name kept: %v", a.assigned)
+	}
+	if as, ok := a.assigned["a__search"]; !ok || as.name != "search" {
+		t.Fatalf("online assigned name released: %v", a.assigned)
+	}
+	if got := a.exposedNames(); !slices.Equal(got, []string{"search"}) {
+		t.Fatalf("tools: %v", got)
+	}
+}
+
+func TestRebuildIndexAutoInitialCollision(t *testing.T) {
+	a := newTestAggregated(t, config.Config{})
+	a.clients["http://b1"] = &MCPClient{Tools: []mcp.Tool{mcp.NewTool("search")}}
+	a.urlService["http://b1"] = "b"
+	a.clients["http://a1"] = &MCPClient{Tools: []mcp.Tool{mcp.NewTool("search")}}
+	a.urlService["http://a1"] = "a"
+	a.rebuildIndex()
+	if got := a.exposedNames(); !slices.Equal(got, []string{"a__search", "b__search"}) {
+		t.Fatalf("initial collision: %v", got)
+	}
+	a.removeInstance("http://b1")
+	if got := a.exposedNames(); !slices.Equal(got, []string{"a__search"}) {
+		t.Fatalf("after leave: %v", got)
+	}
+}
+
+func TestRebuildIndexModes(t *testing.T) {
+	var cfg config.Config
+	cfg.Registry.ToolNamespace.Mode = "always"
+	cfg.Registry.ToolNamespace.Separator = "."
+	a := newTestAggregated(t, cfg)
+	a.addInstance("http://a1", "a", "search")
+	a.addInstance("http://b1", "b", "search", "cat")
+	if got := a.exposedNames(); !slices.Equal(got, []string{"a.search", "b.cat", "b.search"}) {
+		t.Fatalf("always: %v", got)
+	}
+
+	cfg.Registry.ToolNamespace.Mode = "never"
+	cfg.Registry.ToolNamespace.Separator = ""
+	a = newTestAggregated(t, cfg)
+	a.addInstance("http://b1", "b", "search")
+	a.addInstance("http://a1", "a", "search")
+	if got := a.exposedNames(); !slices.Equal(got, []string{"search"}) {
+		t.Fatalf("never: %v", got)
+	}
+	if a.toolIndex["search"].Service != "a" {
+		t.Fatalf("never keeps the first service: %+v", a.toolIndex["search"])
+	}
+}
+
+func TestRebuildIndexAliases(t *testing.T) {
+	var cfg config.Config
+	cfg.Registry.ToolNamespace.Aliases = map[string]string{
+		"b__search": "web_search", // 服务名__工具名
+		"cat":       "read_file",  // 原始工具名
+		"a__ls":     "read_file",  // 与已有别名冲突，跳过
+	}
+	a := newTestAggregated(t, cfg)
+	a.addInstance("http://a1", "a", "search", "cat", "ls")
+	a.addInstance("http://b1", "b", "search")
+	if got := a.exposedNames(); !slices.Equal(got, []string{"read_file", "search", "web_search"}) {
+		t.Fatalf("aliases: %v", got)
+	}
+	if r := a.toolIndex["web_search"]; r.Service != "b" || r.Name != "search" {
+		t.Fatalf("alias route: %+v", r)
+	}
+	if r := a.toolIndex["read_file"]; r.Service != "a" || r.Name != "cat" {
+		t.Fatalf("alias route: %+v", r)
+	}
+	if a.toolSnapshot["web_search"].Name != "web_search" {
+		t.Fatalf("tool definition should use the exposed name: %+v", a.toolSnapshot["web_search"])
+	}
+	if a.Collisions() == 0 {
+		t.Fatal("alias conflict should be counted")
+	}
+}
+
+func TestPickSkipsOpenInstances(t *testing.T) {
+	a := newTestAggregated(t, config.Config{})
+	a.balancer = NewBalancer("", a.inflight)
+	a.addInstance("http://a1", "a", "search")
+	a.addInstance("http://a2", "a", "search")
+	urls := a.toolIndex["search"].URLs
+	ctx := context.Background()
+
+	a.state("http://a1").onFailure(1, time.Minute)
+	for i := 0; i < 4; i++ {
+		if u, _, _ := a.pick(ctx, "search", urls, nil); u != "http://a2" {
+			t.Fatalf("open instance picked: %s", u)
+		}
+	}
+	a.state("http://a2").onFailure(1, time.Minute)
+	if u, cli, _ := a.pick(ctx, "search", urls, nil); u != "" || cli != nil {
+		t.Fatalf("all instances open, got %s", u)
+	}
+
+	// 熔断到期后只有一个请求拿到试探资格
+	a.state("http://a1").openUntil = time.Now()
+	if u, _, _ := a.pick(ctx, "search", urls, nil); u != "http://a1" {
+		t.Fatalf("probe: %s", u)
+	}
+	if u, _, _ := a.pick(ctx, "search", urls, nil); u != "" {
+		t.Fatalf("second probe admitted: %s", u)
+	}
+}
+
+// newTestMCPServer 启动一个提供指定工具的 MCP Streamable HTTP 服务，gate 不为空时请求会阻塞到 gate 关闭
+func newTestMCPServer(t *testing.T, gate chan struct{}, tools ...string) string {
+	t.Helper()
+	s := server.NewMCPServer("test", "0.1.0")
+	for _, name := range tools {
+		s.AddTool(mcp.NewTool(name), func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			return mcp.NewToolResultText(name), nil
+		})
+	}
+	h := server.NewStreamableHTTPServer(s)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if gate != nil {
+			<-gate
+		}
+		h.ServeHTTP(w, r)
+	}))
+	t.Cleanup(ts.Close)
+	return ts.URL + "/mcp"
+}
+
+func useHTTPTransport(t *testing.T) {
+	t.Helper()
+	prev := config.MCP
+	cfg := &config.Config{}
+	cfg.MCP.Transport = "http"
+	config.MCP = &cfg.MCP
+	httpClientOnce = sync.Once{}
+	t.Cleanup(func() {
+		config.MCP = prev
+		httpClientOnce = sync.Once{}
+	})
+}
+
+func TestReconcile(t *testing.T) {
+	a := newTestAggregated(t, config.Config{})
+	useHTTPTransport(t)
+	u1 := newTestMCPServer(t, nil, "search")
+	u2 := newTestMCPServer(t, nil, "search", "cat")
+	down := "http://127.0.0.1:1/mcp"
+
+	a.known = map[string]string{u1: "a", u2: "a", down: "a"}
+	a.reconcile()
+	defer a.Close()
+	if len(a.clients) != 2 || a.clients[down] != nil {
+		t.Fatalf("clients: %v", a.clients)
+	}
+	if got := a.exposedNames(); !slices.Equal(got, []string{"cat", "search"}) {
+		t.Fatalf("tools: %v", got)
+	}
+	if urls := a.toolIndex["search"].URLs; len(urls) != 2 {
+		t.Fatalf("search urls: %v", urls)
+	}
+	out, err := a.CallTool(context.Background(), "cat", nil)
+	if err != nil || strings.TrimSpace(out) != "cat" {
+		t.Fatalf("call tool: %q %v", out, err)
+	}
+
+	a.mu.Lock()
+	delete(a.known, u2)
+	a.mu.Unlock()
+	a.reconcile()
+	if len(a.clients) != 1 || a.clients[u1] == nil {
+		t.Fatalf("clients after remove: %v", a.clients)
+	}
+	if got := a.exposedNames(); !slices.Equal(got, []string{"search"}) {
+		t.Fatalf("tools after remove: %v", got)
+	}
+}
+
+// fakeWatcher 把事件推给 AggregatedClient，并记录每次回调耗时
+type fakeWatcher struct {
+	events  []registry.Event
+	elapsed chan time.Duration
+}
+
+func (w *fakeWatcher) Resolve([]string) (map[string][]string, error) { return nil, nil }
+
+func (w *fakeWatcher) Watch(ctx context.Context, _ []string, onEvent func(registry.Event)) error {
+	for _, ev := range w.events {
+		start := time.Now()
+		onEvent(ev)
+		w.elapsed <- time.Since(start)
+	}
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestWatchDialsAsynchronously(t *testing.T) {
+	var cfg config.Config
+	cfg.Registry.Watch = true
+	cfg.Registry.RefreshInterval = time.Hour
+	newTestAggregated(t, cfg)
+	useHTTPTransport(t)
+
+	gate := make(chan struct{})
+	slow := newTestMCPServer(t, gate, "search")
+	w := &fakeWatcher{
+		events:  []registry.Event{{Type: registry.EventAdd, Service: "a", URL: slow}},
+		elapsed: make(chan time.Duration, 1),
+	}
+	a := NewAggregatedClient(w, []string{"a"})
+	defer a.Close()
+
+	// 实例建连被阻塞时，事件回调也应当立即返回
+	select {
+	case d := <-w.elapsed:
+		if d > time.Second {
+			t.Fatalf("onEvent blocked for %s", d)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("onEvent blocked by dialing")
+	}
+	close(gate)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		a.mu.RLock()
+		_, ok := a.toolIndex["search"]
+		a.mu.RUnlock()
+		if ok {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("instance was not connected after the watch event")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
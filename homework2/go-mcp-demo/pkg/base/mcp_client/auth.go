@@ -0,0 +1,154 @@
+package mcp_client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/FantasyRL/go-mcp-demo/config"
+	"github.com/FantasyRL/go-mcp-demo/pkg/constant"
+	"github.com/FantasyRL/go-mcp-demo/pkg/logger"
+	"github.com/FantasyRL/go-mcp-demo/pkg/utils"
+	"github.com/google/uuid"
+	"github.com/mark3labs/mcp-go/client/transport"
+)
+
+// authHeaderFunc 按 config.MCP.Auth.Mode 生成每次请求携带的凭证，none 返回 nil
+func authHeaderFunc(httpCli *http.Client) (transport.HTTPHeaderFunc, error) {
+	c := config.MCP.Auth
+	switch c.Mode {
+	case "", constant.MCPAuthModeNone:
+		return nil, nil
+	case constant.MCPAuthModeStatic:
+		if c.Token == "" {
+			return nil, fmt.Errorf("mcp auth: static mode requires mcp.auth.token")
+		}
+		return bearer(func(context.Context) (string, error) { return c.Token, nil }), nil
+	case constant.MCPAuthModeHMAC:
+		srv := config.Server()
+		if srv.Secret == "" {
+			return nil, fmt.Errorf("mcp auth: hmac mode requires server.private-key")
+		}
+		ttl := c.HMACTTL
+		if ttl <= 0 {
+			ttl = constant.MCPAuthHMACDefaultTTL
+		}
+		src := &hmacTokenSource{secret: srv.Secret, subject: srv.Name, ttl: ttl}
+		return bearer(src.token), nil
+	case constant.MCPAuthModeOAuth:
+		if c.OAuth.TokenURL == "" || c.OAuth.ClientID == "" {
+			return nil, fmt.Errorf("mcp auth: oauth mode requires mcp.auth.oauth.token_url and client_id")
+		}
+		src := &clientCredentialsSource{cfg: c.OAuth, http: httpCli}
+		return bearer(src.token), nil
+	default:
+		return nil, fmt.Errorf("mcp auth: unknown mode %q", c.Mode)
+	}
+}
+
+// bearer 把 token 获取函数包装为请求头；获取失败时不带凭证，由服务端返回 401
+func bearer(get func(ctx context.Context) (string, error)) transport.HTTPHeaderFunc {
+	return func(ctx context.Context) map[string]string {
+		token, err := get(ctx)
+		if err != nil {
+			logger.Errorf("mcp auth: get token: %v", err)
+			return nil
+		}
+		return map[string]string{"Authorization": "Bearer " + token}
+	}
+}
+
+// hmacTokenSource 使用 server.private-key 签发短期 token，临近过期时重新签发
+type hmacTokenSource struct {
+	secret  string
+	subject string
+	ttl     time.Duration
+
+	mu     sync.Mutex
+	cached string
+	expire time.Time
+}
+
+func (s *hmacTokenSource) token(context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	if s.cached != "" && now.Add(s.ttl/5).Before(s.expire) {
+		return s.cached, nil
+	}
+	id, _ := uuid.NewV7()
+	expire := now.Add(s.ttl)
+	token, err := utils.SignJWT(utils.JWTClaims{
+		Subject:   s.subject,
+		Audience:  constant.MCPAuthHMACAudience,
+		IssuedAt:  now.Unix(),
+		ExpiresAt: expire.Unix(),
+		ID:        id.String(),
+	}, s.secret)
+	if err != nil {
+		return "", err
+	}
+	s.cached, s.expire = token, expire
+	return token, nil
+}
+
+// clientCredentialsSource OAuth 2.1 client_credentials 授权，带 RFC 8707 resource 参数，缓存到过期前
+type clientCredentialsSource struct {
+	cfg  config.MCPOAuth
+	http *http.Client
+
+	mu     sync.Mutex
+	cached string
+	expire time.Time
+}
+
+func (s *clientCredentialsSource) token(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.cached != "" && time.Now().Add(constant.MCPAuthTokenRefreshSkew).Before(s.expire) {
+		return s.cached, nil
+	}
+	form := url.Values{"grant_type": {"client_credentials"}}
+	if len(s.cfg.Scopes) > 0 {
+		form.Set("scope", strings.Join(s.cfg.Scopes, " "))
+	}
+	if s.cfg.Resource != "" {
+		form.Set("resource", s.cfg.Resource)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.cfg.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(url.QueryEscape(s.cfg.ClientID), url.QueryEscape(s.cfg.ClientSecret))
+	resp, err := s.http.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("token request: %w", err)
+	}
+	defer resp.Body.Close()
+	var out struct {
+		AccessToken string `json:"access_token"`
+		TokenType   string `json:"token_type"`
+		ExpiresIn   int64  `json:"expires_in"`
+		Error       string `json:"error"`
+		ErrorDesc   string `json:"error_description"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return "", fmt.Errorf("token response: %s: %w", resp.Status, err)
+	}
+	if resp.StatusCode != http.StatusOK || out.AccessToken == "" {
+		return "", fmt.Errorf("token response: %s: %s %s", resp.Status, out.Error, out.ErrorDesc)
+	}
+	expiresIn := time.Duration(out.ExpiresIn) * time.Second
+	if expiresIn <= 0 {
+		expiresIn = time.Hour
+	}
+	s.cached, s.expire = out.AccessToken, time.Now().Add(expiresIn)
+	return s.cached, nil
+}
@@ -0,0 +1,117 @@
+package mcp_client
+
+import (
+	"context"
+	"hash/crc32"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+
+	"github.com/FantasyRL/go-mcp-demo/pkg/constant"
+)
+
+// Balancer 在同一工具的多个 MCP 实例之间做选择
+type Balancer interface {
+	// Pick 从 urls 中选出本次调用使用的实例，urls 不为空
+	Pick(ctx context.Context, tool string, urls []string) string
+}
+
+type conversationIDKey struct{}
+
+// WithConversationID 在 ctx 中携带会话 ID，供一致性哈希把同一会话固定到同一实例（有状态工具）
+func WithConversationID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, conversationIDKey{}, id)
+}
+
+// ConversationIDFromContext 取出 ctx 中的会话 ID
+func ConversationIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(conversationIDKey{}).(string)
+	return id
+}
+
+// NewBalancer 按策略名创建 Balancer，未知策略退化为轮询
+// inflight 用于 least_inflight 策略查询实例当前的并发调用数
+func NewBalancer(strategy string, inflight func(url string) int64) Balancer {
+	switch strategy {
+	case constant.MCPBalanceLeastInflight:
+		return &leastInflightBalancer{inflight: inflight, fallback: newRoundRobinBalancer()}
+	case constant.MCPBalanceConsistentHash:
+		return &consistentHashBalancer{fallback: newRoundRobinBalancer()}
+	default:
+		return newRoundRobinBalancer()
+	}
+}
+
+// roundRobinBalancer 按工具维度轮询
+type roundRobinBalancer struct {
+	counters sync.Map // tool -> *atomic.Uint64
+}
+
+func newRoundRobinBalancer() *roundRobinBalancer {
+	return &roundRobinBalancer{}
+}
+
+func (b *roundRobinBalancer) Pick(_ context.Context, tool string, urls []string) string {
+	v, _ := b.counters.LoadOrStore(tool, new(atomic.Uint64))
+	n := v.(*atomic.Uint64).Add(1) - 1
+	return urls[n%uint64(len(urls))]
+}
+
+// leastInflightBalancer 选择并发调用最少的实例，并列时轮询
+type leastInflightBalancer struct {
+	inflight func(url string) int64
+	fallback *roundRobinBalancer
+}
+
+func (b *leastInflightBalancer) Pick(ctx context.Context, tool string, urls []string) string {
+	if b.inflight == nil {
+		return b.fallback.Pick(ctx, tool, urls)
+	}
+	var best []string
+	var min int64 = -1
+	for _, u := range urls {
+		n := b.inflight(u)
+		switch {
+		case min < 0 || n < min:
+			min = n
+			best = []string{u}
+		case n == min:
+			best = append(best, u)
+		}
+	}
+	return b.fallback.Pick(ctx, tool, best)
+}
+
+// consistentHashBalancer 按会话 ID 做一致性哈希，没有会话 ID 时轮询
+type consistentHashBalancer struct {
+	fallback *roundRobinBalancer
+}
+
+// hashReplicas 每个实例在哈希环上的虚拟节点数
+const hashReplicas = 64
+
+func (b *consistentHashBalancer) Pick(ctx context.Context, tool string, urls []string) string {
+	key := ConversationIDFromContext(ctx)
+	if key == "" || len(urls) == 1 {
+		return b.fallback.Pick(ctx, tool, urls)
+	}
+	// 实例数量很少，每次现算哈希环即可，省去维护环的同步成本
+	type node struct {
+		hash uint32
+		url  string
+	}
+	ring := make([]node, 0, len(urls)*hashReplicas)
+	for _, u := range urls {
+		for i := 0; i < hashReplicas; i++ {
+			ring = append(ring, node{hash: crc32.ChecksumIEEE([]byte(u + "#" + strconv.Itoa(i))), url: u})
+		}
+	}
+	sort.Slice(ring, func(i, j int) bool { return ring[i].hash < ring[j].hash })
+	h := crc32.ChecksumIEEE([]byte(key))
+	i := sort.Search(len(ring), func(i int) bool { return ring[i].hash >= h })
+	if i == len(ring) {
+		i = 0
+	}
+	return ring[i].url
+}
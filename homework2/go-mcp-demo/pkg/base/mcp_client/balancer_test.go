@@ -0,0 +1,90 @@
+package mcp_client
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/FantasyRL/go-mcp-demo/pkg/constant"
+)
+
+func TestRoundRobinBalancer(t *testing.T) {
+	b := NewBalancer("", nil)
+	urls := []string{"a", "b", "c"}
+	ctx := context.Background()
+	for i := 0; i < 6; i++ {
+		if got := b.Pick(ctx, "search", urls); got != urls[i%3] {
+			t.Fatalf("pick %d: %s", i, got)
+		}
+	}
+	// 每个工具单独计数
+	if got := b.Pick(ctx, "cat", urls); got != "a" {
+		t.Fatalf("per tool counter: %s", got)
+	}
+}
+
+func TestLeastInflightBalancer(t *testing.T) {
+	inflight := map[string]int64{"a": 3, "b": 1, "c": 1}
+	b := NewBalancer(constant.MCPBalanceLeastInflight, func(url string) int64 { return inflight[url] })
+	urls := []string{"a", "b", "c"}
+	ctx := context.Background()
+
+	// 并列最少时在并列实例之间轮询
+	seen := map[string]int{}
+	for i := 0; i < 4; i++ {
+		seen[b.Pick(ctx, "search", urls)]++
+	}
+	if seen["a"] != 0 || seen["b"] != 2 || seen["c"] != 2 {
+		t.Fatalf("ties: %v", seen)
+	}
+
+	inflight["c"] = 0
+	for i := 0; i < 3; i++ {
+		if got := b.Pick(ctx, "search", urls); got != "c" {
+			t.Fatalf("least inflight: %s", got)
+		}
+	}
+}
+
+func TestConsistentHashBalancer(t *testing.T) {
+	b := NewBalancer(constant.MCPBalanceConsistentHash, nil)
+	urls := []string{"http://a", "http://b", "http://c"}
+
+	// 同一会话固定到同一实例
+	picks := map[string]string{}
+	for i := 0; i < 50; i++ {
+		id := fmt.Sprintf("conv-%d", i)
+		ctx := WithConversationID(context.Background(), id)
+		first := b.Pick(ctx, "search", urls)
+		for j := 0; j < 3; j++ {
+			if got := b.Pick(ctx, "search", urls); got != first {
+				t.Fatalf("conversation %s moved from %s to %s", id, first, got)
+			}
+		}
+		picks[id] = first
+	}
+	used := map[string]bool{}
+	for _, u := range picks {
+		used[u] = true
+	}
+	if len(used) != len(urls) {
+		t.Fatalf("conversations should spread over instances: %v", used)
+	}
+
+	// 下线一个实例时，其余实例上的会话不迁移
+	rest := []string{"http://a", "http://b"}
+	for id, u := range picks {
+		if u == "http://c" {
+			continue
+		}
+		if got := b.Pick(WithConversationID(context.Background(), id), "search", rest); got != u {
+			t.Fatalf("conversation %s moved from %s to %s after removing another instance", id, u, got)
+		}
+	}
+
+	// 没有会话 ID 时轮询
+	ctx := context.Background()
+	if a, b2 := b.Pick(ctx, "search", urls), b.Pick(ctx, "search", urls); a == b2 {
+		t.Fatalf("no conversation id should round robin: %s %s", a, b2)
+	}
+}
@@ -0,0 +1,90 @@
+package mcp_client
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// breakerState 熔断器状态
+type breakerState int
+
+const (
+	breakerClosed   breakerState = iota // 正常放行
+	breakerOpen                         // 熔断中，openUntil 之前拒绝请求
+	breakerHalfOpen                     // 已放行一个试探请求，结果返回前拒绝其他请求
+)
+
+// instanceState 单个 MCP 实例的并发数与熔断状态
+// 连续失败达到阈值后熔断（open），openTimeout 之后只放行一个试探请求（half-open），
+// 试探成功即恢复，失败则重新熔断
+type instanceState struct {
+	inflight atomic.Int64
+
+	mu        sync.Mutex
+	state     breakerState
+	failures  int
+	openUntil time.Time
+}
+
+// available 实例当前是否可以被选中，不改变状态
+func (s *instanceState) available(now time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch s.state {
+	case breakerOpen:
+		return !now.Before(s.openUntil)
+	case breakerHalfOpen:
+		return false
+	default:
+		return true
+	}
+}
+
+// acquire 为一次调用申请放行；熔断到期时转为 half-open，只有第一个调用者拿到试探资格
+func (s *instanceState) acquire(now time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch s.state {
+	case breakerOpen:
+		if now.Before(s.openUntil) {
+			return false
+		}
+		s.state = breakerHalfOpen
+		return true
+	case breakerHalfOpen:
+		return false
+	default:
+		return true
+	}
+}
+
+func (s *instanceState) onSuccess() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.state = breakerClosed
+	s.failures = 0
+	s.openUntil = time.Time{}
+}
+
+// onFailure 记录一次失败，返回本次是否触发熔断
+func (s *instanceState) onFailure(threshold int, openTimeout time.Duration) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures++
+	if s.state != breakerHalfOpen && s.failures < threshold {
+		return false
+	}
+	s.state = breakerOpen
+	s.openUntil = time.Now().Add(openTimeout)
+	return true
+}
+
+// onCanceled 调用方取消时没有得到实例的真实结果，归还试探资格让下一个请求继续试探
+func (s *instanceState) onCanceled() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.state == breakerHalfOpen {
+		s.state = breakerOpen
+	}
+}
@@ -0,0 +1,86 @@
+package mcp_client
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestBreaker(t *testing.T) {
+	s := new(instanceState)
+	now := time.Now()
+	if !s.available(now) || !s.acquire(now) {
+		t.Fatal("closed breaker should allow")
+	}
+	if s.onFailure(2, time.Minute) {
+		t.Fatal("first failure should not open")
+	}
+	if !s.onFailure(2, time.Minute) {
+		t.Fatal("second failure should open")
+	}
+	if s.available(now) || s.acquire(now) {
+		t.Fatal("open breaker should reject")
+	}
+
+	// 熔断到期后只放行一个试探请求
+	later := time.Now().Add(2 * time.Minute)
+	if !s.available(later) {
+		t.Fatal("expired breaker should be available for a probe")
+	}
+	if !s.acquire(later) {
+		t.Fatal("first caller should get the probe")
+	}
+	if s.available(later) || s.acquire(later) {
+		t.Fatal("half-open breaker should reject while the probe is in flight")
+	}
+
+	// 试探失败立即重新熔断，不需要再累计到阈值
+	if !s.onFailure(2, time.Minute) {
+		t.Fatal("probe failure should reopen")
+	}
+	if s.acquire(now) {
+		t.Fatal("reopened breaker should reject")
+	}
+
+	// 试探被取消时归还资格
+	later = time.Now().Add(2 * time.Minute)
+	if !s.acquire(later) {
+		t.Fatal("probe after reopen")
+	}
+	s.onCanceled()
+	if !s.acquire(later) {
+		t.Fatal("canceled probe should be returned")
+	}
+
+	// 试探成功后恢复
+	s.onSuccess()
+	if !s.acquire(now) || !s.acquire(now) {
+		t.Fatal("closed breaker should allow every call")
+	}
+	if s.onFailure(2, time.Minute) {
+		t.Fatal("failures should be reset after success")
+	}
+}
+
+func TestBreakerSingleProbe(t *testing.T) {
+	s := new(instanceState)
+	s.onFailure(1, time.Millisecond)
+	later := time.Now().Add(time.Second)
+
+	var admitted atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 32; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if s.acquire(later) {
+				admitted.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	if admitted.Load() != 1 {
+		t.Fatalf("half-open should admit exactly one probe, got %d", admitted.Load())
+	}
+}
@@ -0,0 +1,30 @@
+package mcp_client
+
+import (
+	"context"
+
+	"github.com/FantasyRL/go-mcp-demo/pkg/base/guard"
+	"github.com/FantasyRL/go-mcp-demo/pkg/constant"
+)
+
+// guardedClient 在 ToolClient 外层加上 Sentinel 流控与熔断，资源为 "mcp" 与 "mcp:<工具名>"
+type guardedClient struct {
+	ToolClient
+}
+
+// WithGuard 为 ToolClient 加上 Sentinel 保护
+func WithGuard(c ToolClient) ToolClient {
+	if c == nil {
+		return nil
+	}
+	return &guardedClient{ToolClient: c}
+}
+
+func (g *guardedClient) CallTool(ctx context.Context, name string, args any) (string, error) {
+	var out string
+	err := guard.Do(func() (err error) {
+		out, err = g.ToolClient.CallTool(ctx, name, args)
+		return err
+	}, constant.SentinelResourceMCP, constant.SentinelResourceMCP+":"+name)
+	return out, err
+}
@@ -5,12 +5,22 @@ import (
 	"fmt"
 	"github.com/FantasyRL/go-mcp-demo/pkg/constant"
 	mcpc "github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/client/transport"
 	"github.com/mark3labs/mcp-go/mcp"
+	"net/http"
 )
 
 // newSSEMCPClientWithConn [MCP规范已废弃]通过 SSE 连接指定 URL
 func newSSEMCPClientWithConn(url string) (*MCPClient, error) {
-	c, err := mcpc.NewStreamableHttpClient(url)
+	httpCli, err := getHTTPClient()
+	if err != nil {
+		return nil, err
+	}
+	opts, err := httpTransportOptions(httpCli)
+	if err != nil {
+		return nil, err
+	}
+	c, err := mcpc.NewStreamableHttpClient(url, opts...)
 	if err != nil {
 		return nil, fmt.Errorf("new sse client: %w", err)
 	}
@@ -40,7 +50,15 @@ func newSSEMCPClientWithConn(url string) (*MCPClient, error) {
 
 // newHTTPMCPClientWithConn 通过 Streamable HTTP 连接指定 URL
 func newHTTPMCPClientWithConn(url string) (*MCPClient, error) {
-	c, err := mcpc.NewStreamableHttpClient(url)
+	httpCli, err := getHTTPClient()
+	if err != nil {
+		return nil, err
+	}
+	opts, err := httpTransportOptions(httpCli)
+	if err != nil {
+		return nil, err
+	}
+	c, err := mcpc.NewStreamableHttpClient(url, opts...)
 	if err != nil {
 		return nil, fmt.Errorf("new http client: %w", err)
 	}
@@ -67,3 +85,16 @@ func newHTTPMCPClientWithConn(url string) (*MCPClient, error) {
 
 	return &MCPClient{Client: c, Tools: resTool.Tools}, nil
 }
+
+// httpTransportOptions TLS 与鉴权相关的传输选项
+func httpTransportOptions(httpCli *http.Client) ([]transport.StreamableHTTPCOption, error) {
+	opts := []transport.StreamableHTTPCOption{transport.WithHTTPBasicClient(httpCli)}
+	headerFunc, err := authHeaderFunc(httpCli)
+	if err != nil {
+		return nil, err
+	}
+	if headerFunc != nil {
+		opts = append(opts, transport.WithHTTPHeaderFunc(headerFunc))
+	}
+	return opts, nil
+}
@@ -0,0 +1,56 @@
+package mcp_client
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/FantasyRL/go-mcp-demo/config"
+	"github.com/FantasyRL/go-mcp-demo/pkg/utils"
+)
+
+var (
+	httpClientOnce sync.Once
+	httpClient     *http.Client
+	httpClientErr  error
+)
+
+// getHTTPClient 按 config.MCP.TLS 构建连接 MCP 服务的 http.Client，所有实例共享连接池
+func getHTTPClient() (*http.Client, error) {
+	httpClientOnce.Do(func() {
+		tlsCfg, err := clientTLSConfig()
+		if err != nil {
+			httpClientErr = err
+			return
+		}
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = tlsCfg
+		httpClient = &http.Client{Transport: transport}
+	})
+	return httpClient, httpClientErr
+}
+
+func clientTLSConfig() (*tls.Config, error) {
+	c := config.MCP.TLS
+	tlsCfg := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		ServerName:         c.ServerName,
+		InsecureSkipVerify: c.InsecureSkipVerify,
+	}
+	if c.CAFile != "" {
+		pool, err := utils.LoadCertPool(c.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("mcp tls: %w", err)
+		}
+		tlsCfg.RootCAs = pool
+	}
+	if c.ClientCertFile != "" || c.ClientKeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(c.ClientCertFile, c.ClientKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("mcp tls: load client cert: %w", err)
+		}
+		tlsCfg.Certificates = []tls.Certificate{cert}
+	}
+	return tlsCfg, nil
+}
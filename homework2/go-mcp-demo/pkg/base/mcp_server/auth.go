@@ -0,0 +1,304 @@
+package mcp_server
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/FantasyRL/go-mcp-demo/config"
+	"github.com/FantasyRL/go-mcp-demo/pkg/constant"
+	"github.com/FantasyRL/go-mcp-demo/pkg/logger"
+	"github.com/FantasyRL/go-mcp-demo/pkg/utils"
+)
+
+var (
+	errMissingToken      = errors.New("missing bearer token")
+	errInvalidToken      = errors.New("invalid token")
+	errInsufficientScope = errors.New("insufficient scope")
+)
+
+// authenticator 校验请求中的 Bearer Token，返回调用方标识
+type authenticator interface {
+	authenticate(r *http.Request, token string) (subject string, err error)
+	// challenge 鉴权失败时附加到 WWW-Authenticate 的参数
+	challenge(r *http.Request) []string
+}
+
+type subjectKey struct{}
+
+// SubjectFromContext 取出鉴权通过的调用方标识（static 模式为空）
+func SubjectFromContext(ctx context.Context) string {
+	s, _ := ctx.Value(subjectKey{}).(string)
+	return s
+}
+
+// newAuthenticator 按 config.MCP.Auth.Mode 创建鉴权器，none 返回 nil
+func newAuthenticator() (authenticator, error) {
+	c := config.MCP.Auth
+	switch c.Mode {
+	case "", constant.MCPAuthModeNone:
+		return nil, nil
+	case constant.MCPAuthModeStatic:
+		if len(c.Tokens) == 0 {
+			return nil, fmt.Errorf("mcp_server auth: static mode requires mcp.auth.tokens")
+		}
+		return &staticAuthenticator{tokens: c.Tokens}, nil
+	case constant.MCPAuthModeHMAC:
+		secret := config.Server().Secret
+		if secret == "" {
+			return nil, fmt.Errorf("mcp_server auth: hmac mode requires server.private-key")
+		}
+		return &hmacAuthenticator{secret: secret}, nil
+	case constant.MCPAuthModeOAuth:
+		if c.OAuth.IntrospectionURL == "" {
+			return nil, fmt.Errorf("mcp_server auth: oauth mode requires mcp.auth.oauth.introspection_url")
+		}
+		return &oauthAuthenticator{
+			cfg:   c.OAuth,
+			http:  &http.Client{Timeout: 10 * time.Second},
+			cache: newIntrospectionCache(constant.MCPAuthIntrospectionCacheMax, constant.MCPAuthIntrospectionSweep),
+		}, nil
+	default:
+		return nil, fmt.Errorf("mcp_server auth: unknown mode %q", c.Mode)
+	}
+}
+
+// requireAuth 对 /mcp 做鉴权，失败时按 RFC 6750 返回 WWW-Authenticate
+func (s *HTTPServer) requireAuth(auth authenticator, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := bearerToken(r)
+		var (
+			subject string
+			err     = errMissingToken
+		)
+		if ok {
+			subject, err = auth.authenticate(r, token)
+		}
+		if err != nil {
+			s.writeAuthError(w, r, auth, err)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), subjectKey{}, subject)))
+	})
+}
+
+func (s *HTTPServer) writeAuthError(w http.ResponseWriter, r *http.Request, auth authenticator, err error) {
+	params := append([]string{`realm="` + s.serviceName + `"`}, auth.challenge(r)...)
+	code, desc := http.StatusUnauthorized, err.Error()
+	switch {
+	case errors.Is(err, errMissingToken):
+	case errors.Is(err, errInsufficientScope):
+		code = http.StatusForbidden
+		params = append(params, `error="insufficient_scope"`)
+	default:
+		params = append(params, `error="invalid_token"`)
+	}
+	if code == http.StatusUnauthorized && !errors.Is(err, errMissingToken) {
+		logger.Warnf("mcp_server: %s rejected request from %s: %v", s.serviceName, r.RemoteAddr, err)
+	}
+	w.Header().Set("WWW-Authenticate", "Bearer "+strings.Join(params, ", "))
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(map[string]string{"error": desc})
+}
+
+func bearerToken(r *http.Request) (string, bool) {
+	h := r.Header.Get("Authorization")
+	if len(h) < 7 || !strings.EqualFold(h[:7], "Bearer ") {
+		return "", false
+	}
+	token := strings.TrimSpace(h[7:])
+	return token, token != ""
+}
+
+// resourceBaseURL 按请求推导本服务的外部地址
+func resourceBaseURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host
+}
+
+// staticAuthenticator 静态 token 列表
+type staticAuthenticator struct {
+	tokens []string
+}
+
+func (a *staticAuthenticator) challenge(*http.Request) []string { return nil }
+
+func (a *staticAuthenticator) authenticate(_ *http.Request, token string) (string, error) {
+	for _, t := range a.tokens {
+		if subtle.ConstantTimeCompare([]byte(t), []byte(token)) == 1 {
+			return "", nil
+		}
+	}
+	return "", errInvalidToken
+}
+
+// hmacAuthenticator 校验使用 server.private-key 签发的 HS256 token
+type hmacAuthenticator struct {
+	secret string
+}
+
+func (a *hmacAuthenticator) challenge(*http.Request) []string { return nil }
+
+func (a *hmacAuthenticator) authenticate(_ *http.Request, token string) (string, error) {
+	var claims utils.JWTClaims
+	if err := utils.ParseJWT(token, a.secret, &claims); err != nil {
+		return "", fmt.Errorf("%w: %v", errInvalidToken, err)
+	}
+	if claims.Audience != constant.MCPAuthHMACAudience {
+		return "", fmt.Errorf("%w: unexpected audience %q", errInvalidToken, claims.Audience)
+	}
+	return claims.Subject, nil
+}
+
+// oauthAuthenticator OAuth 2.1 资源服务器：通过 RFC 7662 内省校验 access token，
+// 并按规范校验 audience（防止其他资源的 token 被转用）与 scope
+type oauthAuthenticator struct {
+	cfg  config.MCPOAuth
+	http *http.Client
+
+	cache *introspectionCache
+}
+
+type introspection struct {
+	Active   bool            `json:"active"`
+	Scope    string          `json:"scope"`
+	Subject  string          `json:"sub"`
+	ClientID string          `json:"client_id"`
+	Exp      int64           `json:"exp"`
+	Aud      json.RawMessage `json:"aud"` // string 或 []string
+
+	cachedUntil time.Time
+}
+
+// challenge MCP Authorization 规范要求 401 时通过 resource_metadata 告知客户端资源元数据地址
+func (a *oauthAuthenticator) challenge(r *http.Request) []string {
+	params := []string{`resource_metadata="` + resourceBaseURL(r) + constant.MCPAuthProtectedResourcePath + `"`}
+	if len(a.cfg.Scopes) > 0 {
+		params = append(params, `scope="`+strings.Join(a.cfg.Scopes, " ")+`"`)
+	}
+	return params
+}
+
+// handleProtectedResource RFC 9728 受保护资源元数据，供 MCP 客户端发现授权服务器
+func (a *oauthAuthenticator) handleProtectedResource(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"resource":                 a.resource(r),
+		"authorization_servers":    a.cfg.AuthorizationServers,
+		"scopes_supported":         a.cfg.Scopes,
+		"bearer_methods_supported": []string{"header"},
+	})
+}
+
+// resource 本资源服务器的标识，未配置时按请求地址推导
+func (a *oauthAuthenticator) resource(r *http.Request) string {
+	if a.cfg.Resource != "" {
+		return a.cfg.Resource
+	}
+	return resourceBaseURL(r) + constant.RegistryMCPDefaultPath
+}
+
+func (a *oauthAuthenticator) authenticate(r *http.Request, token string) (string, error) {
+	info, err := a.introspect(r.Context(), token)
+	if err != nil {
+		return "", err
+	}
+	if !info.Active || (info.Exp != 0 && time.Now().Unix() >= info.Exp) {
+		return "", errInvalidToken
+	}
+	resource := a.resource(r)
+	if !info.hasAudience(resource) {
+		return "", fmt.Errorf("%w: audience does not include %s", errInvalidToken, resource)
+	}
+	granted := strings.Fields(info.Scope)
+	for _, need := range a.cfg.Scopes {
+		if !contains(granted, need) {
+			return "", fmt.Errorf("%w: missing %s", errInsufficientScope, need)
+		}
+	}
+	if info.Subject != "" {
+		return info.Subject, nil
+	}
+	return info.ClientID, nil
+}
+
+func (a *oauthAuthenticator) introspect(ctx context.Context, token string) (*introspection, error) {
+	if info, ok := a.cache.get(token, time.Now()); ok {
+		return info, nil
+	}
+	form := url.Values{"token": {token}, "token_type_hint": {"access_token"}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.cfg.IntrospectionURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if a.cfg.IntrospectionClientID != "" {
+		req.SetBasicAuth(url.QueryEscape(a.cfg.IntrospectionClientID), url.QueryEscape(a.cfg.IntrospectionClientSecret))
+	}
+	resp, err := a.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: introspection: %v", errInvalidToken, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w: introspection: %s", errInvalidToken, resp.Status)
+	}
+	var info introspection
+	if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
+		return nil, fmt.Errorf("%w: introspection: %v", errInvalidToken, err)
+	}
+	// 只缓存有效 token，且不超过其过期时间；失效或内省失败的结果不缓存，避免被无效 token 撑满
+	now := time.Now()
+	if info.Active && (info.Exp == 0 || now.Unix() < info.Exp) {
+		until := now.Add(constant.MCPAuthIntrospectionCacheTTL)
+		if info.Exp != 0 && time.Unix(info.Exp, 0).Before(until) {
+			until = time.Unix(info.Exp, 0)
+		}
+		info.cachedUntil = until
+		a.cache.set(token, &info, now)
+	}
+	return &info, nil
+}
+
+func (i *introspection) hasAudience(resource string) bool {
+	if len(i.Aud) == 0 {
+		return false
+	}
+	var one string
+	if json.Unmarshal(i.Aud, &one) == nil {
+		return sameResource(one, resource)
+	}
+	var many []string
+	if json.Unmarshal(i.Aud, &many) == nil {
+		for _, a := range many {
+			if sameResource(a, resource) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func sameResource(a, b string) bool {
+	return strings.TrimRight(a, "/") == strings.TrimRight(b, "/")
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,92 @@
+package mcp_server
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"sync"
+	"time"
+)
+
+// introspectionCache 有界的内省结果缓存：按 token 摘要索引，超过容量淘汰最久未使用的，
+// 写入时每隔 sweepEvery 清理一次已过期的条目，避免大量一次性 token 占住内存
+type introspectionCache struct {
+	mu         sync.Mutex
+	max        int
+	sweepEvery time.Duration
+	nextSweep  time.Time
+	lru        *list.List // 最近使用的在前，元素为 *cacheEntry
+	items      map[[sha256.Size]byte]*list.Element
+}
+
+type cacheEntry struct {
+	key  [sha256.Size]byte
+	info *introspection
+}
+
+func newIntrospectionCache(max int, sweepEvery time.Duration) *introspectionCache {
+	return &introspectionCache{
+		max:        max,
+		sweepEvery: sweepEvery,
+		lru:        list.New(),
+		items:      make(map[[sha256.Size]byte]*list.Element),
+	}
+}
+
+// get 返回未过期的缓存结果
+func (c *introspectionCache) get(token string, now time.Time) (*introspection, bool) {
+	key := sha256.Sum256([]byte(token))
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	e := el.Value.(*cacheEntry)
+	if !now.Before(e.info.cachedUntil) {
+		c.remove(el)
+		return nil, false
+	}
+	c.lru.MoveToFront(el)
+	return e.info, true
+}
+
+// set 缓存结果直到 info.cachedUntil
+func (c *introspectionCache) set(token string, info *introspection, now time.Time) {
+	key := sha256.Sum256([]byte(token))
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if now.After(c.nextSweep) {
+		c.sweep(now)
+		c.nextSweep = now.Add(c.sweepEvery)
+	}
+	if el, ok := c.items[key]; ok {
+		el.Value.(*cacheEntry).info = info
+		c.lru.MoveToFront(el)
+		return
+	}
+	c.items[key] = c.lru.PushFront(&cacheEntry{key: key, info: info})
+	for c.lru.Len() > c.max {
+		c.remove(c.lru.Back())
+	}
+}
+
+func (c *introspectionCache) size() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lru.Len()
+}
+
+func (c *introspectionCache) sweep(now time.Time) {
+	for el := c.lru.Front(); el != nil; {
+		next := el.Next()
+		if !now.Before(el.Value.(*cacheEntry).info.cachedUntil) {
+			c.remove(el)
+		}
+		el = next
+	}
+}
+
+func (c *introspectionCache) remove(el *list.Element) {
+	c.lru.Remove(el)
+	delete(c.items, el.Value.(*cacheEntry).key)
+}
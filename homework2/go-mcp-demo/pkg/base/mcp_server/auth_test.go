@@ -0,0 +1,221 @@
+package mcp_server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/FantasyRL/go-mcp-demo/config"
+	"github.com/FantasyRL/go-mcp-demo/pkg/constant"
+	"github.com/FantasyRL/go-mcp-demo/pkg/utils"
+)
+
+func serveAuth(t *testing.T, auth authenticator, token string) *httptest.ResponseRecorder {
+	t.Helper()
+	s := &HTTPServer{serviceName: "mcp_test"}
+	h := s.requireAuth(auth, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(SubjectFromContext(r.Context())))
+	}))
+	req := httptest.NewRequest(http.MethodPost, "http://mcp.local/mcp", nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestStaticAuth(t *testing.T) {
+	auth := &staticAuthenticator{tokens: []string{"t1", "t2"}}
+	if rec := serveAuth(t, auth, "t2"); rec.Code != http.StatusOK {
+		t.Fatalf("valid token: code=%d", rec.Code)
+	}
+	rec := serveAuth(t, auth, "")
+	if rec.Code != http.StatusUnauthorized || !strings.HasPrefix(rec.Header().Get("WWW-Authenticate"), "Bearer ") {
+		t.Fatalf("missing token: code=%d header=%q", rec.Code, rec.Header().Get("WWW-Authenticate"))
+	}
+	if rec := serveAuth(t, auth, "bad"); !strings.Contains(rec.Header().Get("WWW-Authenticate"), `error="invalid_token"`) {
+		t.Fatalf("bad token: header=%q", rec.Header().Get("WWW-Authenticate"))
+	}
+}
+
+func TestHMACAuth(t *testing.T) {
+	auth := &hmacAuthenticator{secret: "s3cret"}
+	sign := func(secret, aud string, exp time.Time) string {
+		token, err := utils.SignJWT(utils.JWTClaims{Subject: "host", Audience: aud, ExpiresAt: exp.Unix()}, secret)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	}
+	future := time.Now().Add(time.Minute)
+	if rec := serveAuth(t, auth, sign("s3cret", constant.MCPAuthHMACAudience, future)); rec.Code != http.StatusOK || rec.Body.String() != "host" {
+		t.Fatalf("valid token: code=%d body=%q", rec.Code, rec.Body.String())
+	}
+	cases := map[string]string{
+		"wrong secret":   sign("other", constant.MCPAuthHMACAudience, future),
+		"wrong audience": sign("s3cret", "api", future),
+		"expired":        sign("s3cret", constant.MCPAuthHMACAudience, time.Now().Add(-time.Minute)),
+	}
+	// 没有 exp 的 token 永不过期，必须拒绝
+	noExp, err := utils.SignJWT(utils.JWTClaims{Subject: "host", Audience: constant.MCPAuthHMACAudience}, "s3cret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases["no exp"] = noExp
+	for name, token := range cases {
+		if rec := serveAuth(t, auth, token); rec.Code != http.StatusUnauthorized {
+			t.Errorf("%s: code=%d", name, rec.Code)
+		}
+	}
+}
+
+func TestOAuthAuth(t *testing.T) {
+	introspect := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, _ := r.BasicAuth(); user != "rs" || pass != "rs-secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_ = r.ParseForm()
+		resp := map[string]any{"active": false}
+		switch r.PostForm.Get("token") {
+		case "good":
+			resp = map[string]any{"active": true, "sub": "alice", "scope": "mcp:tools other", "aud": []string{"https://mcp.example.com/mcp"}}
+		case "other-resource":
+			resp = map[string]any{"active": true, "sub": "alice", "scope": "mcp:tools", "aud": "https://other.example.com"}
+		case "no-scope":
+			resp = map[string]any{"active": true, "sub": "alice", "scope": "other", "aud": "https://mcp.example.com/mcp"}
+		}
+		_ = json.NewEncoder(w).Encode(resp)
+	}))
+	defer introspect.Close()
+
+	cfg := &config.Config{}
+	old := config.MCP
+	config.MCP = &cfg.MCP
+	defer func() { config.MCP = old }()
+	config.MCP.Auth.Mode = constant.MCPAuthModeOAuth
+	config.MCP.Auth.OAuth = config.MCPOAuth{
+		Resource:                  "https://mcp.example.com/mcp",
+		AuthorizationServers:      []string{"https://auth.example.com"},
+		Scopes:                    []string{"mcp:tools"},
+		IntrospectionURL:          introspect.URL,
+		IntrospectionClientID:     "rs",
+		IntrospectionClientSecret: "rs-secret",
+	}
+	auth, err := newAuthenticator()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if rec := serveAuth(t, auth, "good"); rec.Code != http.StatusOK || rec.Body.String() != "alice" {
+		t.Fatalf("good token: code=%d body=%q", rec.Code, rec.Body.String())
+	}
+	rec := serveAuth(t, auth, "")
+	if rec.Code != http.StatusUnauthorized || !strings.Contains(rec.Header().Get("WWW-Authenticate"),
+		`resource_metadata="http://mcp.local/.well-known/oauth-protected-resource"`) {
+		t.Fatalf("missing token: code=%d header=%q", rec.Code, rec.Header().Get("WWW-Authenticate"))
+	}
+	if rec := serveAuth(t, auth, "inactive"); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("inactive token: code=%d", rec.Code)
+	}
+	if rec := serveAuth(t, auth, "other-resource"); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("token for another resource: code=%d", rec.Code)
+	}
+	rec = serveAuth(t, auth, "no-scope")
+	if rec.Code != http.StatusForbidden || !strings.Contains(rec.Header().Get("WWW-Authenticate"), `error="insufficient_scope"`) {
+		t.Fatalf("insufficient scope: code=%d header=%q", rec.Code, rec.Header().Get("WWW-Authenticate"))
+	}
+
+	meta := httptest.NewRecorder()
+	auth.(*oauthAuthenticator).handleProtectedResource(meta, httptest.NewRequest(http.MethodGet, "http://mcp.local"+constant.MCPAuthProtectedResourcePath, nil))
+	var doc map[string]any
+	if err := json.Unmarshal(meta.Body.Bytes(), &doc); err != nil || doc["resource"] != "https://mcp.example.com/mcp" {
+		t.Fatalf("resource metadata = %s", meta.Body.String())
+	}
+}
+
+func TestOAuthCachesOnlyActiveTokens(t *testing.T) {
+	var mu sync.Mutex
+	calls := map[string]int{}
+	introspect := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		token := r.PostForm.Get("token")
+		mu.Lock()
+		calls[token]++
+		mu.Unlock()
+		resp := map[string]any{"active": false}
+		if token == "good" {
+			resp = map[string]any{"active": true, "sub": "alice", "aud": "https://mcp.example.com/mcp"}
+		}
+		_ = json.NewEncoder(w).Encode(resp)
+	}))
+	defer introspect.Close()
+
+	cfg := &config.Config{}
+	old := config.MCP
+	config.MCP = &cfg.MCP
+	t.Cleanup(func() { config.MCP = old })
+	config.MCP.Auth.Mode = constant.MCPAuthModeOAuth
+	config.MCP.Auth.OAuth = config.MCPOAuth{Resource: "https://mcp.example.com/mcp", IntrospectionURL: introspect.URL}
+	auth, err := newAuthenticator()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 3; i++ {
+		if rec := serveAuth(t, auth, "good"); rec.Code != http.StatusOK {
+			t.Fatalf("good token: code=%d", rec.Code)
+		}
+		if rec := serveAuth(t, auth, "revoked"); rec.Code != http.StatusUnauthorized {
+			t.Fatalf("revoked token: code=%d", rec.Code)
+		}
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if calls["good"] != 1 || calls["revoked"] != 3 {
+		t.Fatalf("introspection calls = %v, want good cached and revoked never cached", calls)
+	}
+	if n := auth.(*oauthAuthenticator).cache.size(); n != 1 {
+		t.Fatalf("cache size = %d", n)
+	}
+}
+
+func TestIntrospectionCacheBounded(t *testing.T) {
+	c := newIntrospectionCache(2, time.Minute)
+	now := time.Now()
+	info := func(ttl time.Duration) *introspection {
+		return &introspection{Active: true, cachedUntil: now.Add(ttl)}
+	}
+
+	c.set("a", info(time.Hour), now)
+	c.set("b", info(time.Hour), now)
+	// 访问 a 后再写入 c，淘汰最久未使用的 b
+	if _, ok := c.get("a", now); !ok {
+		t.Fatal("a should be cached")
+	}
+	c.set("c", info(time.Hour), now)
+	if _, ok := c.get("b", now); ok || c.size() != 2 {
+		t.Fatalf("b should be evicted, size=%d", c.size())
+	}
+
+	// 过期条目读取时删除
+	c.set("short", info(time.Second), now)
+	if _, ok := c.get("short", now.Add(2*time.Second)); ok {
+		t.Fatal("expired entry should not be returned")
+	}
+
+	// 到达清理间隔后，写入时清掉所有过期条目
+	c = newIntrospectionCache(10, time.Minute)
+	for _, token := range []string{"x", "y", "z"} {
+		c.set(token, info(time.Second), now)
+	}
+	c.set("fresh", info(time.Hour), now.Add(2*time.Minute))
+	if c.size() != 1 {
+		t.Fatalf("size after sweep = %d, want 1", c.size())
+	}
+}
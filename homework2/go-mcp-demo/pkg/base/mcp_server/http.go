@@ -0,0 +1,197 @@
+package mcp_server
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/FantasyRL/go-mcp-demo/config"
+	"github.com/FantasyRL/go-mcp-demo/pkg/base/registry"
+	"github.com/FantasyRL/go-mcp-demo/pkg/base/registry/factory"
+	"github.com/FantasyRL/go-mcp-demo/pkg/constant"
+	"github.com/FantasyRL/go-mcp-demo/pkg/logger"
+	"github.com/FantasyRL/go-mcp-demo/pkg/utils"
+	"github.com/google/uuid"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
+
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// HTTPServer Streamable HTTP 服务的完整生命周期：
+// 监听 -> 注册到注册中心 -> 收到 SIGINT/SIGTERM -> 注销 -> 停止接收新请求并等待在途 MCP 请求 -> 退出
+type HTTPServer struct {
+	serviceName string
+	mcp         *server.StreamableHTTPServer
+
+	draining atomic.Bool    // 进入退出流程后 /health 返回 503
+	inflight sync.WaitGroup // 在途的 MCP POST 请求（工具调用等）
+
+	// baseCtx 在在途请求处理完后取消，用于结束 GET 监听流
+	baseCtx    context.Context
+	cancelBase context.CancelFunc
+}
+
+// Start 在 addr 上监听并阻塞，直到收到退出信号且优雅退出完成
+func (s *HTTPServer) Start(addr string) error {
+	tlsCfg, err := serverTLSConfig()
+	if err != nil {
+		return err
+	}
+
+	auth, err := newAuthenticator()
+	if err != nil {
+		return err
+	}
+
+	mux := http.NewServeMux()
+	var mcpHandler http.Handler = s.trackInflight(s.mcp)
+	if auth != nil {
+		mcpHandler = s.requireAuth(auth, mcpHandler)
+	}
+	if oa, ok := auth.(*oauthAuthenticator); ok {
+		// RFC 9728：元数据地址可以带上资源路径，两种形式都提供
+		mux.HandleFunc(constant.MCPAuthProtectedResourcePath, oa.handleProtectedResource)
+		mux.HandleFunc(constant.MCPAuthProtectedResourcePath+constant.RegistryMCPDefaultPath, oa.handleProtectedResource)
+	}
+	if tlsCfg != nil && tlsCfg.ClientCAs != nil {
+		mcpHandler = requireClientCert(mcpHandler)
+	}
+	mux.Handle(constant.RegistryMCPDefaultPath, mcpHandler)
+	mux.HandleFunc(constant.MCPServerHealthPath, s.handleHealth)
+
+	srv := &http.Server{
+		Addr:        addr,
+		Handler:     mux,
+		BaseContext: func(net.Listener) context.Context { return s.baseCtx },
+	}
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("listen %s: %w", addr, err)
+	}
+	scheme := "http"
+	if tlsCfg != nil {
+		ln = tls.NewListener(ln, tlsCfg)
+		scheme = "https"
+	}
+
+	serveErr := make(chan error, 1)
+	go func() { serveErr <- srv.Serve(ln) }()
+
+	// 端口已经可用后再注册，避免注册中心先探测到失败
+	deregister, err := s.register(addr, scheme)
+	if err != nil {
+		_ = srv.Close()
+		return err
+	}
+
+	sigCtx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	select {
+	case err := <-serveErr:
+		_ = deregister()
+		if errors.Is(err, http.ErrServerClosed) {
+			return nil
+		}
+		return err
+	case <-sigCtx.Done():
+		logger.Infof("mcp_server: %s received shutdown signal, draining", s.serviceName)
+	}
+	return s.shutdown(srv, deregister)
+}
+
+// shutdown 先从注册中心注销，让客户端不再选中本实例，再排空在途请求并退出
+// 排空期间继续监听：/health 返回 503，新的 MCP POST 请求直接返回 503
+func (s *HTTPServer) shutdown(srv *http.Server, deregister func() error) error {
+	s.draining.Store(true)
+
+	if err := deregister(); err != nil {
+		logger.Errorf("mcp_server: %s deregister failed: %v", s.serviceName, err)
+	} else {
+		logger.Infof("mcp_server: %s deregistered", s.serviceName)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), constant.MCPServerShutdownTimeout)
+	defer cancel()
+
+	// 等待在途的工具调用完成，然后结束 GET 监听流
+	drained := make(chan struct{})
+	go func() {
+		s.inflight.Wait()
+		close(drained)
+	}()
+	select {
+	case <-drained:
+	case <-ctx.Done():
+		logger.Warnf("mcp_server: %s drain timeout, closing remaining sessions", s.serviceName)
+	}
+	s.cancelBase()
+
+	// 关闭监听并等待连接空闲，超时后强制关闭
+	if err := srv.Shutdown(ctx); err != nil {
+		_ = srv.Close()
+	}
+	return nil
+}
+
+// register 按配置的注册中心注册自身，返回注销函数；未配置注册中心时为空操作
+func (s *HTTPServer) register(addr, scheme string) (func() error, error) {
+	if !factory.Enabled() {
+		return func() error { return nil }, nil
+	}
+	registrar, err := factory.NewRegistrar(s.serviceName)
+	if err != nil {
+		return nil, fmt.Errorf("mcp_server: %w", err)
+	}
+	id, _ := uuid.NewV7()
+	deregister, err := registrar.Register(context.Background(), &registry.Registration{
+		Service:   s.serviceName,
+		ID:        id.String(),
+		Address:   addr,
+		Port:      utils.AddrGetPort(addr),
+		Tags:      []string{constant.RegistryMCPTag},
+		Meta:      map[string]string{"addr": addr},
+		Scheme:    scheme,
+		Path:      constant.RegistryMCPDefaultPath,
+		HealthURL: scheme + "://" + addr + constant.MCPServerHealthPath,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("mcp_server: %s register failed: %w", config.Registry.Provider, err)
+	}
+	logger.Infof("%s : registered to %s successfully on %s://%s", s.serviceName, config.Registry.Provider, scheme, addr)
+	return deregister, nil
+}
+
+// trackInflight 统计在途的 POST 请求；GET 是长连接监听流，不计入
+func (s *HTTPServer) trackInflight(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if s.draining.Load() {
+			http.Error(w, "server is shutting down", http.StatusServiceUnavailable)
+			return
+		}
+		s.inflight.Add(1)
+		defer s.inflight.Done()
+		next.ServeHTTP(w, r)
+	})
+}
+
+// handleHealth 健康检查，退出流程中返回 503 让注册中心尽快摘除
+func (s *HTTPServer) handleHealth(w http.ResponseWriter, _ *http.Request) {
+	status, code := "ok", http.StatusOK
+	if s.draining.Load() {
+		status, code = "draining", http.StatusServiceUnavailable
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(map[string]string{"status": status, "service": s.serviceName})
+}
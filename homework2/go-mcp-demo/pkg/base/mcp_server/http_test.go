@@ -0,0 +1,104 @@
+package mcp_server
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/FantasyRL/go-mcp-demo/pkg/constant"
+)
+
+func TestShutdown(t *testing.T) {
+	baseCtx, cancel := context.WithCancel(context.Background())
+	s := &HTTPServer{serviceName: "mcp_test", baseCtx: baseCtx, cancelBase: cancel}
+
+	started, release := make(chan struct{}), make(chan struct{})
+	mux := http.NewServeMux()
+	mux.Handle(constant.RegistryMCPDefaultPath, s.trackInflight(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		w.WriteHeader(http.StatusOK)
+	})))
+	mux.HandleFunc(constant.MCPServerHealthPath, s.handleHealth)
+	srv := &http.Server{Handler: mux, BaseContext: func(net.Listener) context.Context { return s.baseCtx }}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() { _ = srv.Serve(ln) }()
+	base := "http://" + ln.Addr().String()
+	// 不复用连接：空闲池中从未发出请求的连接会让 Shutdown 多等 5s
+	cli := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+
+	resp, err := cli.Get(base + constant.MCPServerHealthPath)
+	if err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("health before shutdown: %v %v", resp, err)
+	}
+	_ = resp.Body.Close()
+
+	// 一个在途的工具调用
+	callDone := make(chan int, 1)
+	go func() {
+		resp, err := cli.Post(base+constant.RegistryMCPDefaultPath, "application/json", nil)
+		if err != nil {
+			callDone <- 0
+			return
+		}
+		_ = resp.Body.Close()
+		callDone <- resp.StatusCode
+	}()
+	<-started
+
+	deregistered := make(chan struct{})
+	shutdownDone := make(chan struct{})
+	go func() {
+		_ = s.shutdown(srv, func() error {
+			close(deregistered)
+			return nil
+		})
+		close(shutdownDone)
+	}()
+
+	// 注销发生在排空之前
+	select {
+	case <-deregistered:
+	case <-time.After(3 * time.Second):
+		t.Fatal("deregister should not wait for in-flight calls")
+	}
+
+	// 排空期间 /health 返回 503，新的调用被拒绝
+	resp, err = cli.Get(base + constant.MCPServerHealthPath)
+	if err != nil || resp.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("health while draining: %v %v", resp, err)
+	}
+	_ = resp.Body.Close()
+	resp, err = cli.Post(base+constant.RegistryMCPDefaultPath, "application/json", nil)
+	if err != nil || resp.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("new call while draining: %v %v", resp, err)
+	}
+	_ = resp.Body.Close()
+	select {
+	case <-shutdownDone:
+		t.Fatal("shutdown returned before in-flight call finished")
+	default:
+	}
+
+	close(release)
+	if code := <-callDone; code != http.StatusOK {
+		t.Fatalf("in-flight call: %d", code)
+	}
+	select {
+	case <-shutdownDone:
+	case <-time.After(5 * time.Second):
+		t.Fatal("shutdown did not finish after draining")
+	}
+	if baseCtx.Err() == nil {
+		t.Fatal("shutdown should cancel the base context")
+	}
+	if resp, err := cli.Get(base + constant.MCPServerHealthPath); err == nil {
+		_ = resp.Body.Close()
+		t.Fatal("listener should be closed after shutdown")
+	}
+}
@@ -1,16 +1,10 @@
 package mcp_server
 
 import (
-	"github.com/FantasyRL/go-mcp-demo/config"
+	"context"
 	"github.com/FantasyRL/go-mcp-demo/pkg/base/prompt_set"
-	"github.com/FantasyRL/go-mcp-demo/pkg/base/registry"
-	"github.com/FantasyRL/go-mcp-demo/pkg/base/registry/consul"
 	"github.com/FantasyRL/go-mcp-demo/pkg/base/tool_set"
 	"github.com/FantasyRL/go-mcp-demo/pkg/constant"
-	"github.com/FantasyRL/go-mcp-demo/pkg/logger"
-	"github.com/FantasyRL/go-mcp-demo/pkg/utils"
-	"github.com/google/uuid"
-	"log"
 	"time"
 
 	"github.com/mark3labs/mcp-go/server"
@@ -48,29 +42,17 @@ func NewCoreServer(name, version string, toolSet *tool_set.ToolSet, promptSet *p
 }
 
 // NewStreamableHTTPServer 基于核心 Server 创建StreamableHTTP服务器组件
-func NewStreamableHTTPServer(core *server.MCPServer, serviceName string, addr string) *server.StreamableHTTPServer {
-	switch config.Registry.Provider {
-	case constant.RegistryProviderConsul:
-		registrar := consul.NewRegistrar(serviceName)
-		id, _ := uuid.NewV7()
-		_, err := registrar.Register(&registry.Registration{
-			Service: serviceName,
-			ID:      id.String(),
-			Address: addr,
-			Port:    utils.AddrGetPort(addr),
-			Tags:    []string{constant.RegistryMCPTag},
-			Meta:    map[string]string{"addr": addr},
-			Path:    constant.RegistryMCPDefaultPath,
-		})
-		if err != nil {
-			log.Fatal("mcp_server: consul register failed, err: " + err.Error())
-		}
-		logger.Infof("%s : registered to consul successfully on %s", serviceName, addr)
-	default:
-	}
+// 注册中心的注册/注销、/health 健康检查与信号处理都在 HTTPServer.Start 中完成
+func NewStreamableHTTPServer(core *server.MCPServer, serviceName string, addr string) *HTTPServer {
 	var httpOpts []server.StreamableHTTPOption
 	httpOpts = append(httpOpts, server.WithHeartbeatInterval(constant.MCPServerHeartbeatInterval))
-	return server.NewStreamableHTTPServer(core, httpOpts...)
+	baseCtx, cancel := context.WithCancel(context.Background())
+	return &HTTPServer{
+		serviceName: serviceName,
+		mcp:         server.NewStreamableHTTPServer(core, httpOpts...),
+		baseCtx:     baseCtx,
+		cancelBase:  cancel,
+	}
 }
 
 // ServeStdio stdio
@@ -0,0 +1,48 @@
+package mcp_server
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http"
+
+	"github.com/FantasyRL/go-mcp-demo/config"
+	"github.com/FantasyRL/go-mcp-demo/pkg/utils"
+)
+
+// serverTLSConfig 按 config.MCP.TLS 构建服务端 TLS 配置，未配置证书时返回 nil（明文 HTTP）
+func serverTLSConfig() (*tls.Config, error) {
+	c := config.MCP.TLS
+	if c.CertFile == "" && c.KeyFile == "" {
+		return nil, nil
+	}
+	cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("mcp_server tls: load cert: %w", err)
+	}
+	tlsCfg := &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{cert},
+	}
+	if c.ClientCAFile != "" {
+		pool, err := utils.LoadCertPool(c.ClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("mcp_server tls: %w", err)
+		}
+		tlsCfg.ClientCAs = pool
+		// 握手阶段只校验客户端提供的证书，是否必须提供由 requireClientCert 按路由决定，
+		// 这样注册中心的 /health 检查不需要客户端证书
+		tlsCfg.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+	return tlsCfg, nil
+}
+
+// requireClientCert mTLS 模式下拒绝未提供客户端证书的请求
+func requireClientCert(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 {
+			http.Error(w, "client certificate required", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,194 @@
+package mcp_server
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/FantasyRL/go-mcp-demo/config"
+	"github.com/FantasyRL/go-mcp-demo/pkg/constant"
+)
+
+// testCA 测试用的自签名 CA
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newTestCA(t *testing.T, name string) *testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	return &testCA{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+// issue 签发叶子证书，返回 PEM 编码的证书和私钥
+func (ca *testCA) issue(t *testing.T, name string, usage x509.ExtKeyUsage) (certPEM, keyPEM []byte) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+func writeFile(t *testing.T, dir, name string, data []byte) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestMutualTLS(t *testing.T) {
+	dir := t.TempDir()
+	ca, otherCA := newTestCA(t, "mcp-ca"), newTestCA(t, "other-ca")
+	serverCert, serverKey := ca.issue(t, "mcp-server", x509.ExtKeyUsageServerAuth)
+	clientCert, clientKey := ca.issue(t, "mcp-host", x509.ExtKeyUsageClientAuth)
+	strangerCert, strangerKey := otherCA.issue(t, "stranger", x509.ExtKeyUsageClientAuth)
+
+	cfg := &config.Config{}
+	old := config.MCP
+	config.MCP = &cfg.MCP
+	t.Cleanup(func() { config.MCP = old })
+	config.MCP.TLS.CertFile = writeFile(t, dir, "server.pem", serverCert)
+	config.MCP.TLS.KeyFile = writeFile(t, dir, "server.key", serverKey)
+	config.MCP.TLS.ClientCAFile = writeFile(t, dir, "ca.pem", ca.pem)
+
+	tlsCfg, err := serverTLSConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tlsCfg.ClientAuth != tls.VerifyClientCertIfGiven {
+		t.Fatalf("client auth = %v", tlsCfg.ClientAuth)
+	}
+
+	// 与 Start 相同：只有 MCP 路由要求客户端证书
+	mux := http.NewServeMux()
+	mux.Handle(constant.RegistryMCPDefaultPath, requireClientCert(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})))
+	mux.HandleFunc(constant.MCPServerHealthPath, func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) })
+	srv := httptest.NewUnstartedServer(mux)
+	srv.TLS = tlsCfg
+	srv.StartTLS()
+	t.Cleanup(srv.Close)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	newClient := func(certPEM, keyPEM []byte) *http.Client {
+		c := &tls.Config{RootCAs: roots}
+		if certPEM != nil {
+			pair, err := tls.X509KeyPair(certPEM, keyPEM)
+			if err != nil {
+				t.Fatal(err)
+			}
+			c.Certificates = []tls.Certificate{pair}
+		}
+		return &http.Client{Transport: &http.Transport{TLSClientConfig: c, DisableKeepAlives: true}}
+	}
+	get := func(cli *http.Client, path string) (int, error) {
+		resp, err := cli.Get(srv.URL + path)
+		if err != nil {
+			return 0, err
+		}
+		_ = resp.Body.Close()
+		return resp.StatusCode, nil
+	}
+
+	anonymous := newClient(nil, nil)
+	// 健康检查不需要客户端证书
+	if code, err := get(anonymous, constant.MCPServerHealthPath); err != nil || code != http.StatusOK {
+		t.Fatalf("health without client cert: %d %v", code, err)
+	}
+	if code, err := get(anonymous, constant.RegistryMCPDefaultPath); err != nil || code != http.StatusUnauthorized {
+		t.Fatalf("mcp without client cert: %d %v", code, err)
+	}
+	if code, err := get(newClient(clientCert, clientKey), constant.RegistryMCPDefaultPath); err != nil || code != http.StatusOK {
+		t.Fatalf("mcp with client cert: %d %v", code, err)
+	}
+	// 其他 CA 签发的证书不被接受：客户端不会发送，或在握手阶段被拒绝
+	if code, err := get(newClient(strangerCert, strangerKey), constant.RegistryMCPDefaultPath); err == nil && code != http.StatusUnauthorized {
+		t.Fatalf("client cert from an untrusted CA should be rejected: %d", code)
+	}
+	// 客户端不信任服务端证书时握手失败
+	untrusting := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+	if _, err := get(untrusting, constant.MCPServerHealthPath); err == nil {
+		t.Fatal("server cert should not be trusted without the CA")
+	}
+}
+
+func TestServerTLSConfig(t *testing.T) {
+	cfg := &config.Config{}
+	old := config.MCP
+	config.MCP = &cfg.MCP
+	t.Cleanup(func() { config.MCP = old })
+
+	// 未配置证书时使用明文 HTTP
+	if tlsCfg, err := serverTLSConfig(); err != nil || tlsCfg != nil {
+		t.Fatalf("plain http: %v %v", tlsCfg, err)
+	}
+
+	dir := t.TempDir()
+	ca := newTestCA(t, "mcp-ca")
+	cert, key := ca.issue(t, "mcp-server", x509.ExtKeyUsageServerAuth)
+	config.MCP.TLS.CertFile = writeFile(t, dir, "server.pem", cert)
+	config.MCP.TLS.KeyFile = writeFile(t, dir, "server.key", key)
+	tlsCfg, err := serverTLSConfig()
+	if err != nil || tlsCfg == nil || tlsCfg.ClientCAs != nil {
+		t.Fatalf("https without mtls: %v %v", tlsCfg, err)
+	}
+
+	config.MCP.TLS.ClientCAFile = filepath.Join(dir, "missing.pem")
+	if _, err := serverTLSConfig(); err == nil {
+		t.Fatal("missing client ca file should fail")
+	}
+}
@@ -4,15 +4,16 @@ import (
 	"github.com/FantasyRL/go-mcp-demo/config"
 	"github.com/FantasyRL/go-mcp-demo/pkg/base/ai_provider"
 	"github.com/FantasyRL/go-mcp-demo/pkg/base/mcp_client"
-	"github.com/FantasyRL/go-mcp-demo/pkg/base/registry/consul"
+	"github.com/FantasyRL/go-mcp-demo/pkg/base/registry/factory"
 	"github.com/FantasyRL/go-mcp-demo/pkg/constant"
 	"log"
 )
 
 // WithMCPClient 通过配置手动注入初始化 ClientSet.MCPCli。
 // - stdio: 直接创建单连接客户端（本地进程/stdio）
-// - none(单点): 使用 config.MCP.HTTP.BaseURL 创建单连接客户端
-// - consul: 创建聚合客户端（基于 Consul Resolver 定时刷新，自动发现多实例）
+// - none/空(单点): 使用 config.MCP.HTTP.BaseURL 创建单连接客户端
+// - consul/etcd/nacos: 创建聚合客户端（基于 registry/factory 创建的 Resolver，自动发现多实例）
+// 工具调用统一经过 Sentinel（资源 "mcp" 与 "mcp:<工具名>"）
 func WithMCPClient(services []string) Option {
 	return func(clientSet *ClientSet) {
 		switch {
@@ -24,8 +25,8 @@ func WithMCPClient(services []string) Option {
 			}
 			clientSet.MCPCli = mcpCli
 
-		// 单点通信：直接使用配置的 BaseURL
-		case config.Registry.Provider == constant.RegistryProviderNone:
+		// 单点通信（registry.provider 为空或 none）：直接使用配置的 BaseURL
+		case !factory.Enabled():
 			if config.MCP.HTTP.BaseURL == "" {
 				log.Fatalf("missing MCP HTTP BaseURL while registry provider is 'none'")
 			}
@@ -35,20 +36,18 @@ func WithMCPClient(services []string) Option {
 			}
 			clientSet.MCPCli = mcpCli
 
-		// 服务发现（Consul）：使用聚合客户端，多路连接 + 定时刷新
-		case config.Registry.Provider == constant.RegistryProviderConsul:
-			resolver := consul.NewResolver()
-			if resolver == nil {
-				log.Fatalf("consul config invalid, can't create MCP client")
+		// 服务发现：使用聚合客户端，多路连接 + 定时刷新/订阅，具体注册中心由 factory 按配置选择
+		default:
+			resolver, err := factory.NewResolver()
+			if err != nil {
+				log.Fatalf("can't create MCP client: %s", err)
 			}
-			ac := mcp_client.NewAggregatedClient(resolver, services) // 可按需调整刷新周期
+			ac := mcp_client.NewAggregatedClient(resolver, services)
 			clientSet.RegistryResolver = resolver
 			clientSet.MCPCli = ac
 			clientSet.cleanups = append(clientSet.cleanups, ac.Close)
-
-		default:
-			log.Fatalf("unknown registry provider: %s, can't create MCP client", config.Registry.Provider)
 		}
+		clientSet.MCPCli = mcp_client.WithGuard(clientSet.MCPCli)
 	}
 }
 
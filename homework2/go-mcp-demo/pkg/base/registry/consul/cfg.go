@@ -8,4 +8,7 @@ type ConsulConfig struct {
 	Tag        string // 可空，按 tag 过滤
 	Scheme     string // http / https，默认 http
 	Path       string // 例如 "/mcp"，默认 "/mcp"
+	Check      string // 健康检查方式 http / tcp / ttl，默认 http
+
+	TLSSkipVerify bool // HTTPS 健康检查跳过证书校验，默认 false
 }
@@ -1,11 +1,15 @@
 package consul
 
 import (
+	"context"
 	"fmt"
 	"github.com/FantasyRL/go-mcp-demo/config"
 	"github.com/FantasyRL/go-mcp-demo/pkg/base/registry"
 	"github.com/FantasyRL/go-mcp-demo/pkg/constant"
+	"github.com/FantasyRL/go-mcp-demo/pkg/logger"
 	"github.com/hashicorp/consul/api"
+	"sync"
+	"time"
 )
 
 type Registrar struct {
@@ -21,6 +25,9 @@ func NewRegistrar(serviceName string) *Registrar {
 		Service:    serviceName,
 		Scheme:     config.Registry.Consul.Scheme,
 		Path:       config.Registry.Consul.Path,
+		Check:      config.Registry.Consul.Check,
+
+		TLSSkipVerify: config.Registry.Consul.TLSSkipVerify,
 	}
 	if cfg.Address == "" {
 		return nil
@@ -29,7 +36,7 @@ func NewRegistrar(serviceName string) *Registrar {
 }
 
 // Register 注册服务实例，用于mcp_server将自己注册到consul
-func (r *Registrar) Register(reg *registry.Registration) (func() error, error) {
+func (r *Registrar) Register(ctx context.Context, reg *registry.Registration) (func() error, error) {
 	// 创建 Consul 客户端配置
 	conf := api.DefaultConfig()
 	conf.Address = r.cfg.Address
@@ -55,26 +62,98 @@ func (r *Registrar) Register(reg *registry.Registration) (func() error, error) {
 		deregister = constant.RegistryDeregisterAfter
 	}
 
+	check := &api.AgentServiceCheck{
+		CheckID: reg.ID,
+		// 自动注销时间
+		DeregisterCriticalServiceAfter: deregister.String(),
+	}
+	checkType := reg.CheckType
+	if checkType == "" {
+		checkType = r.cfg.Check
+	}
+	if checkType == "" {
+		checkType = constant.RegistryCheckTypeHTTP
+	}
+	if checkType == constant.RegistryCheckTypeHTTP && reg.HealthURL == "" {
+		checkType = constant.RegistryCheckTypeTCP
+	}
+	switch checkType {
+	case constant.RegistryCheckTypeTTL:
+		// 由服务自己按 interval 上报心跳，超过 ttl 未上报即视为不健康
+		check.TTL = (interval * constant.RegistryTTLMultiplier).String()
+	case constant.RegistryCheckTypeTCP:
+		check.TCP = reg.Address
+		// 心跳间隔
+		check.Interval = interval.String()
+	default:
+		check.HTTP = reg.HealthURL
+		check.Method = "GET"
+		check.Interval = interval.String()
+		// 默认校验证书，自签名证书且 agent 无法信任时由配置显式关闭
+		check.TLSSkipVerify = r.cfg.TLSSkipVerify
+	}
+
+	// scheme/path 写入 Meta，供 Resolver 拼出完整 URL
+	meta := make(map[string]string, len(reg.Meta)+2)
+	for k, v := range reg.Meta {
+		meta[k] = v
+	}
+	if reg.Scheme != "" {
+		meta["scheme"] = reg.Scheme
+	}
+	if reg.Path != "" {
+		meta["path"] = reg.Path
+	}
+
 	asr := &api.AgentServiceRegistration{
 		ID:      reg.ID,      // 唯一ID
 		Name:    reg.Service, // 服务名称（同一服务下可能有多个实例）
 		Address: reg.Address,
 		Port:    reg.Port,
 		Tags:    reg.Tags, // 标签，用于标识环境/版本/分区等
-		Meta:    reg.Meta, // 元信息，可存储额外属性，这里存储了url
-		Check: &api.AgentServiceCheck{
-			TCP:     fmt.Sprintf("%s", reg.Address),
-			CheckID: reg.ID,
-			// 心跳间隔
-			Interval: interval.String(),
-			// 自动注销时间
-			DeregisterCriticalServiceAfter: deregister.String(),
-		},
-	}
-	if err := cl.Agent().ServiceRegister(asr); err != nil {
+		Meta:    meta,     // 元信息，可存储额外属性，这里存储了 addr/scheme/path
+		Check:   check,
+	}
+	if err := cl.Agent().ServiceRegisterOpts(asr, api.ServiceRegisterOpts{}.WithContext(ctx)); err != nil {
 		return nil, fmt.Errorf("consul register: %w", err)
 	}
+
+	stop := func() {}
+	if checkType == constant.RegistryCheckTypeTTL {
+		stop = startTTLHeartbeat(cl, reg.ID, interval)
+	}
+	var once sync.Once
 	return func() error {
-		return cl.Agent().ServiceDeregister(asr.ID)
+		var err error
+		once.Do(func() {
+			stop()
+			err = cl.Agent().ServiceDeregister(asr.ID)
+		})
+		return err
 	}, nil
 }
+
+// startTTLHeartbeat 定时上报 TTL 检查为 passing，返回停止函数
+func startTTLHeartbeat(cl *api.Client, checkID string, interval time.Duration) func() {
+	done := make(chan struct{})
+	report := func() {
+		if err := cl.Agent().UpdateTTL(checkID, "ok", api.HealthPassing); err != nil {
+			logger.Errorf("consul ttl heartbeat %s: %v", checkID, err)
+		}
+	}
+	go func() {
+		report()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				report()
+			case <-done:
+				return
+			}
+		}
+	}()
+	var once sync.Once
+	return func() { once.Do(func() { close(done) }) }
+}
@@ -1,9 +1,14 @@
 package consul
 
 import (
+	"context"
 	"fmt"
+	"github.com/FantasyRL/go-mcp-demo/pkg/base/registry"
+	"github.com/FantasyRL/go-mcp-demo/pkg/constant"
 	"github.com/FantasyRL/go-mcp-demo/pkg/logger"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/FantasyRL/go-mcp-demo/config"
 	"github.com/hashicorp/consul/api"
@@ -11,6 +16,11 @@ import (
 
 type Resolver struct {
 	cfg *ConsulConfig
+
+	// client 在多次 Resolve/Watch 之间复用
+	clientOnce sync.Once
+	client     *api.Client
+	clientErr  error
 }
 
 func NewResolver() *Resolver {
@@ -30,7 +40,26 @@ func NewResolver() *Resolver {
 	}
 }
 
-// Resolve 返回所有通过健康检查的实例 URL
+// getClient 懒加载并复用 Consul 客户端
+func (r *Resolver) getClient() (*api.Client, error) {
+	r.clientOnce.Do(func() {
+		conf := api.DefaultConfig()
+		conf.Address = r.cfg.Address
+		if r.cfg.Datacenter != "" {
+			conf.Datacenter = r.cfg.Datacenter
+		}
+		if r.cfg.Token != "" {
+			conf.Token = r.cfg.Token
+		}
+		r.client, r.clientErr = api.NewClient(conf)
+		if r.clientErr != nil {
+			r.clientErr = fmt.Errorf("consul client: %w", r.clientErr)
+		}
+	})
+	return r.client, r.clientErr
+}
+
+// Resolve 返回所有通过健康检查的实例完整 URL
 func (r *Resolver) Resolve(services []string) (map[string][]string, error) {
 	// 基本校验
 	if len(services) == 0 {
@@ -40,18 +69,9 @@ func (r *Resolver) Resolve(services []string) (map[string][]string, error) {
 		return nil, fmt.Errorf("consul: empty address")
 	}
 
-	// 构造 Consul 客户端
-	conf := api.DefaultConfig()
-	conf.Address = r.cfg.Address
-	if r.cfg.Datacenter != "" {
-		conf.Datacenter = r.cfg.Datacenter
-	}
-	if r.cfg.Token != "" {
-		conf.Token = r.cfg.Token
-	}
-	cl, err := api.NewClient(conf)
+	cl, err := r.getClient()
 	if err != nil {
-		return nil, fmt.Errorf("consul client: %w", err)
+		return nil, err
 	}
 
 	q := &api.QueryOptions{
@@ -71,15 +91,9 @@ func (r *Resolver) Resolve(services []string) (map[string][]string, error) {
 			return nil, fmt.Errorf("consul discover %q: %w", svc, err)
 		}
 		// 没有健康实例不视为整体错误，继续查下一个服务
-		for _, inst := range entries {
-			// 使用注册时写入的完整 addr
-			if inst.Service != nil && inst.Service.Meta != nil {
-				if u := strings.TrimSpace(inst.Service.Meta["addr"]); u != "" {
-					out[svc] = append(out[svc], u)
-				} else {
-					logger.Errorf("consul: service %s no metadata", inst.Service.Service)
-				}
-			}
+		out[svc] = append(out[svc], r.instanceURLs(entries)...)
+		if len(out[svc]) == 0 {
+			delete(out, svc)
 		}
 	}
 	if len(out) == 0 {
@@ -87,3 +101,105 @@ func (r *Resolver) Resolve(services []string) (map[string][]string, error) {
 	}
 	return out, nil
 }
+
+// Watch 使用 Consul blocking query 监听服务健康实例的变化，每个服务一个 goroutine
+func (r *Resolver) Watch(ctx context.Context, services []string, onEvent func(registry.Event)) error {
+	if len(services) == 0 {
+		return fmt.Errorf("no Services provided")
+	}
+	cl, err := r.getClient()
+	if err != nil {
+		return err
+	}
+
+	var wg sync.WaitGroup
+	for _, svc := range services {
+		if svc == "" {
+			continue
+		}
+		wg.Add(1)
+		go func(svc string) {
+			defer wg.Done()
+			r.watchService(ctx, cl, svc, onEvent)
+		}(svc)
+	}
+	wg.Wait()
+	return ctx.Err()
+}
+
+// watchService 对单个服务循环发起阻塞查询，把前后两次结果的差异转换为增量事件
+func (r *Resolver) watchService(ctx context.Context, cl *api.Client, svc string, onEvent func(registry.Event)) {
+	var (
+		index   uint64
+		current = map[string]struct{}{}
+		backoff = constant.RegistryWatchRetryMin
+	)
+	for ctx.Err() == nil {
+		q := (&api.QueryOptions{
+			Datacenter: r.cfg.Datacenter,
+			Token:      r.cfg.Token,
+			WaitIndex:  index,
+			WaitTime:   constant.RegistryWatchWaitTime,
+		}).WithContext(ctx)
+		entries, meta, err := cl.Health().Service(svc, r.cfg.Tag, true, q)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			logger.Errorf("consul watch %q: %v, retry in %s", svc, err, backoff)
+			select {
+			case <-time.After(backoff):
+			case <-ctx.Done():
+				return
+			}
+			backoff = min(backoff*2, constant.RegistryWatchRetryMax)
+			continue
+		}
+		backoff = constant.RegistryWatchRetryMin
+
+		// Consul 要求 index 回退时重置，否则可能一直阻塞
+		if meta.LastIndex < index {
+			index = 0
+		} else {
+			index = meta.LastIndex
+		}
+
+		next := make(map[string]struct{})
+		for _, u := range r.instanceURLs(entries) {
+			next[u] = struct{}{}
+			if _, ok := current[u]; !ok {
+				onEvent(registry.Event{Type: registry.EventAdd, Service: svc, URL: u})
+			}
+		}
+		for u := range current {
+			if _, ok := next[u]; !ok {
+				onEvent(registry.Event{Type: registry.EventRemove, Service: svc, URL: u})
+			}
+		}
+		current = next
+	}
+}
+
+// instanceURLs 由注册时写入 Meta 的 addr/scheme/path 拼出实例完整 URL
+// Meta 中没有 scheme/path 时使用 consul 配置中的 scheme/path
+func (r *Resolver) instanceURLs(entries []*api.ServiceEntry) []string {
+	var out []string
+	for _, inst := range entries {
+		if inst.Service != nil && inst.Service.Meta != nil {
+			meta := inst.Service.Meta
+			if u := strings.TrimSpace(meta["addr"]); u != "" {
+				scheme, path := meta["scheme"], meta["path"]
+				if scheme == "" {
+					scheme = r.cfg.Scheme
+				}
+				if path == "" {
+					path = r.cfg.Path
+				}
+				out = append(out, registry.InstanceURL(scheme, u, path))
+			} else {
+				logger.Errorf("consul: service %s no metadata", inst.Service.Service)
+			}
+		}
+	}
+	return out
+}
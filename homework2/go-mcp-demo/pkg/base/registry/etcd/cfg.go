@@ -0,0 +1,29 @@
+package etcd
+
+import (
+	"time"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.uber.org/zap"
+)
+
+type EtcdConfig struct {
+	Endpoints   []string      // 如 http://127.0.0.1:2379，可多个
+	Username    string        // 可空，开启 auth 时填写
+	Password    string        // 可空
+	Prefix      string        // key 前缀，默认 /go-mcp-demo/services
+	LeaseTTL    time.Duration // 注册租约 TTL，默认 10s
+	DialTimeout time.Duration // 建连与单次请求超时，默认 5s
+}
+
+// newClient 创建 etcd v3 客户端；建连是异步的，etcd 暂时不可用时不会阻塞启动
+func newClient(cfg *EtcdConfig) (*clientv3.Client, error) {
+	return clientv3.New(clientv3.Config{
+		Endpoints:   cfg.Endpoints,
+		Username:    cfg.Username,
+		Password:    cfg.Password,
+		DialTimeout: cfg.DialTimeout,
+		// 客户端内部的重连日志较多，错误由调用方记录
+		Logger: zap.NewNop(),
+	})
+}
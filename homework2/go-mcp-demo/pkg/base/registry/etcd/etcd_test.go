@@ -0,0 +1,230 @@
+package etcd
+
+import (
+	"context"
+	"net"
+	"net/url"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/FantasyRL/go-mcp-demo/pkg/base/registry"
+	"go.etcd.io/etcd/server/v3/embed"
+)
+
+// startEtcd 启动一个单节点的内嵌 etcd，返回客户端地址
+func startEtcd(t *testing.T) string {
+	t.Helper()
+	cfg := embed.NewConfig()
+	cfg.Dir = t.TempDir()
+	cfg.LogLevel = "error"
+	clientURL, peerURL := freeURL(t), freeURL(t)
+	cfg.ListenClientUrls = []url.URL{clientURL}
+	cfg.AdvertiseClientUrls = []url.URL{clientURL}
+	cfg.ListenPeerUrls = []url.URL{peerURL}
+	cfg.AdvertisePeerUrls = []url.URL{peerURL}
+	cfg.InitialCluster = cfg.Name + "=" + peerURL.String()
+
+	e, err := embed.StartEtcd(cfg)
+	if err != nil {
+		t.Fatalf("start etcd: %v", err)
+	}
+	t.Cleanup(e.Close)
+	select {
+	case <-e.Server.ReadyNotify():
+	case <-time.After(10 * time.Second):
+		t.Fatal("etcd not ready")
+	}
+	return clientURL.String()
+}
+
+func freeURL(t *testing.T) url.URL {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	return url.URL{Scheme: "http", Host: ln.Addr().String()}
+}
+
+func newTestRegistrar(t *testing.T, endpoint string, ttl time.Duration) *Registrar {
+	t.Helper()
+	cfg := withDefaults(&EtcdConfig{Endpoints: []string{endpoint}, LeaseTTL: ttl})
+	cli, err := newClient(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = cli.Close() })
+	return &Registrar{cfg: cfg, cli: cli}
+}
+
+func newTestResolver(t *testing.T, endpoint string) *Resolver {
+	t.Helper()
+	cfg := withDefaults(&EtcdConfig{Endpoints: []string{endpoint}})
+	cli, err := newClient(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = cli.Close() })
+	return &Resolver{cfg: cfg, cli: cli}
+}
+
+func register(t *testing.T, r *Registrar, service, id, addr string) func() error {
+	t.Helper()
+	deregister, err := r.Register(context.Background(), &registry.Registration{
+		Service: service,
+		ID:      id,
+		Meta:    map[string]string{"addr": addr},
+	})
+	if err != nil {
+		t.Fatalf("register %s: %v", id, err)
+	}
+	return deregister
+}
+
+func TestRegisterAndResolve(t *testing.T) {
+	endpoint := startEtcd(t)
+	registrar, resolver := newTestRegistrar(t, endpoint, 5*time.Second), newTestResolver(t, endpoint)
+
+	register(t, registrar, "mcp_local", "a", "127.0.0.1:10002")
+	deregister := register(t, registrar, "mcp_local", "b", "127.0.0.1:10003")
+	if _, err := registrar.Register(context.Background(), &registry.Registration{
+		Service: "mcp_remote",
+		ID:      "c",
+		Meta:    map[string]string{"addr": "127.0.0.1:10004"},
+		Scheme:  "https",
+		Path:    "/rpc",
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := resolver.Resolve([]string{"mcp_local", "mcp_remote"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(got["mcp_local"])
+	if strings.Join(got["mcp_local"], ",") != "http://127.0.0.1:10002/mcp,http://127.0.0.1:10003/mcp" {
+		t.Fatalf("mcp_local = %v", got["mcp_local"])
+	}
+	if len(got["mcp_remote"]) != 1 || got["mcp_remote"][0] != "https://127.0.0.1:10004/rpc" {
+		t.Fatalf("mcp_remote = %v", got["mcp_remote"])
+	}
+
+	// 注销会撤销租约，key 随之删除
+	if err := deregister(); err != nil {
+		t.Fatal(err)
+	}
+	got, err = resolver.Resolve([]string{"mcp_local"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got["mcp_local"]) != 1 || got["mcp_local"][0] != "http://127.0.0.1:10002/mcp" {
+		t.Fatalf("after deregister mcp_local = %v", got["mcp_local"])
+	}
+}
+
+func TestWatch(t *testing.T) {
+	endpoint := startEtcd(t)
+	registrar, resolver := newTestRegistrar(t, endpoint, 5*time.Second), newTestResolver(t, endpoint)
+	register(t, registrar, "mcp_local", "a", "127.0.0.1:10002")
+
+	events := make(chan registry.Event, 8)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = resolver.Watch(ctx, []string{"mcp_local"}, func(ev registry.Event) { events <- ev }) }()
+
+	expect := func(typ registry.EventType, url string) {
+		t.Helper()
+		select {
+		case ev := <-events:
+			if ev.Type != typ || ev.URL != url || ev.Service != "mcp_local" {
+				t.Fatalf("got event %+v, want type=%d url=%s", ev, typ, url)
+			}
+		case <-time.After(10 * time.Second):
+			t.Fatalf("timeout waiting for event type=%d url=%s", typ, url)
+		}
+	}
+
+	// 首次全量同步
+	expect(registry.EventAdd, "http://127.0.0.1:10002/mcp")
+
+	deregister := register(t, registrar, "mcp_local", "b", "127.0.0.1:10003")
+	expect(registry.EventAdd, "http://127.0.0.1:10003/mcp")
+
+	// 其他服务的变化不应推送
+	register(t, registrar, "mcp_remote", "c", "127.0.0.1:10004")
+
+	// 同一实例的地址变化拆成下线 + 上线
+	if _, err := registrar.cli.Put(context.Background(), instanceKey(registrar.cfg.Prefix, "mcp_local", "a"), `{"addr":"127.0.0.1:10005"}`); err != nil {
+		t.Fatal(err)
+	}
+	expect(registry.EventRemove, "http://127.0.0.1:10002/mcp")
+	expect(registry.EventAdd, "http://127.0.0.1:10005/mcp")
+
+	if err := deregister(); err != nil {
+		t.Fatal(err)
+	}
+	expect(registry.EventRemove, "http://127.0.0.1:10003/mcp")
+}
+
+func TestLeaseExpiresWhenProcessDies(t *testing.T) {
+	endpoint := startEtcd(t)
+	registrar, resolver := newTestRegistrar(t, endpoint, 2*time.Second), newTestResolver(t, endpoint)
+	register(t, registrar, "mcp_local", "a", "127.0.0.1:10002")
+
+	events := make(chan registry.Event, 8)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = resolver.Watch(ctx, []string{"mcp_local"}, func(ev registry.Event) { events <- ev }) }()
+	<-events
+
+	// 续约 2 个 TTL 后实例仍然存在
+	time.Sleep(4 * time.Second)
+	if got, err := resolver.Resolve([]string{"mcp_local"}); err != nil || len(got["mcp_local"]) != 1 {
+		t.Fatalf("lease should be kept alive: %v %v", got, err)
+	}
+
+	// 模拟进程异常退出：连接断开后不再续约，租约到期删除 key
+	_ = registrar.cli.Close()
+	select {
+	case ev := <-events:
+		if ev.Type != registry.EventRemove {
+			t.Fatalf("got event %+v", ev)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("instance was not removed after lease expired")
+	}
+}
+
+func TestKeepAliveReRegistersLostLease(t *testing.T) {
+	endpoint := startEtcd(t)
+	registrar, resolver := newTestRegistrar(t, endpoint, 2*time.Second), newTestResolver(t, endpoint)
+	deregister := register(t, registrar, "mcp_local", "a", "127.0.0.1:10002")
+	defer deregister()
+
+	// 模拟租约丢失（如 etcd 数据被清理）：从另一个客户端撤销租约
+	leases, err := resolver.cli.Leases(context.Background())
+	if err != nil || len(leases.Leases) != 1 {
+		t.Fatalf("leases: %v %v", leases, err)
+	}
+	if _, err := resolver.cli.Revoke(context.Background(), leases.Leases[0].ID); err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(10 * time.Second)
+	for time.Now().Before(deadline) {
+		if got, err := resolver.Resolve([]string{"mcp_local"}); err == nil && len(got["mcp_local"]) == 1 {
+			if err := deregister(); err != nil {
+				t.Fatalf("deregister with the new lease: %v", err)
+			}
+			if _, err := resolver.Resolve([]string{"mcp_local"}); err == nil {
+				t.Fatal("instance should be removed after deregister")
+			}
+			return
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	t.Fatal("instance was not re-registered after lease loss")
+}
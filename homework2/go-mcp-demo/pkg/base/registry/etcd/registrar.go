@@ -0,0 +1,180 @@
+package etcd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/FantasyRL/go-mcp-demo/config"
+	"github.com/FantasyRL/go-mcp-demo/pkg/base/registry"
+	"github.com/FantasyRL/go-mcp-demo/pkg/constant"
+	"github.com/FantasyRL/go-mcp-demo/pkg/logger"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+type Registrar struct {
+	cfg *EtcdConfig
+	cli *clientv3.Client
+}
+
+func NewRegistrar() *Registrar {
+	cfg := loadConfig()
+	if len(cfg.Endpoints) == 0 {
+		return nil
+	}
+	cli, err := newClient(cfg)
+	if err != nil {
+		logger.Errorf("etcd registrar: %v", err)
+		return nil
+	}
+	return &Registrar{cfg: cfg, cli: cli}
+}
+
+// instance 写入 etcd 的实例信息，字段与 consul 的 Meta 保持一致
+type instance struct {
+	Addr   string            `json:"addr"`
+	Scheme string            `json:"scheme,omitempty"`
+	Path   string            `json:"path,omitempty"`
+	Tags   []string          `json:"tags,omitempty"`
+	Meta   map[string]string `json:"meta,omitempty"`
+}
+
+// Register 以租约方式注册实例：key 绑定租约，后台持续续约；进程异常退出时租约到期自动删除
+func (r *Registrar) Register(ctx context.Context, reg *registry.Registration) (func() error, error) {
+	addr := reg.Meta["addr"]
+	if addr == "" {
+		addr = reg.Address
+	}
+	value, err := json.Marshal(instance{
+		Addr:   addr,
+		Scheme: reg.Scheme,
+		Path:   reg.Path,
+		Tags:   reg.Tags,
+		Meta:   reg.Meta,
+	})
+	if err != nil {
+		return nil, err
+	}
+	key := instanceKey(r.cfg.Prefix, reg.Service, reg.ID)
+
+	id, err := r.putWithLease(ctx, key, string(value))
+	if err != nil {
+		return nil, fmt.Errorf("etcd register: %w", err)
+	}
+	var lease atomic.Int64
+	lease.Store(int64(id))
+
+	kaCtx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		r.keepAlive(kaCtx, key, string(value), &lease)
+	}()
+
+	var once sync.Once
+	return func() error {
+		var err error
+		once.Do(func() {
+			cancel()
+			<-done
+			revokeCtx, cancelRevoke := context.WithTimeout(context.Background(), r.cfg.DialTimeout)
+			defer cancelRevoke()
+			// 撤销租约会同时删除绑定的 key
+			_, err = r.cli.Revoke(revokeCtx, clientv3.LeaseID(lease.Load()))
+		})
+		return err
+	}, nil
+}
+
+func (r *Registrar) putWithLease(ctx context.Context, key, value string) (clientv3.LeaseID, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.cfg.DialTimeout)
+	defer cancel()
+	lease, err := r.cli.Grant(ctx, int64(r.cfg.LeaseTTL/time.Second))
+	if err != nil {
+		return 0, err
+	}
+	if _, err := r.cli.Put(ctx, key, value, clientv3.WithLease(lease.ID)); err != nil {
+		return 0, err
+	}
+	return lease.ID, nil
+}
+
+// keepAlive 通过 KeepAlive 流持续续约；流结束说明租约已丢失（如 etcd 重启、网络中断超过 TTL），
+// 此时重新申请租约并写入，直到 ctx 结束
+func (r *Registrar) keepAlive(ctx context.Context, key, value string, lease *atomic.Int64) {
+	backoff := constant.RegistryWatchRetryMin
+	for {
+		ch, err := r.cli.KeepAlive(ctx, clientv3.LeaseID(lease.Load()))
+		if err == nil {
+			for range ch {
+				// 续约应答，不需要处理
+			}
+		}
+		// ctx 结束或客户端已关闭（Close）时不再重新注册
+		if ctx.Err() != nil || r.cli.Ctx().Err() != nil {
+			return
+		}
+		logger.Warnf("etcd lease %x for %s lost (err=%v), re-registering", lease.Load(), key, err)
+		id, err := r.putWithLease(ctx, key, value)
+		if err != nil {
+			if ctx.Err() != nil || r.cli.Ctx().Err() != nil {
+				return
+			}
+			logger.Errorf("etcd re-register %s: %v, retry in %s", key, err, backoff)
+			select {
+			case <-time.After(backoff):
+			case <-ctx.Done():
+				return
+			}
+			backoff = min(backoff*2, constant.RegistryWatchRetryMax)
+			continue
+		}
+		lease.Store(int64(id))
+		backoff = constant.RegistryWatchRetryMin
+	}
+}
+
+// Close 关闭 etcd 连接，已注册实例的租约到期后自动删除
+func (r *Registrar) Close() error {
+	return r.cli.Close()
+}
+
+func loadConfig() *EtcdConfig {
+	cfg := &EtcdConfig{
+		Endpoints:   config.Registry.Etcd.Endpoints,
+		Username:    config.Registry.Etcd.Username,
+		Password:    config.Registry.Etcd.Password,
+		Prefix:      config.Registry.Etcd.Prefix,
+		LeaseTTL:    config.Registry.Etcd.LeaseTTL,
+		DialTimeout: config.Registry.Etcd.DialTimeout,
+	}
+	return withDefaults(cfg)
+}
+
+func withDefaults(cfg *EtcdConfig) *EtcdConfig {
+	if cfg.Prefix == "" {
+		cfg.Prefix = constant.RegistryEtcdDefaultPrefix
+	}
+	cfg.Prefix = "/" + strings.Trim(cfg.Prefix, "/")
+	// etcd 租约 TTL 以秒为单位，至少 1s
+	if cfg.LeaseTTL < time.Second {
+		cfg.LeaseTTL = constant.RegistryEtcdDefaultLeaseTTL
+	}
+	if cfg.DialTimeout <= 0 {
+		cfg.DialTimeout = constant.RegistryEtcdDefaultDialTimeout
+	}
+	return cfg
+}
+
+// servicePrefix 服务下所有实例的 key 前缀，如 /go-mcp-demo/services/mcp_local/
+func servicePrefix(prefix, service string) string {
+	return prefix + "/" + service + "/"
+}
+
+func instanceKey(prefix, service, id string) string {
+	return servicePrefix(prefix, service) + id
+}
@@ -0,0 +1,210 @@
+package etcd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/FantasyRL/go-mcp-demo/pkg/base/registry"
+	"github.com/FantasyRL/go-mcp-demo/pkg/constant"
+	"github.com/FantasyRL/go-mcp-demo/pkg/logger"
+	"strings"
+	"sync"
+	"time"
+
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+type Resolver struct {
+	cfg *EtcdConfig
+	cli *clientv3.Client
+}
+
+func NewResolver() *Resolver {
+	cfg := loadConfig()
+	if len(cfg.Endpoints) == 0 {
+		return nil
+	}
+	cli, err := newClient(cfg)
+	if err != nil {
+		logger.Errorf("etcd resolver: %v", err)
+		return nil
+	}
+	return &Resolver{cfg: cfg, cli: cli}
+}
+
+// Resolve 返回服务前缀下所有实例的完整 URL；租约过期的实例已被 etcd 删除，不需要额外过滤
+func (r *Resolver) Resolve(services []string) (map[string][]string, error) {
+	if len(services) == 0 {
+		return nil, fmt.Errorf("no Services provided")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), r.cfg.DialTimeout)
+	defer cancel()
+
+	out := make(map[string][]string)
+	for _, svc := range services {
+		if svc == "" {
+			continue
+		}
+		resp, err := r.cli.Get(ctx, servicePrefix(r.cfg.Prefix, svc), clientv3.WithPrefix())
+		if err != nil {
+			return nil, fmt.Errorf("etcd discover %q: %w", svc, err)
+		}
+		for _, kv := range resp.Kvs {
+			if u := instanceURL(kv); u != "" {
+				out[svc] = append(out[svc], u)
+			}
+		}
+	}
+	if len(out) == 0 {
+		return nil, fmt.Errorf("etcd: no instances for %v", services)
+	}
+	return out, nil
+}
+
+// Watch 先全量拉取一次，再从下一个 revision 开始 watch 前缀，把 PUT/DELETE 转换为增量事件
+func (r *Resolver) Watch(ctx context.Context, services []string, onEvent func(registry.Event)) error {
+	if len(services) == 0 {
+		return fmt.Errorf("no Services provided")
+	}
+	var wg sync.WaitGroup
+	for _, svc := range services {
+		if svc == "" {
+			continue
+		}
+		wg.Add(1)
+		go func(svc string) {
+			defer wg.Done()
+			r.watchService(ctx, svc, onEvent)
+		}(svc)
+	}
+	wg.Wait()
+	return ctx.Err()
+}
+
+func (r *Resolver) watchService(ctx context.Context, svc string, onEvent func(registry.Event)) {
+	var (
+		prefix  = servicePrefix(r.cfg.Prefix, svc)
+		current = map[string]string{} // key -> url，DELETE 事件只带 key
+		rev     int64
+		backoff = constant.RegistryWatchRetryMin
+	)
+	retry := func(err error) bool {
+		logger.Errorf("etcd watch %q: %v, retry in %s", svc, err, backoff)
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return false
+		}
+		backoff = min(backoff*2, constant.RegistryWatchRetryMax)
+		return true
+	}
+
+	for ctx.Err() == nil {
+		// rev 为 0 表示需要全量同步：首次启动或需要的 revision 已被压缩
+		if rev == 0 {
+			getCtx, cancel := context.WithTimeout(ctx, r.cfg.DialTimeout)
+			resp, err := r.cli.Get(getCtx, prefix, clientv3.WithPrefix())
+			cancel()
+			if err != nil {
+				if ctx.Err() != nil || !retry(err) {
+					return
+				}
+				continue
+			}
+			next := make(map[string]string, len(resp.Kvs))
+			for _, kv := range resp.Kvs {
+				if u := instanceURL(kv); u != "" {
+					next[string(kv.Key)] = u
+				}
+			}
+			r.diff(svc, current, next, onEvent)
+			current = next
+			rev = resp.Header.Revision
+		}
+
+		// 从全量同步时的下一个 revision 开始 watch，期间的变化不会丢失；
+		// WithRequireLeader 让失去 leader 的节点及时断开，由客户端切换到其他节点
+		wctx, cancel := context.WithCancel(clientv3.WithRequireLeader(ctx))
+		var werr error
+		for wresp := range r.cli.Watch(wctx, prefix, clientv3.WithPrefix(), clientv3.WithRev(rev+1)) {
+			if wresp.CompactRevision != 0 {
+				logger.Warnf("etcd watch %q: revision %d compacted, resync", svc, rev+1)
+				rev = 0
+				break
+			}
+			if werr = wresp.Err(); werr != nil {
+				break
+			}
+			for _, ev := range wresp.Events {
+				r.apply(svc, current, ev, onEvent)
+			}
+			rev = wresp.Header.Revision
+			backoff = constant.RegistryWatchRetryMin
+		}
+		cancel()
+		if ctx.Err() != nil {
+			return
+		}
+		if werr != nil && !retry(werr) {
+			return
+		}
+		// watch 通道被关闭（如 endpoint 重启）时从最后的 revision 续接
+	}
+}
+
+// apply 把一条 watch 事件转换为增量事件
+func (r *Resolver) apply(svc string, current map[string]string, ev *clientv3.Event, onEvent func(registry.Event)) {
+	key := string(ev.Kv.Key)
+	if ev.Type == mvccpb.DELETE {
+		if u, ok := current[key]; ok {
+			delete(current, key)
+			onEvent(registry.Event{Type: registry.EventRemove, Service: svc, URL: u})
+		}
+		return
+	}
+	u := instanceURL(ev.Kv)
+	if u == "" {
+		return
+	}
+	if old, ok := current[key]; ok && old != u {
+		onEvent(registry.Event{Type: registry.EventRemove, Service: svc, URL: old})
+	}
+	if current[key] != u {
+		current[key] = u
+		onEvent(registry.Event{Type: registry.EventAdd, Service: svc, URL: u})
+	}
+}
+
+// diff 对比全量同步前后的实例集合并推送事件
+func (r *Resolver) diff(svc string, current, next map[string]string, onEvent func(registry.Event)) {
+	for key, u := range next {
+		if current[key] != u {
+			onEvent(registry.Event{Type: registry.EventAdd, Service: svc, URL: u})
+		}
+	}
+	for key, u := range current {
+		if next[key] != u {
+			onEvent(registry.Event{Type: registry.EventRemove, Service: svc, URL: u})
+		}
+	}
+}
+
+// instanceURL 解析注册时写入的实例信息，拼出实例完整 URL
+func instanceURL(kv *mvccpb.KeyValue) string {
+	var inst instance
+	if err := json.Unmarshal(kv.Value, &inst); err != nil {
+		logger.Errorf("etcd: invalid instance value at %s: %v", kv.Key, err)
+		return ""
+	}
+	addr := strings.TrimSpace(inst.Addr)
+	if addr == "" {
+		return ""
+	}
+	return registry.InstanceURL(inst.Scheme, addr, inst.Path)
+}
+
+// Close 关闭 etcd 连接，进行中的 Watch 随之结束
+func (r *Resolver) Close() error {
+	return r.cli.Close()
+}
@@ -0,0 +1,103 @@
+package factory
+
+import (
+	"fmt"
+	"github.com/FantasyRL/go-mcp-demo/config"
+	"github.com/FantasyRL/go-mcp-demo/pkg/base/registry"
+	"github.com/FantasyRL/go-mcp-demo/pkg/base/registry/consul"
+	"github.com/FantasyRL/go-mcp-demo/pkg/base/registry/etcd"
+	"github.com/FantasyRL/go-mcp-demo/pkg/base/registry/nacos"
+	"github.com/FantasyRL/go-mcp-demo/pkg/constant"
+)
+
+// provider 一个注册中心实现的构造函数；配置不完整时构造函数返回 nil
+type provider struct {
+	resolver  func() registry.Resolver
+	registrar func(serviceName string) registry.Registrar
+}
+
+// providers 新增注册中心只需要在这里登记，mcp_server 与 base 不感知具体实现
+var providers = map[string]provider{
+	constant.RegistryProviderConsul: {
+		resolver: func() registry.Resolver {
+			if r := consul.NewResolver(); r != nil {
+				return r
+			}
+			return nil
+		},
+		registrar: func(serviceName string) registry.Registrar {
+			if r := consul.NewRegistrar(serviceName); r != nil {
+				return r
+			}
+			return nil
+		},
+	},
+	constant.RegistryProviderEtcd: {
+		resolver: func() registry.Resolver {
+			if r := etcd.NewResolver(); r != nil {
+				return r
+			}
+			return nil
+		},
+		registrar: func(string) registry.Registrar {
+			if r := etcd.NewRegistrar(); r != nil {
+				return r
+			}
+			return nil
+		},
+	},
+	constant.RegistryProviderNacos: {
+		resolver: func() registry.Resolver {
+			if r := nacos.NewResolver(); r != nil {
+				return r
+			}
+			return nil
+		},
+		registrar: func(string) registry.Registrar {
+			if r := nacos.NewRegistrar(); r != nil {
+				return r
+			}
+			return nil
+		},
+	},
+}
+
+// Enabled 是否配置了注册中心（provider 为空或 none 时不使用注册中心）
+func Enabled() bool {
+	p := config.Registry.Provider
+	return p != "" && p != constant.RegistryProviderNone
+}
+
+// NewResolver 按 config.Registry.Provider 创建服务发现
+func NewResolver() (registry.Resolver, error) {
+	p, err := lookup()
+	if err != nil {
+		return nil, err
+	}
+	r := p.resolver()
+	if r == nil {
+		return nil, fmt.Errorf("registry: %s config invalid", config.Registry.Provider)
+	}
+	return r, nil
+}
+
+// NewRegistrar 按 config.Registry.Provider 创建服务注册
+func NewRegistrar(serviceName string) (registry.Registrar, error) {
+	p, err := lookup()
+	if err != nil {
+		return nil, err
+	}
+	r := p.registrar(serviceName)
+	if r == nil {
+		return nil, fmt.Errorf("registry: %s config invalid", config.Registry.Provider)
+	}
+	return r, nil
+}
+
+func lookup() (provider, error) {
+	p, ok := providers[config.Registry.Provider]
+	if !ok {
+		return provider{}, fmt.Errorf("registry: unknown provider %q", config.Registry.Provider)
+	}
+	return p, nil
+}
@@ -0,0 +1,17 @@
+package nacos
+
+import "time"
+
+type NacosConfig struct {
+	Addresses         []string      // 如 http://127.0.0.1:8848/nacos，可多个；省略路径时默认 /nacos
+	Namespace         string        // 命名空间 ID，可空（public）
+	Group             string        // 分组，默认 DEFAULT_GROUP
+	Cluster           string        // 集群名，默认 DEFAULT
+	Username          string        // 可空，开启鉴权时填写
+	Password          string        // 可空
+	BeatInterval      time.Duration // 临时实例心跳间隔，默认 5s
+	SubscribeInterval time.Duration // 订阅刷新间隔（推送丢失时的兜底），默认 5s
+	PushPort          int           // 接收 UDP 推送的端口，默认 0 随机端口
+	ClientIP          string        // Nacos 推送的目标 IP，也是监听地址，必须是本机地址；默认自动探测
+	Timeout           time.Duration // 单次请求超时，默认 5s
+}
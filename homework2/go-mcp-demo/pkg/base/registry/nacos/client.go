@@ -0,0 +1,219 @@
+package nacos
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/FantasyRL/go-mcp-demo/pkg/constant"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// client 基于 Nacos Open API(v1) 的最小客户端，只实现实例注册、心跳、列表与登录
+type client struct {
+	servers  []string // 含 context path，如 http://127.0.0.1:8848/nacos
+	username string
+	password string
+	http     *http.Client
+
+	mu          sync.Mutex
+	token       string
+	tokenExpire time.Time
+}
+
+func newClient(cfg *NacosConfig) *client {
+	servers := make([]string, 0, len(cfg.Addresses))
+	for _, addr := range cfg.Addresses {
+		addr = strings.TrimRight(strings.TrimSpace(addr), "/")
+		if addr == "" {
+			continue
+		}
+		if !strings.Contains(addr, "://") {
+			addr = "http://" + addr
+		}
+		if u, err := url.Parse(addr); err == nil && u.Path == "" {
+			addr += constant.RegistryNacosDefaultContextPath
+		}
+		servers = append(servers, addr)
+	}
+	return &client{
+		servers:  servers,
+		username: cfg.Username,
+		password: cfg.Password,
+		http:     &http.Client{Timeout: cfg.Timeout},
+	}
+}
+
+// host 对应 /v1/ns/instance/list 返回的实例
+type host struct {
+	IP          string            `json:"ip"`
+	Port        int               `json:"port"`
+	Healthy     bool              `json:"healthy"`
+	Enabled     bool              `json:"enabled"`
+	Ephemeral   bool              `json:"ephemeral"`
+	ClusterName string            `json:"clusterName"`
+	Metadata    map[string]string `json:"metadata"`
+}
+
+// serviceInfo /v1/ns/instance/list 的响应，与 UDP 推送中的 data 格式相同
+type serviceInfo struct {
+	Name        string `json:"name"` // 带分组，如 DEFAULT_GROUP@@mcp_local
+	Hosts       []host `json:"hosts"`
+	LastRefTime int64  `json:"lastRefTime"` // 服务端生成该列表的时间（毫秒），用于丢弃过期的推送
+}
+
+// beatInfo 心跳内容，Nacos 据此在实例被删除后自动重建
+type beatInfo struct {
+	ServiceName string            `json:"serviceName"`
+	IP          string            `json:"ip"`
+	Port        int               `json:"port"`
+	Cluster     string            `json:"cluster"`
+	Metadata    map[string]string `json:"metadata"`
+	Scheduled   bool              `json:"scheduled"`
+}
+
+// beatResult /v1/ns/instance/beat 的响应
+type beatResult struct {
+	ClientBeatInterval int64 `json:"clientBeatInterval"` // 服务端建议的心跳间隔（毫秒）
+	Code               int   `json:"code"`
+}
+
+// codeResourceNotFound 心跳时实例已不存在（如 Nacos 重启或心跳超时被删除），需要重新注册
+const codeResourceNotFound = 20404
+
+// do 依次尝试各个 server，直到某个返回成功
+func (c *client) do(ctx context.Context, method, path string, params url.Values, out any) error {
+	if len(c.servers) == 0 {
+		return fmt.Errorf("nacos: no server addresses")
+	}
+	var lastErr error
+	for _, srv := range c.servers {
+		lastErr = c.doOnce(ctx, srv, method, path, params, out, true)
+		if lastErr == nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return lastErr
+		}
+	}
+	return lastErr
+}
+
+func (c *client) doOnce(ctx context.Context, srv, method, path string, params url.Values, out any, retryAuth bool) error {
+	q := cloneValues(params)
+	if c.username != "" {
+		token, err := c.accessToken(ctx, srv)
+		if err != nil {
+			return err
+		}
+		q.Set("accessToken", token)
+	}
+
+	// Nacos Open API 对 POST/PUT/DELETE 同样接受 query 参数，统一放在 URL 上
+	req, err := http.NewRequestWithContext(ctx, method, srv+path+"?"+q.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	// Nacos 按 User-Agent 判断客户端是否支持 UDP 推送
+	req.Header.Set("User-Agent", constant.RegistryNacosUserAgent)
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("nacos %s %s: %w", method, path, err)
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode == http.StatusForbidden && retryAuth && c.username != "" {
+		// token 过期，重新登录后重试一次
+		c.mu.Lock()
+		c.token = ""
+		c.mu.Unlock()
+		return c.doOnce(ctx, srv, method, path, params, out, false)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("nacos %s %s: %s - %s", method, path, resp.Status, strings.TrimSpace(string(data)))
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("nacos %s %s: decode response: %w", method, path, err)
+	}
+	return nil
+}
+
+// accessToken 返回缓存的 token，过期前重新登录
+func (c *client) accessToken(ctx context.Context, srv string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.token != "" && time.Now().Before(c.tokenExpire) {
+		return c.token, nil
+	}
+	form := url.Values{"username": {c.username}, "password": {c.password}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, srv+"/v1/auth/login", strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("nacos login: %w", err)
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("nacos login: %s - %s", resp.Status, strings.TrimSpace(string(data)))
+	}
+	var out struct {
+		AccessToken string `json:"accessToken"`
+		TokenTTL    int64  `json:"tokenTtl"` // 秒
+	}
+	if err := json.Unmarshal(data, &out); err != nil {
+		return "", fmt.Errorf("nacos login: decode response: %w", err)
+	}
+	c.token = out.AccessToken
+	// 预留 10% 的余量提前刷新
+	c.tokenExpire = time.Now().Add(time.Duration(out.TokenTTL) * time.Second * 9 / 10)
+	return c.token, nil
+}
+
+func (c *client) registerInstance(ctx context.Context, params url.Values) error {
+	return c.do(ctx, http.MethodPost, "/v1/ns/instance", params, nil)
+}
+
+func (c *client) deregisterInstance(ctx context.Context, params url.Values) error {
+	return c.do(ctx, http.MethodDelete, "/v1/ns/instance", params, nil)
+}
+
+func (c *client) beat(ctx context.Context, params url.Values, info beatInfo) (*beatResult, error) {
+	b, err := json.Marshal(info)
+	if err != nil {
+		return nil, err
+	}
+	q := cloneValues(params)
+	q.Set("beat", string(b))
+	var out beatResult
+	if err := c.do(ctx, http.MethodPut, "/v1/ns/instance/beat", q, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// listInstances 查询服务下的健康实例；params 带 udpPort/clientIP 时同时订阅该服务的推送
+func (c *client) listInstances(ctx context.Context, params url.Values) (*serviceInfo, error) {
+	q := cloneValues(params)
+	q.Set("healthyOnly", "true")
+	var out serviceInfo
+	if err := c.do(ctx, http.MethodGet, "/v1/ns/instance/list", q, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+func mustJSON(v any) string {
+	b, _ := json.Marshal(v)
+	return string(b)
+}
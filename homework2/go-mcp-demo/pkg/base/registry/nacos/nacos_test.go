@@ -0,0 +1,381 @@
+package nacos
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/FantasyRL/go-mcp-demo/pkg/base/registry"
+)
+
+// fakeNacos 内存版 Nacos Open API，只实现本包用到的接口
+type fakeNacos struct {
+	mu          sync.Mutex
+	instances   map[string]map[string]host // service -> ip:port -> host
+	subscribers map[string]map[string]bool // service -> 订阅推送的 clientIP:udpPort
+	refTime     int64
+	beats       int
+	token       string
+	acks        chan pushAck
+}
+
+func newFakeNacos() *fakeNacos {
+	return &fakeNacos{
+		instances:   map[string]map[string]host{},
+		subscribers: map[string]map[string]bool{},
+		acks:        make(chan pushAck, 16),
+	}
+}
+
+// serviceInfo 生成服务当前的实例列表，调用方持有 f.mu
+func (f *fakeNacos) serviceInfo(svc string) serviceInfo {
+	f.refTime++
+	hosts := []host{}
+	for _, h := range f.instances[svc] {
+		hosts = append(hosts, h)
+	}
+	return serviceInfo{Name: svc, Hosts: hosts, LastRefTime: f.refTime}
+}
+
+// push 像 Nacos 一样把最新实例列表通过 UDP 推送给订阅者，并等待确认，调用方持有 f.mu
+func (f *fakeNacos) push(svc string) {
+	data, _ := json.Marshal(f.serviceInfo(svc))
+	pkt, _ := json.Marshal(pushPacket{Type: "dom", Data: string(data), LastRefTime: f.refTime})
+	for addr := range f.subscribers[svc] {
+		go func() {
+			conn, err := net.Dial("udp", addr)
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+			_, _ = conn.Write(gzipped(pkt))
+			_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+			buf := make([]byte, 1024)
+			n, err := conn.Read(buf)
+			if err != nil {
+				return
+			}
+			var ack pushAck
+			_ = json.Unmarshal(buf[:n], &ack)
+			f.acks <- ack
+		}()
+	}
+}
+
+func gzipped(b []byte) []byte {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	_, _ = zw.Write(b)
+	_ = zw.Close()
+	return buf.Bytes()
+}
+
+func (f *fakeNacos) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	_ = r.ParseForm()
+	if r.URL.Path == "/nacos/v1/auth/login" {
+		if r.PostForm.Get("username") != "nacos" || r.PostForm.Get("password") != "secret" {
+			http.Error(w, "unknown user", http.StatusForbidden)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"accessToken": f.token, "tokenTtl": 18000})
+		return
+	}
+	if f.token != "" && r.URL.Query().Get("accessToken") != f.token {
+		http.Error(w, "token invalid", http.StatusForbidden)
+		return
+	}
+
+	q := r.URL.Query()
+	svc := q.Get("groupName") + "@@" + q.Get("serviceName")
+	port, _ := strconv.Atoi(q.Get("port"))
+	key := q.Get("ip") + ":" + q.Get("port")
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch {
+	case r.URL.Path == "/nacos/v1/ns/instance" && r.Method == http.MethodPost:
+		var meta map[string]string
+		_ = json.Unmarshal([]byte(q.Get("metadata")), &meta)
+		if f.instances[svc] == nil {
+			f.instances[svc] = map[string]host{}
+		}
+		f.instances[svc][key] = host{IP: q.Get("ip"), Port: port, Healthy: true, Enabled: true, Ephemeral: true, Metadata: meta}
+		f.push(svc)
+		_, _ = w.Write([]byte("ok"))
+	case r.URL.Path == "/nacos/v1/ns/instance" && r.Method == http.MethodDelete:
+		delete(f.instances[svc], key)
+		f.push(svc)
+		_, _ = w.Write([]byte("ok"))
+	case r.URL.Path == "/nacos/v1/ns/instance/beat":
+		f.beats++
+		var beat beatInfo
+		_ = json.Unmarshal([]byte(q.Get("beat")), &beat)
+		code := 10200
+		if _, ok := f.instances[svc][beat.IP+":"+strconv.Itoa(beat.Port)]; !ok {
+			code = codeResourceNotFound
+		}
+		_ = json.NewEncoder(w).Encode(beatResult{ClientBeatInterval: 50, Code: code})
+	case r.URL.Path == "/nacos/v1/ns/instance/list":
+		// 只有能识别的客户端才会被推送
+		if q.Get("udpPort") != "" && strings.HasPrefix(r.UserAgent(), "Nacos-Go-Client:v") {
+			if f.subscribers[svc] == nil {
+				f.subscribers[svc] = map[string]bool{}
+			}
+			f.subscribers[svc][net.JoinHostPort(q.Get("clientIP"), q.Get("udpPort"))] = true
+		}
+		_ = json.NewEncoder(w).Encode(f.serviceInfo(svc))
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// drop 模拟心跳超时被 Nacos 删除
+func (f *fakeNacos) drop(service string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.instances, "DEFAULT_GROUP@@"+service)
+}
+
+func newTestPair(t *testing.T, fake *fakeNacos, username, password string) (*Registrar, *Resolver) {
+	srv := httptest.NewServer(fake)
+	t.Cleanup(srv.Close)
+	cfg := withDefaults(&NacosConfig{
+		Addresses:         []string{srv.URL}, // 省略 context path，默认 /nacos
+		Username:          username,
+		Password:          password,
+		BeatInterval:      50 * time.Millisecond,
+		SubscribeInterval: 50 * time.Millisecond,
+	})
+	return &Registrar{cfg: cfg, cli: newClient(cfg)}, &Resolver{cfg: cfg, cli: newClient(cfg)}
+}
+
+func register(t *testing.T, r *Registrar, service, addr string) func() error {
+	t.Helper()
+	deregister, err := r.Register(context.Background(), &registry.Registration{
+		Service: service,
+		ID:      addr,
+		Meta:    map[string]string{"addr": addr},
+		Path:    "/mcp",
+	})
+	if err != nil {
+		t.Fatalf("register %s: %v", addr, err)
+	}
+	t.Cleanup(func() { _ = deregister() })
+	return deregister
+}
+
+func TestRegisterAndResolve(t *testing.T) {
+	fake := newFakeNacos()
+	fake.token = "tk"
+	registrar, resolver := newTestPair(t, fake, "nacos", "secret")
+	register(t, registrar, "mcp_local", "127.0.0.1:10002")
+	deregister := register(t, registrar, "mcp_local", "127.0.0.1:10003")
+
+	got, err := resolver.Resolve([]string{"mcp_local"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(got["mcp_local"])
+	if strings.Join(got["mcp_local"], ",") != "http://127.0.0.1:10002/mcp,http://127.0.0.1:10003/mcp" {
+		t.Fatalf("mcp_local = %v", got["mcp_local"])
+	}
+
+	fake.mu.Lock()
+	meta := fake.instances["DEFAULT_GROUP@@mcp_local"]["127.0.0.1:10002"].Metadata
+	fake.mu.Unlock()
+	if meta["addr"] != "127.0.0.1:10002" || meta["path"] != "/mcp" {
+		t.Fatalf("metadata = %v", meta)
+	}
+
+	if err := deregister(); err != nil {
+		t.Fatal(err)
+	}
+	got, err = resolver.Resolve([]string{"mcp_local"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got["mcp_local"]) != 1 {
+		t.Fatalf("after deregister mcp_local = %v", got["mcp_local"])
+	}
+}
+
+func TestResolveRejectsBadCredentials(t *testing.T) {
+	fake := newFakeNacos()
+	fake.token = "tk"
+	_, resolver := newTestPair(t, fake, "nacos", "wrong")
+	if _, err := resolver.Resolve([]string{"mcp_local"}); err == nil {
+		t.Fatal("expected login error")
+	}
+}
+
+func TestSubscribe(t *testing.T) {
+	fake := newFakeNacos()
+	registrar, resolver := newTestPair(t, fake, "", "")
+	register(t, registrar, "mcp_local", "127.0.0.1:10002")
+
+	events := make(chan registry.Event, 8)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = resolver.Watch(ctx, []string{"mcp_local"}, func(ev registry.Event) { events <- ev }) }()
+
+	expect := func(typ registry.EventType, url string) {
+		t.Helper()
+		select {
+		case ev := <-events:
+			if ev.Type != typ || ev.URL != url {
+				t.Fatalf("got event %+v, want type=%d url=%s", ev, typ, url)
+			}
+		case <-time.After(3 * time.Second):
+			t.Fatalf("timeout waiting for event type=%d url=%s", typ, url)
+		}
+	}
+
+	expect(registry.EventAdd, "http://127.0.0.1:10002/mcp")
+	deregister := register(t, registrar, "mcp_local", "127.0.0.1:10003")
+	expect(registry.EventAdd, "http://127.0.0.1:10003/mcp")
+	if err := deregister(); err != nil {
+		t.Fatal(err)
+	}
+	expect(registry.EventRemove, "http://127.0.0.1:10003/mcp")
+}
+
+func TestHeartbeatReRegisters(t *testing.T) {
+	fake := newFakeNacos()
+	registrar, resolver := newTestPair(t, fake, "", "")
+	register(t, registrar, "mcp_local", "127.0.0.1:10002")
+	fake.drop("mcp_local")
+
+	deadline := time.Now().Add(3 * time.Second)
+	for time.Now().Before(deadline) {
+		if got, err := resolver.Resolve([]string{"mcp_local"}); err == nil && len(got["mcp_local"]) == 1 {
+			return
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Fatal("instance was not re-registered by heartbeat")
+}
+
+func TestSubscribePush(t *testing.T) {
+	fake := newFakeNacos()
+	registrar, resolver := newTestPair(t, fake, "", "")
+	// 拉长刷新间隔，实例变化只能通过推送及时感知
+	resolver.cfg.SubscribeInterval = time.Hour
+	resolver.cfg.ClientIP = "127.0.0.1"
+	register(t, registrar, "mcp_local", "127.0.0.1:10002")
+
+	events := make(chan registry.Event, 8)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = resolver.Watch(ctx, []string{"mcp_local"}, func(ev registry.Event) { events <- ev }) }()
+
+	expect := func(typ registry.EventType, url string) {
+		t.Helper()
+		select {
+		case ev := <-events:
+			if ev.Type != typ || ev.URL != url {
+				t.Fatalf("got event %+v, want type=%d url=%s", ev, typ, url)
+			}
+		case <-time.After(3 * time.Second):
+			t.Fatalf("timeout waiting for event type=%d url=%s", typ, url)
+		}
+	}
+	expectAck := func() {
+		t.Helper()
+		select {
+		case ack := <-fake.acks:
+			if ack.Type != "push-ack" || ack.LastRefTime == "" {
+				t.Fatalf("ack = %+v", ack)
+			}
+		case <-time.After(3 * time.Second):
+			t.Fatal("push was not acknowledged")
+		}
+	}
+
+	// 首次查询同时完成订阅
+	expect(registry.EventAdd, "http://127.0.0.1:10002/mcp")
+	deregister := register(t, registrar, "mcp_local", "127.0.0.1:10003")
+	expect(registry.EventAdd, "http://127.0.0.1:10003/mcp")
+	expectAck()
+	if err := deregister(); err != nil {
+		t.Fatal(err)
+	}
+	expect(registry.EventRemove, "http://127.0.0.1:10003/mcp")
+	expectAck()
+}
+
+func TestSubscriptionDropsStaleList(t *testing.T) {
+	var events []registry.Event
+	sub := &subscription{svc: "mcp_local", current: map[string]struct{}{}, onEvent: func(ev registry.Event) { events = append(events, ev) }}
+	h := host{Healthy: true, Enabled: true, Metadata: map[string]string{"addr": "127.0.0.1:10002"}}
+	sub.update(serviceInfo{Hosts: []host{h}, LastRefTime: 2})
+	// 晚到的旧列表不能把实例摘除
+	sub.update(serviceInfo{LastRefTime: 1})
+	if len(events) != 1 || events[0].Type != registry.EventAdd {
+		t.Fatalf("events = %+v", events)
+	}
+	sub.update(serviceInfo{LastRefTime: 3})
+	if len(events) != 2 || events[1].Type != registry.EventRemove {
+		t.Fatalf("events = %+v", events)
+	}
+}
+
+func TestPushRejectsUnknownSender(t *testing.T) {
+	handled := make(chan serviceInfo, 1)
+	push, err := listenPush(&NacosConfig{Addresses: []string{"http://127.0.0.1:8848/nacos"}, ClientIP: "127.0.0.1"}, func(info serviceInfo) { handled <- info })
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go push.serve(ctx)
+
+	data, _ := json.Marshal(serviceInfo{Name: "DEFAULT_GROUP@@mcp_local", LastRefTime: 1 << 62})
+	pkt, _ := json.Marshal(pushPacket{Type: "dom", Data: string(data), LastRefTime: 1 << 62})
+	send := func(from string) bool {
+		t.Helper()
+		conn, err := net.DialUDP("udp", &net.UDPAddr{IP: net.ParseIP(from)}, push.conn.LocalAddr().(*net.UDPAddr))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		_, _ = conn.Write(pkt)
+		_ = conn.SetReadDeadline(time.Now().Add(300 * time.Millisecond))
+		_, err = conn.Read(make([]byte, 1024))
+		return err == nil
+	}
+
+	// 伪造的推送既不确认也不处理
+	if send("127.0.0.2") {
+		t.Fatal("packet from unknown sender was acknowledged")
+	}
+	select {
+	case info := <-handled:
+		t.Fatalf("packet from unknown sender was handled: %+v", info)
+	default:
+	}
+	if !send("127.0.0.1") {
+		t.Fatal("packet from nacos server was not acknowledged")
+	}
+	<-handled
+}
+
+func TestPushRejectsOversizedPacket(t *testing.T) {
+	p := &pushReceiver{handle: func(serviceInfo) { t.Fatal("oversized packet was handled") }}
+	big := append(append([]byte(`{"type":"dump","data":"`), bytes.Repeat([]byte("a"), maxPushSize)...), `"}`...)
+	if _, err := p.handlePacket(gzipped(big)); err == nil {
+		t.Fatal("expected error")
+	}
+	if _, err := p.handlePacket(gzipped([]byte(`{"type":"dump"}`))); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,205 @@
+package nacos
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/FantasyRL/go-mcp-demo/pkg/logger"
+)
+
+// pushReceiver 接收 Nacos 的 UDP 推送（v1 Open API 的订阅方式）：
+// 查询实例列表时带上 udpPort/clientIP 即订阅了该服务，实例变化时 Nacos 立即把最新的实例列表推送到该端口；
+// 订阅在一段时间内没有再次查询时会失效，所以仍然按 SubscribeInterval 定时查询，同时作为推送丢失时的兜底
+// 推送会直接改变可用的 MCP 实例，只接受来自 Nacos 服务器地址的数据包
+type pushReceiver struct {
+	conn     *net.UDPConn
+	clientIP string
+	port     int
+	servers  map[netip.Addr]struct{} // Addresses 解析出的 IP，启动时解析一次
+	handle   func(serviceInfo)
+}
+
+const maxPushSize = 4 << 20 // 解压后单个推送包的最大字节数
+
+// pushPacket Nacos 推送的数据包，data 为服务的完整实例列表（JSON 字符串）
+type pushPacket struct {
+	Type        string `json:"type"` // "dom"/"service" 实例变化，"dump" 查询客户端缓存
+	Data        string `json:"data"`
+	LastRefTime int64  `json:"lastRefTime"`
+}
+
+// pushAck 回复给 Nacos 的确认，未确认的推送会被重试
+type pushAck struct {
+	Type        string `json:"type"`
+	LastRefTime string `json:"lastRefTime"`
+	Data        string `json:"data"`
+}
+
+// listenPush 在 clientIP:port 上监听 UDP 推送（port 为 0 时随机端口），
+// clientIP 为空时自动探测与 Nacos 通信使用的本机 IP，配置时必须是本机的地址
+func listenPush(cfg *NacosConfig, handle func(serviceInfo)) (*pushReceiver, error) {
+	clientIP := cfg.ClientIP
+	if clientIP == "" {
+		ip, err := localIP(cfg.Addresses)
+		if err != nil {
+			return nil, err
+		}
+		clientIP = ip
+	}
+	ip := net.ParseIP(clientIP)
+	if ip == nil {
+		return nil, fmt.Errorf("invalid client ip %q", clientIP)
+	}
+	servers, err := serverIPs(cfg.Addresses)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: ip, Port: cfg.PushPort})
+	if err != nil {
+		return nil, fmt.Errorf("listen udp: %w", err)
+	}
+	return &pushReceiver{
+		conn:     conn,
+		clientIP: clientIP,
+		port:     conn.LocalAddr().(*net.UDPAddr).Port,
+		servers:  servers,
+		handle:   handle,
+	}, nil
+}
+
+// subscribeParams 查询实例列表时附带的订阅参数
+func (p *pushReceiver) subscribeParams(q url.Values) {
+	q.Set("udpPort", strconv.Itoa(p.port))
+	q.Set("clientIP", p.clientIP)
+}
+
+// serve 处理推送直到 ctx 结束
+func (p *pushReceiver) serve(ctx context.Context) {
+	go func() {
+		<-ctx.Done()
+		_ = p.conn.Close()
+	}()
+	buf := make([]byte, 64*1024)
+	for {
+		n, from, err := p.conn.ReadFromUDP(buf)
+		if err != nil {
+			if ctx.Err() == nil {
+				logger.Errorf("nacos push: read: %v", err)
+			}
+			return
+		}
+		if _, ok := p.servers[from.AddrPort().Addr().Unmap()]; !ok {
+			logger.Warnf("nacos push: drop packet from unknown sender %s", from)
+			continue
+		}
+		ack, err := p.handlePacket(buf[:n])
+		if err != nil {
+			logger.Errorf("nacos push from %s: %v", from, err)
+			continue
+		}
+		b, _ := json.Marshal(ack)
+		if _, err := p.conn.WriteToUDP(b, from); err != nil {
+			logger.Errorf("nacos push ack to %s: %v", from, err)
+		}
+	}
+}
+
+func (p *pushReceiver) handlePacket(b []byte) (pushAck, error) {
+	// 数据较大时 Nacos 会先 gzip 压缩
+	if len(b) > 2 && b[0] == 0x1f && b[1] == 0x8b {
+		r, err := gzip.NewReader(bytes.NewReader(b))
+		if err != nil {
+			return pushAck{}, err
+		}
+		if b, err = io.ReadAll(io.LimitReader(r, maxPushSize+1)); err != nil {
+			return pushAck{}, err
+		}
+		if len(b) > maxPushSize {
+			return pushAck{}, fmt.Errorf("packet exceeds %d bytes after decompression", maxPushSize)
+		}
+	}
+	var pkt pushPacket
+	if err := json.Unmarshal(b, &pkt); err != nil {
+		return pushAck{}, fmt.Errorf("decode packet: %w", err)
+	}
+	ack := pushAck{LastRefTime: strconv.FormatInt(pkt.LastRefTime, 10)}
+	switch pkt.Type {
+	case "dom", "service":
+		var info serviceInfo
+		if err := json.Unmarshal([]byte(pkt.Data), &info); err != nil {
+			return pushAck{}, fmt.Errorf("decode service: %w", err)
+		}
+		p.handle(info)
+		ack.Type = "push-ack"
+	case "dump":
+		ack.Type = "dump-ack"
+	default:
+		ack.Type = "unknown-ack"
+	}
+	return ack, nil
+}
+
+// serverHost 返回 Nacos 地址的 host:port，省略端口时为 8848
+func serverHost(address string) (string, error) {
+	addr := strings.TrimSpace(address)
+	if !strings.Contains(addr, "://") {
+		addr = "http://" + addr
+	}
+	u, err := url.Parse(addr)
+	if err != nil {
+		return "", err
+	}
+	if u.Port() == "" {
+		return net.JoinHostPort(u.Hostname(), "8848"), nil
+	}
+	return u.Host, nil
+}
+
+// serverIPs 解析全部 Nacos 地址的 IP，用于校验推送来源
+func serverIPs(addresses []string) (map[netip.Addr]struct{}, error) {
+	ips := make(map[netip.Addr]struct{})
+	for _, a := range addresses {
+		hostport, err := serverHost(a)
+		if err != nil {
+			return nil, err
+		}
+		h, _, _ := net.SplitHostPort(hostport)
+		addrs, err := net.DefaultResolver.LookupNetIP(context.Background(), "ip", h)
+		if err != nil {
+			return nil, fmt.Errorf("resolve nacos server %s: %w", h, err)
+		}
+		for _, ip := range addrs {
+			ips[ip.Unmap()] = struct{}{}
+		}
+	}
+	if len(ips) == 0 {
+		return nil, fmt.Errorf("nacos: no server addresses")
+	}
+	return ips, nil
+}
+
+// localIP 返回访问第一个 Nacos 地址时使用的本机 IP（UDP Dial 不会真正发包）
+func localIP(addresses []string) (string, error) {
+	if len(addresses) == 0 {
+		return "", fmt.Errorf("nacos: no server addresses")
+	}
+	host, err := serverHost(addresses[0])
+	if err != nil {
+		return "", err
+	}
+	conn, err := net.Dial("udp", host)
+	if err != nil {
+		return "", fmt.Errorf("detect client ip: %w", err)
+	}
+	defer conn.Close()
+	return conn.LocalAddr().(*net.UDPAddr).IP.String(), nil
+}
@@ -0,0 +1,189 @@
+package nacos
+
+import (
+	"context"
+	"fmt"
+	"github.com/FantasyRL/go-mcp-demo/config"
+	"github.com/FantasyRL/go-mcp-demo/pkg/base/registry"
+	"github.com/FantasyRL/go-mcp-demo/pkg/constant"
+	"github.com/FantasyRL/go-mcp-demo/pkg/logger"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type Registrar struct {
+	cfg *NacosConfig
+	cli *client
+}
+
+func NewRegistrar() *Registrar {
+	cfg := loadConfig()
+	if len(cfg.Addresses) == 0 {
+		return nil
+	}
+	return &Registrar{cfg: cfg, cli: newClient(cfg)}
+}
+
+// Register 以临时实例注册并定时发送心跳；心跳中断后 Nacos 会自动摘除实例
+// metadata 与 consul 的 Meta 一致，addr 为实例的完整地址
+func (r *Registrar) Register(ctx context.Context, reg *registry.Registration) (func() error, error) {
+	addr := reg.Meta["addr"]
+	if addr == "" {
+		addr = net.JoinHostPort(reg.Address, strconv.Itoa(reg.Port))
+	}
+	ip, portStr, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, fmt.Errorf("nacos register: invalid addr %q: %w", addr, err)
+	}
+	port, _ := strconv.Atoi(portStr)
+
+	meta := make(map[string]string, len(reg.Meta)+3)
+	for k, v := range reg.Meta {
+		meta[k] = v
+	}
+	meta["addr"] = addr
+	if reg.Scheme != "" {
+		meta["scheme"] = reg.Scheme
+	}
+	if reg.Path != "" {
+		meta["path"] = reg.Path
+	}
+	if len(reg.Tags) > 0 {
+		meta["tags"] = strings.Join(reg.Tags, ",")
+	}
+
+	params := r.serviceParams(reg.Service)
+	params.Set("ip", ip)
+	params.Set("port", strconv.Itoa(port))
+	params.Set("ephemeral", "true")
+	regParams := cloneValues(params)
+	regParams.Set("weight", "1")
+	regParams.Set("enabled", "true")
+	regParams.Set("healthy", "true")
+	regParams.Set("metadata", mustJSON(meta))
+
+	if err := r.cli.registerInstance(ctx, regParams); err != nil {
+		return nil, fmt.Errorf("nacos register: %w", err)
+	}
+
+	beat := beatInfo{
+		ServiceName: r.cfg.Group + "@@" + reg.Service,
+		IP:          ip,
+		Port:        port,
+		Cluster:     r.cfg.Cluster,
+		Metadata:    meta,
+		Scheduled:   true,
+	}
+	beatCtx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		r.heartbeat(beatCtx, params, regParams, beat)
+	}()
+
+	var once sync.Once
+	return func() error {
+		var err error
+		once.Do(func() {
+			cancel()
+			<-stopped
+			ctx, done := context.WithTimeout(context.Background(), r.cfg.Timeout)
+			defer done()
+			err = r.cli.deregisterInstance(ctx, params)
+		})
+		return err
+	}, nil
+}
+
+// heartbeat 定时发送心跳；实例已被删除（20404）时重新注册，服务端下发的间隔优先
+func (r *Registrar) heartbeat(ctx context.Context, params, regParams url.Values, beat beatInfo) {
+	interval := r.cfg.BeatInterval
+	timer := time.NewTimer(interval)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		}
+		res, err := r.cli.beat(ctx, params, beat)
+		switch {
+		case ctx.Err() != nil:
+			return
+		case err != nil:
+			logger.Errorf("nacos heartbeat %s %s:%d: %v", beat.ServiceName, beat.IP, beat.Port, err)
+		case res.Code == codeResourceNotFound:
+			logger.Warnf("nacos instance %s %s:%d not found, re-registering", beat.ServiceName, beat.IP, beat.Port)
+			if err := r.cli.registerInstance(ctx, regParams); err != nil {
+				logger.Errorf("nacos re-register %s: %v", beat.ServiceName, err)
+			}
+		case res.ClientBeatInterval > 0:
+			interval = time.Duration(res.ClientBeatInterval) * time.Millisecond
+		}
+		timer.Reset(interval)
+	}
+}
+
+// serviceParams 服务级别的公共参数
+func (r *Registrar) serviceParams(service string) url.Values {
+	return serviceParams(r.cfg, service)
+}
+
+func serviceParams(cfg *NacosConfig, service string) url.Values {
+	q := url.Values{}
+	q.Set("serviceName", service)
+	q.Set("groupName", cfg.Group)
+	if cfg.Namespace != "" {
+		q.Set("namespaceId", cfg.Namespace)
+	}
+	q.Set("clusterName", cfg.Cluster)
+	return q
+}
+
+func loadConfig() *NacosConfig {
+	cfg := &NacosConfig{
+		Addresses:         config.Registry.Nacos.Addresses,
+		Namespace:         config.Registry.Nacos.Namespace,
+		Group:             config.Registry.Nacos.Group,
+		Cluster:           config.Registry.Nacos.Cluster,
+		Username:          config.Registry.Nacos.Username,
+		Password:          config.Registry.Nacos.Password,
+		BeatInterval:      config.Registry.Nacos.BeatInterval,
+		SubscribeInterval: config.Registry.Nacos.SubscribeInterval,
+		PushPort:          config.Registry.Nacos.PushPort,
+		ClientIP:          config.Registry.Nacos.ClientIP,
+		Timeout:           config.Registry.Nacos.Timeout,
+	}
+	return withDefaults(cfg)
+}
+
+func withDefaults(cfg *NacosConfig) *NacosConfig {
+	if cfg.Group == "" {
+		cfg.Group = constant.RegistryNacosDefaultGroup
+	}
+	if cfg.Cluster == "" {
+		cfg.Cluster = constant.RegistryNacosDefaultCluster
+	}
+	if cfg.BeatInterval <= 0 {
+		cfg.BeatInterval = constant.RegistryNacosDefaultBeatInterval
+	}
+	if cfg.SubscribeInterval <= 0 {
+		cfg.SubscribeInterval = constant.RegistryNacosDefaultSubscribeInterval
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = constant.RegistryNacosDefaultTimeout
+	}
+	return cfg
+}
+
+func cloneValues(v url.Values) url.Values {
+	out := make(url.Values, len(v))
+	for k, vs := range v {
+		out[k] = append([]string(nil), vs...)
+	}
+	return out
+}
@@ -0,0 +1,174 @@
+package nacos
+
+import (
+	"context"
+	"fmt"
+	"github.com/FantasyRL/go-mcp-demo/pkg/base/registry"
+	"github.com/FantasyRL/go-mcp-demo/pkg/constant"
+	"github.com/FantasyRL/go-mcp-demo/pkg/logger"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type Resolver struct {
+	cfg *NacosConfig
+	cli *client
+}
+
+func NewResolver() *Resolver {
+	cfg := loadConfig()
+	if len(cfg.Addresses) == 0 {
+		return nil
+	}
+	return &Resolver{cfg: cfg, cli: newClient(cfg)}
+}
+
+// Resolve 返回所有健康且启用的实例完整 URL
+func (r *Resolver) Resolve(services []string) (map[string][]string, error) {
+	if len(services) == 0 {
+		return nil, fmt.Errorf("no Services provided")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), r.cfg.Timeout)
+	defer cancel()
+
+	out := make(map[string][]string)
+	for _, svc := range services {
+		if svc == "" {
+			continue
+		}
+		info, err := r.cli.listInstances(ctx, serviceParams(r.cfg, svc))
+		if err != nil {
+			return nil, fmt.Errorf("nacos discover %q: %w", svc, err)
+		}
+		if urls := instanceURLs(info.Hosts); len(urls) > 0 {
+			out[svc] = urls
+		}
+	}
+	if len(out) == 0 {
+		return nil, fmt.Errorf("nacos: no healthy instances for %v", services)
+	}
+	return out, nil
+}
+
+// Watch 订阅服务实例变化：查询实例列表时订阅 Nacos 的 UDP 推送，实例变化由推送立即通知；
+// 同时按 SubscribeInterval 刷新订阅并对比结果，推送不可达（如 NAT 后）时退化为定时拉取
+func (r *Resolver) Watch(ctx context.Context, services []string, onEvent func(registry.Event)) error {
+	if len(services) == 0 {
+		return fmt.Errorf("no Services provided")
+	}
+	subs := make(map[string]*subscription, len(services))
+	for _, svc := range services {
+		if svc != "" {
+			subs[r.cfg.Group+"@@"+svc] = &subscription{svc: svc, current: map[string]struct{}{}, onEvent: onEvent}
+		}
+	}
+
+	push, err := listenPush(r.cfg, func(info serviceInfo) {
+		if sub := subs[info.Name]; sub != nil {
+			sub.update(info)
+		}
+	})
+	if err != nil {
+		logger.Warnf("nacos: udp push unavailable, fall back to polling every %s: %v", r.cfg.SubscribeInterval, err)
+	} else {
+		go push.serve(ctx)
+	}
+
+	var wg sync.WaitGroup
+	for _, sub := range subs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r.subscribe(ctx, sub, push)
+		}()
+	}
+	wg.Wait()
+	return ctx.Err()
+}
+
+// subscription 单个服务的订阅状态，推送与定时刷新都通过 update 更新
+type subscription struct {
+	svc     string
+	onEvent func(registry.Event)
+
+	mu          sync.Mutex
+	current     map[string]struct{}
+	lastRefTime int64
+}
+
+// update 用最新的实例列表替换当前结果并推送增量事件，早于已应用结果的列表直接丢弃
+func (s *subscription) update(info serviceInfo) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if info.LastRefTime != 0 && info.LastRefTime < s.lastRefTime {
+		return
+	}
+	s.lastRefTime = info.LastRefTime
+	urls := instanceURLs(info.Hosts)
+	next := make(map[string]struct{}, len(urls))
+	for _, u := range urls {
+		next[u] = struct{}{}
+		if _, ok := s.current[u]; !ok {
+			s.onEvent(registry.Event{Type: registry.EventAdd, Service: s.svc, URL: u})
+		}
+	}
+	for u := range s.current {
+		if _, ok := next[u]; !ok {
+			s.onEvent(registry.Event{Type: registry.EventRemove, Service: s.svc, URL: u})
+		}
+	}
+	s.current = next
+}
+
+// subscribe 定时查询实例列表以保持推送订阅，查询结果同样用于更新
+func (r *Resolver) subscribe(ctx context.Context, sub *subscription, push *pushReceiver) {
+	params := serviceParams(r.cfg, sub.svc)
+	if push != nil {
+		push.subscribeParams(params)
+	}
+	backoff := constant.RegistryWatchRetryMin
+	for {
+		wait := r.cfg.SubscribeInterval
+		reqCtx, cancel := context.WithTimeout(ctx, r.cfg.Timeout)
+		info, err := r.cli.listInstances(reqCtx, params)
+		cancel()
+		switch {
+		case ctx.Err() != nil:
+			return
+		case err != nil:
+			// 出错时保留当前实例，避免 Nacos 短暂不可用导致全部摘除
+			logger.Errorf("nacos subscribe %q: %v, retry in %s", sub.svc, err, backoff)
+			wait = backoff
+			backoff = min(backoff*2, constant.RegistryWatchRetryMax)
+		default:
+			backoff = constant.RegistryWatchRetryMin
+			sub.update(*info)
+		}
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// instanceURLs 健康且启用的实例 URL，优先使用注册时写入 metadata 的 addr，scheme/path 同样来自 metadata
+func instanceURLs(hosts []host) []string {
+	var out []string
+	for _, h := range hosts {
+		if !h.Healthy || !h.Enabled {
+			continue
+		}
+		addr := strings.TrimSpace(h.Metadata["addr"])
+		if addr == "" && h.IP != "" && h.Port > 0 {
+			addr = net.JoinHostPort(h.IP, strconv.Itoa(h.Port))
+		}
+		if addr != "" {
+			out = append(out, registry.InstanceURL(h.Metadata["scheme"], addr, h.Metadata["path"]))
+		}
+	}
+	return out
+}
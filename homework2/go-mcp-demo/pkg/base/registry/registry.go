@@ -2,6 +2,8 @@ package registry
 
 import (
 	"context"
+	"github.com/FantasyRL/go-mcp-demo/pkg/constant"
+	"strings"
 	"time"
 )
 
@@ -30,6 +32,49 @@ type Registration struct {
 	Path    string            // 例如 /mcp（可选）
 
 	// 健康检查（可选）
-	CheckInterval   time.Duration
+	CheckType       string        // "http" | "tcp" | "ttl"，默认 http（HealthURL 为空时退化为 tcp）
+	HealthURL       string        // HTTP 检查地址，如 http://127.0.0.1:10002/health
+	CheckInterval   time.Duration // 检查间隔；ttl 模式下为心跳间隔
 	DeregisterAfter time.Duration
 }
+
+// Watcher 基于推送的服务发现（如 Consul blocking query），实例变化时立即通知
+// Resolver 可选实现该接口，AggregatedClient 会优先使用它代替定时轮询
+type Watcher interface {
+	// Watch 持续监听服务实例变化直到 ctx 结束，每次变化以增量事件回调 onEvent
+	// 首次查询到的实例也会以 EventAdd 推送
+	Watch(ctx context.Context, services []string, onEvent func(Event)) error
+}
+
+// EventType 实例变化类型
+type EventType int
+
+const (
+	EventAdd    EventType = iota // 新增实例
+	EventRemove                  // 实例下线/不健康
+)
+
+// Event 服务实例增量变化
+type Event struct {
+	Type    EventType
+	Service string // 服务名
+	URL     string // 与 Resolver.Resolve 返回的实例 URL 格式一致
+}
+
+// InstanceURL 由注册时写入的 scheme/addr/path 拼出实例的完整 URL，scheme 默认 http，path 默认 /mcp
+// addr 本身已是完整 URL（含 ://）时原样返回
+func InstanceURL(scheme, addr, path string) string {
+	if strings.Contains(addr, "://") {
+		return addr
+	}
+	if scheme == "" {
+		scheme = "http"
+	}
+	if path == "" {
+		path = constant.RegistryMCPDefaultPath
+	}
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	return scheme + "://" + addr + path
+}
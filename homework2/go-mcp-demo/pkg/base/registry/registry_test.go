@@ -0,0 +1,22 @@
+package registry
+
+import "testing"
+
+func TestInstanceURL(t *testing.T) {
+	cases := []struct {
+		scheme, addr, path string
+		want               string
+	}{
+		{"", "127.0.0.1:10002", "", "http://127.0.0.1:10002/mcp"},
+		{"https", "127.0.0.1:10002", "", "https://127.0.0.1:10002/mcp"},
+		{"https", "mcp.example.com:443", "rpc", "https://mcp.example.com:443/rpc"},
+		{"http", "127.0.0.1:10002", "/rpc", "http://127.0.0.1:10002/rpc"},
+		// addr 已是完整 URL 时忽略 scheme/path
+		{"http", "https://mcp.example.com/mcp", "/rpc", "https://mcp.example.com/mcp"},
+	}
+	for _, c := range cases {
+		if got := InstanceURL(c.scheme, c.addr, c.path); got != c.want {
+			t.Errorf("InstanceURL(%q, %q, %q) = %q, want %q", c.scheme, c.addr, c.path, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,233 @@
+package vector_index
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"math"
+	"os"
+	"slices"
+	"sort"
+	"sync"
+
+	"github.com/FantasyRL/go-mcp-demo/pkg/utils"
+)
+
+// Document 索引中的一条记录，Vector 一般来自 ai_provider.Client.Embed
+type Document struct {
+	ID       string            `json:"id"`
+	Text     string            `json:"text,omitempty"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+	Vector   []float32         `json:"vector"`
+}
+
+// Result 检索结果，Score 为余弦相似度，范围 [-1, 1]
+type Result struct {
+	Document
+	Score float64
+}
+
+// Index 进程内的向量索引，按余弦相似度暴力检索，适合几万条以内的数据
+// 所有向量的维度必须相同（更换向量模型后需要删除全部记录再重建）；path 非空时每次修改后落盘
+type Index struct {
+	mu    sync.RWMutex
+	path  string
+	dim   int
+	docs  map[string]*Document
+	norms map[string]float64 // id -> 向量的模，检索时不用重复计算
+}
+
+// New 创建索引并加载 path 中已有的记录，文件不存在时视为空
+func New(path string) (*Index, error) {
+	x := &Index{
+		path:  path,
+		docs:  make(map[string]*Document),
+		norms: make(map[string]float64),
+	}
+	if path == "" {
+		return x, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return x, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("vector_index: read index file: %w", err)
+	}
+	var docs []*Document
+	if err := json.Unmarshal(data, &docs); err != nil {
+		return nil, fmt.Errorf("vector_index: parse index file %s: %w", path, err)
+	}
+	for _, d := range docs {
+		if err := check(d, x.dim); err != nil {
+			return nil, fmt.Errorf("vector_index: load %s: %w", path, err)
+		}
+		x.put(d)
+	}
+	return x, nil
+}
+
+// Upsert 添加或替换记录（按 ID），任意一条不合法或落盘失败时整批不写入；一批记录只落盘一次
+// 保存的是 Vector 与 Metadata 的副本，调用方之后修改 docs 不影响索引
+func (x *Index) Upsert(docs ...Document) error {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	dim := x.dim
+	for i := range docs {
+		if err := check(&docs[i], dim); err != nil {
+			return err
+		}
+		dim = len(docs[i].Vector)
+	}
+	added := make([]*Document, 0, len(docs))
+	next := maps.Clone(x.docs)
+	for i := range docs {
+		d := docs[i]
+		d.Vector = slices.Clone(d.Vector)
+		d.Metadata = maps.Clone(d.Metadata)
+		added = append(added, &d)
+		next[d.ID] = &d
+	}
+	// 先落盘再更新内存，写文件失败时内存与文件保持一致
+	if err := x.save(next); err != nil {
+		return err
+	}
+	for _, d := range added {
+		x.put(d)
+	}
+	return nil
+}
+
+// check 校验记录，dim 为 0 表示索引为空，任意维度都可以
+func check(d *Document, dim int) error {
+	if d.ID == "" {
+		return errors.New("vector_index: document id is required")
+	}
+	if len(d.Vector) == 0 {
+		return fmt.Errorf("vector_index: document %q has an empty vector", d.ID)
+	}
+	if dim != 0 && len(d.Vector) != dim {
+		return fmt.Errorf("vector_index: document %q has dimension %d, index dimension is %d", d.ID, len(d.Vector), dim)
+	}
+	// NaN / Inf 会让相似度变成 NaN，排序结果不确定，也无法写入 JSON
+	for i, v := range d.Vector {
+		if f := float64(v); math.IsNaN(f) || math.IsInf(f, 0) {
+			return fmt.Errorf("vector_index: document %q has a non-finite value at index %d", d.ID, i)
+		}
+	}
+	return nil
+}
+
+// put 写入一条已校验的记录；调用方需持有写锁
+func (x *Index) put(d *Document) {
+	x.dim = len(d.Vector)
+	x.docs[d.ID] = d
+	x.norms[d.ID] = norm(d.Vector)
+}
+
+// Delete 删除记录，不存在的 ID 忽略；落盘失败时不删除
+func (x *Index) Delete(ids ...string) error {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	next := maps.Clone(x.docs)
+	for _, id := range ids {
+		delete(next, id)
+	}
+	if err := x.save(next); err != nil {
+		return err
+	}
+	for _, id := range ids {
+		delete(x.docs, id)
+		delete(x.norms, id)
+	}
+	if len(x.docs) == 0 {
+		x.dim = 0
+	}
+	return nil
+}
+
+// Get 按 ID 返回记录
+func (x *Index) Get(id string) (Document, bool) {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	d, ok := x.docs[id]
+	if !ok {
+		return Document{}, false
+	}
+	return *d, true
+}
+
+// Len 记录数
+func (x *Index) Len() int {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	return len(x.docs)
+}
+
+// Search 返回与 vector 最相似的 k 条记录，按相似度从高到低排序；k <= 0 时返回全部
+// filter 非 nil 时只检索 filter 返回 true 的记录
+func (x *Index) Search(vector []float32, k int, filter func(Document) bool) ([]Result, error) {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	if len(x.docs) == 0 {
+		return []Result{}, nil
+	}
+	if len(vector) != x.dim {
+		return nil, fmt.Errorf("vector_index: query has dimension %d, index dimension is %d", len(vector), x.dim)
+	}
+	qn := norm(vector)
+	results := make([]Result, 0, len(x.docs))
+	for id, d := range x.docs {
+		if filter != nil && !filter(*d) {
+			continue
+		}
+		score := 0.0
+		if n := qn * x.norms[id]; n > 0 {
+			score = dot(vector, d.Vector) / n
+		}
+		results = append(results, Result{Document: *d, Score: score})
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].ID < results[j].ID
+	})
+	if k > 0 && len(results) > k {
+		results = results[:k]
+	}
+	return results, nil
+}
+
+// save 把修改后的全部文档 all 写回索引文件；调用方需持有写锁
+func (x *Index) save(all map[string]*Document) error {
+	if x.path == "" {
+		return nil
+	}
+	docs := make([]*Document, 0, len(all))
+	for _, d := range all {
+		docs = append(docs, d)
+	}
+	sort.Slice(docs, func(i, j int) bool { return docs[i].ID < docs[j].ID })
+	data, err := json.Marshal(docs)
+	if err != nil {
+		return err
+	}
+	if err := utils.WriteFileAtomic(x.path, data, 0o600); err != nil {
+		return fmt.Errorf("vector_index: save index: %w", err)
+	}
+	return nil
+}
+
+func dot(a, b []float32) float64 {
+	var s float64
+	for i := range a {
+		s += float64(a[i]) * float64(b[i])
+	}
+	return s
+}
+
+func norm(v []float32) float64 {
+	return math.Sqrt(dot(v, v))
+}
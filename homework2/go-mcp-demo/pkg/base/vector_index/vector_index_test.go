@@ -0,0 +1,93 @@
+package vector_index
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestIndex(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "index.json")
+	x, err := New(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = x.Upsert(
+		Document{ID: "a", Text: "cat", Vector: []float32{1, 0, 0}},
+		Document{ID: "b", Text: "dog", Vector: []float32{0.8, 0.6, 0}, Metadata: map[string]string{"kind": "pet"}},
+		Document{ID: "c", Text: "car", Vector: []float32{0, 0, 2}},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := x.Upsert(Document{ID: "d", Vector: []float32{1, 0, 0}}, Document{ID: "e", Vector: []float32{1, 0}}); err == nil || x.Len() != 3 {
+		t.Fatalf("dimension mismatch should reject the whole batch: %v, len %d", err, x.Len())
+	}
+
+	// 重新加载后检索结果一致
+	x, err = New(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := x.Search([]float32{2, 0, 0}, 2, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0].ID != "a" || got[0].Score < 0.999 || got[1].ID != "b" || got[1].Score < 0.799 || got[1].Score > 0.801 {
+		t.Fatalf("results = %+v", got)
+	}
+	got, _ = x.Search([]float32{1, 0, 0}, 0, func(d Document) bool { return d.Metadata["kind"] == "pet" })
+	if len(got) != 1 || got[0].ID != "b" {
+		t.Fatalf("filtered results = %+v", got)
+	}
+	if _, err := x.Search([]float32{1, 0}, 1, nil); err == nil {
+		t.Fatal("query dimension mismatch should be rejected")
+	}
+
+	if err := x.Delete("a"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := x.Get("a"); ok || x.Len() != 2 {
+		t.Fatalf("len = %d after delete", x.Len())
+	}
+}
+
+func TestIndexUpsertSafety(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "index.json")
+	x, err := New(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v := []float32{1, 0}
+	if err := x.Upsert(Document{ID: "a", Vector: v}); err != nil {
+		t.Fatal(err)
+	}
+	// 调用方修改传入的向量不影响索引
+	v[0] = 0
+	if d, _ := x.Get("a"); d.Vector[0] != 1 {
+		t.Fatalf("vector = %v", d.Vector)
+	}
+	for _, bad := range []float32{float32(math.NaN()), float32(math.Inf(1))} {
+		if err := x.Upsert(Document{ID: "b", Vector: []float32{bad, 0}}); err == nil {
+			t.Fatalf("%v should be rejected", bad)
+		}
+	}
+
+	// 落盘失败时内存不变
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(path, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if err := x.Upsert(Document{ID: "c", Vector: []float32{0, 1}}); err == nil {
+		t.Fatal("expected save error")
+	}
+	if err := x.Delete("a"); err == nil {
+		t.Fatal("expected save error")
+	}
+	if _, ok := x.Get("c"); ok || x.Len() != 1 {
+		t.Fatalf("len = %d after failed writes", x.Len())
+	}
+}
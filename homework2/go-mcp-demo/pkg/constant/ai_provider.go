@@ -0,0 +1,31 @@
+package constant
+
+import "time"
+
+const (
+	AiProviderModeLocal  = "local"  // 本地模型
+	AiProviderModeRemote = "remote" // 远程模型
+
+	AiProviderTypeOllama    = "ollama"    // ollama 原生接口（/api/chat），同时可以使用其 OpenAI 兼容层
+	AiProviderTypeOpenAI    = "openai"    // OpenAI 兼容接口
+	AiProviderTypeAnthropic = "anthropic" // Anthropic Messages API
+	AiProviderTypeGemini    = "gemini"    // Google Gemini generateContent
+
+	AiProviderAPIChatCompletions = "chat_completions" // openai 服务商默认使用 Chat Completions
+	AiProviderAPIResponses       = "responses"        // openai 服务商使用 Responses API（推理条目、内置工具、previous_response_id）
+
+	AiProviderBuiltinToolWebSearch       = "web_search"       // Responses API 内置工具：联网搜索
+	AiProviderBuiltinToolCodeInterpreter = "code_interpreter" // Responses API 内置工具：代码执行（自动创建容器）
+
+	AiProviderModelSeparator = "/" // "服务商/模型名" 的分隔符
+
+	AiProviderRetryBackoff    = 500 * time.Millisecond // 默认首次重试等待时间
+	AiProviderRetryMaxBackoff = 5 * time.Second        // 默认单次重试等待上限
+
+	AnthropicAPIVersion          = "2023-06-01" // anthropic-version 请求头
+	AiProviderAnthropicMaxTokens = 4096         // Anthropic 要求必须指定 max_tokens，未配置时使用该值
+
+	ImageRefPrefix   = "image:"  // 上传图片的引用前缀，后接图片内容的 sha256
+	MaxImageSize     = 10 << 20  // 单张图片默认的最大字节数
+	ImageMemMaxBytes = 256 << 20 // 未配置 cli.image_dir 时内存中保存图片的总字节数上限
+)
@@ -0,0 +1,18 @@
+package constant
+
+import "time"
+
+const (
+	AuthDefaultAccessTokenTTL  = 2 * time.Hour      // 访问令牌默认有效期
+	AuthDefaultRefreshTokenTTL = 7 * 24 * time.Hour // 刷新令牌默认有效期
+	AuthDefaultUserID          = int64(1)           // 未开启鉴权时使用的用户 id
+	AuthTokenAudience          = "host"             // host 签发令牌的 aud，与 MCP 的 hmac token 区分
+	AuthTokenTypeAccess        = "access"           // 访问令牌
+	AuthTokenTypeRefresh       = "refresh"          // 刷新令牌
+	AuthAPIKeyPrefix           = "mcpd_"            // API Key 明文前缀
+	AuthAPIKeyHeader           = "X-API-Key"        // 携带 API Key 的请求头
+	AuthAccessTokenQuery       = "access_token"     // EventSource 无法设置请求头，SSE 允许通过 query 携带访问令牌
+	AuthUserIDKey              = "user_id"          // RequestContext 中保存当前用户 id 的 key
+	AuthAPIKeyIDKey            = "api_key_id"       // RequestContext 中保存当前 API Key id 的 key
+	AuthPasswordIterations     = 210000             // pbkdf2-sha256 默认迭代次数
+)
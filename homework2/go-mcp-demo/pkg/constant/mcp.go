@@ -9,7 +9,30 @@ const (
 	MCPClientInitTimeout       = 5 * time.Second  // MCP客户端初始化超时时间
 	MCPDefaultCallTimeout      = 30 * time.Second // MCP调用默认超时时间
 	MCPServerHeartbeatInterval = 25 * time.Second // MCP服务器心跳间隔
+	MCPServerHealthPath        = "/health"        // MCP服务器健康检查路径
+	MCPServerShutdownTimeout   = 15 * time.Second // MCP服务器优雅退出等待在途请求的最长时间
 
-	AiProviderModeLocal  = "local"  // 本地模型
-	AiProviderModeRemote = "remote" // 远程模型
+	MCPToolNamespaceModeAuto   = "auto"    // 仅在工具名冲突时加服务名前缀
+	MCPToolNamespaceModeAlways = "always"  // 总是加服务名前缀
+	MCPToolNamespaceModeNever  = "never"   // 从不加前缀
+	MCPToolNamespaceSeparator  = "__"      // 服务名与工具名之间的分隔符
+	MCPToolNameRetention       = time.Hour // 服务下线后保留其 auto 模式暴露名的时长，期间重新上线仍使用原来的名字
+
+	MCPBalanceRoundRobin          = "round_robin"     // 轮询
+	MCPBalanceLeastInflight       = "least_inflight"  // 最少并发
+	MCPBalanceConsistentHash      = "consistent_hash" // 按会话一致性哈希
+	MCPBreakerDefaultThreshold    = 3                 // 默认连续失败 3 次熔断
+	MCPBreakerDefaultOpenDuration = 30 * time.Second  // 默认熔断 30s
+
+	MCPAuthModeNone              = "none"                                  // 不鉴权
+	MCPAuthModeStatic            = "static"                                // 静态 Bearer Token
+	MCPAuthModeHMAC              = "hmac"                                  // 使用 server.private-key 签发的 HS256 token
+	MCPAuthModeOAuth             = "oauth"                                 // OAuth 2.1 资源服务器（MCP Authorization 规范）
+	MCPAuthHMACDefaultTTL        = 5 * time.Minute                         // hmac token 默认有效期
+	MCPAuthHMACAudience          = "mcp"                                   // hmac token 的 aud
+	MCPAuthProtectedResourcePath = "/.well-known/oauth-protected-resource" // RFC 9728 资源元数据路径
+	MCPAuthIntrospectionCacheTTL = 30 * time.Second                        // token 内省结果最长缓存时间
+	MCPAuthIntrospectionCacheMax = 4096                                    // 内省结果最多缓存的 token 数，超出后淘汰最久未使用的
+	MCPAuthIntrospectionSweep    = time.Minute                             // 清理过期内省结果的间隔
+	MCPAuthTokenRefreshSkew      = 30 * time.Second                        // 客户端提前刷新 token 的余量
 )
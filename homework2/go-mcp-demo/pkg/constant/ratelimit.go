@@ -0,0 +1,10 @@
+package constant
+
+const (
+	RateLimitHeaderLimit           = "X-RateLimit-Limit"            // 每分钟允许的请求数
+	RateLimitHeaderRemaining       = "X-RateLimit-Remaining"        // 当前窗口剩余请求数
+	RateLimitHeaderReset           = "X-RateLimit-Reset"            // 当前窗口结束时间（Unix 秒）
+	RateLimitHeaderTokensLimit     = "X-RateLimit-Tokens-Limit"     // 每日 token 配额
+	RateLimitHeaderTokensRemaining = "X-RateLimit-Tokens-Remaining" // 今日剩余 token
+	RateLimitHeaderRetryAfter      = "Retry-After"                  // 被限流时建议的重试等待秒数
+)
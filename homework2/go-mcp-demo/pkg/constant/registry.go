@@ -11,7 +11,27 @@ const (
 	RegistryMCPTag         = "mcp"
 	RegistryMCPDefaultPath = "/mcp"
 
+	RegistryCheckTypeHTTP = "http" // 由注册中心请求 /health
+	RegistryCheckTypeTCP  = "tcp"  // 由注册中心探测端口
+	RegistryCheckTypeTTL  = "ttl"  // 由服务自己定时上报心跳
+
 	RegistryCheckInterval                  = 5 * time.Second
 	RegistryDeregisterAfter                = 15 * time.Second
+	RegistryTTLMultiplier                  = 3 // ttl = 心跳间隔 * 3，容忍偶发的心跳丢失
 	RegistryResolverDefaultRefreshInterval = 10 * time.Second
+	RegistryWatchWaitTime                  = 5 * time.Minute  // 阻塞查询单次最长等待时间
+	RegistryWatchRetryMin                  = 1 * time.Second  // watch 出错后的最小重试间隔
+	RegistryWatchRetryMax                  = 30 * time.Second // watch 出错后的最大重试间隔
+
+	RegistryEtcdDefaultPrefix      = "/go-mcp-demo/services" // etcd 中服务实例的 key 前缀
+	RegistryEtcdDefaultLeaseTTL    = 10 * time.Second        // etcd 注册租约默认 TTL
+	RegistryEtcdDefaultDialTimeout = 5 * time.Second         // etcd 单次请求默认超时
+
+	RegistryNacosDefaultContextPath       = "/nacos"
+	RegistryNacosDefaultGroup             = "DEFAULT_GROUP"
+	RegistryNacosDefaultCluster           = "DEFAULT"
+	RegistryNacosDefaultBeatInterval      = 5 * time.Second          // 临时实例心跳间隔，Nacos 15s 未收到心跳标记不健康、30s 删除
+	RegistryNacosDefaultSubscribeInterval = 5 * time.Second          // 订阅刷新间隔，Nacos 10s 内未刷新的订阅不再推送
+	RegistryNacosUserAgent                = "Nacos-Go-Client:v1.0.0" // Nacos 只向能识别的客户端 UDP 推送
+	RegistryNacosDefaultTimeout           = 5 * time.Second          // 单次请求默认超时
 )
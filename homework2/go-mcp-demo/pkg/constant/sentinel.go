@@ -0,0 +1,13 @@
+package constant
+
+import "time"
+
+const (
+	SentinelResourceAPI        = "api"         // 网关整体的 Sentinel 资源名
+	SentinelResourceAIProvider = "ai_provider" // 调用模型，单个服务商为 "ai_provider:<服务商>"
+	SentinelResourceMCP        = "mcp"         // 调用 MCP 工具，单个工具为 "mcp:<工具名>"
+	SentinelDefaultThreshold   = 100           // 未配置 flow 规则时 "api" 的默认 QPS
+
+	SentinelBreakerDefaultStatInterval = 10 * time.Second // 熔断默认统计窗口
+	SentinelBreakerDefaultRetryTimeout = 30 * time.Second // 熔断默认持续时间
+)
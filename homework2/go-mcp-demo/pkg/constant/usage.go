@@ -0,0 +1,7 @@
+package constant
+
+import "time"
+
+const (
+	UsageFlushInterval = 10 * time.Second // 用量记录定时落盘的间隔，退出时会再写一次
+)
@@ -44,7 +44,7 @@ type logger struct {
 
 var (
 	control           controlLogger
-	logLevel          = zapcore.InfoLevel
+	logLevel          = zap.NewAtomicLevelAt(zapcore.InfoLevel) // 热更新时并发修改，需原子读写
 	callerSkip        = 2
 	logFileHandler    atomic.Value
 	stdErrFileHandler atomic.Value // 全局变量，避免被 GC 回收
@@ -65,11 +65,16 @@ func Init(service string, level string) {
 		panic("server should not be empty")
 	}
 
-	logLevel = parseLevel(level)
+	logLevel.SetLevel(parseLevel(level))
 	control.updateLogger(service)
 	control.scheduleUpdateLogger(service)
 }
 
+// SetLevel 修改日志等级，用于配置热更新
+func SetLevel(level string) {
+	logLevel.SetLevel(parseLevel(level))
+}
+
 // AddLoggerHook 会将传进的参数在每一次日志输出后执行
 func AddLoggerHook(fns ...func(zapcore.Entry) error) {
 	control.hooks = append(control.hooks, fns...)
@@ -114,10 +119,10 @@ func (l *controlLogger) updateLogger(service string) {
 
 	// 让日志输出到不同的位置
 	logLevelFn := zap.LevelEnablerFunc(func(lvl zapcore.Level) bool {
-		return lvl <= logLevel
+		return lvl <= logLevel.Level()
 	})
 	errLevelFn := zap.LevelEnablerFunc(func(lvl zapcore.Level) bool {
-		return lvl > logLevel
+		return lvl > logLevel.Level()
 	})
 
 	logCore := zapcore.NewCore(defaultEnc(), zapcore.Lock(logFileHandler.Load().(*os.File)), logLevelFn)    //nolint
@@ -0,0 +1,357 @@
+// Package jsonschema 一个够用的 JSON Schema 校验器，支持常用关键字：
+// type / enum / const / properties / required / additionalProperties / items /
+// minItems / maxItems / minLength / maxLength / pattern / minimum / maximum /
+// exclusiveMinimum / exclusiveMaximum / anyOf / oneOf / allOf / $ref(仅 #/$defs/...)
+// 其余关键字（description、default、format 等）会被忽略
+package jsonschema
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Error 一处不符合 schema 的位置，Path 形如 ai_provider.mode、sentinel.flow[0].resource，根节点为空
+type Error struct {
+	Path string
+	Msg  string
+}
+
+func (e Error) Error() string {
+	if e.Path == "" {
+		return e.Msg
+	}
+	return e.Path + ": " + e.Msg
+}
+
+// Schema 已解析的 schema
+type Schema struct {
+	root map[string]any
+}
+
+// Parse 解析 JSON 格式的 schema
+func Parse(data []byte) (*Schema, error) {
+	var root map[string]any
+	if err := json.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("jsonschema: %w", err)
+	}
+	return &Schema{root: root}, nil
+}
+
+// New 使用已经解码的 schema
+func New(root map[string]any) *Schema {
+	return &Schema{root: root}
+}
+
+// Validate 校验 v 并返回全部错误，v 可以是 json.Unmarshal 或 YAML 解码得到的 map/slice/基础类型
+func (s *Schema) Validate(v any) []Error {
+	vd := &validator{root: s.root}
+	vd.validate(s.root, v, "")
+	return vd.errs
+}
+
+type validator struct {
+	root map[string]any
+	errs []Error
+}
+
+func (vd *validator) fail(path, format string, args ...any) {
+	vd.errs = append(vd.errs, Error{Path: path, Msg: fmt.Sprintf(format, args...)})
+}
+
+func (vd *validator) validate(s map[string]any, v any, path string) {
+	if ref, ok := s["$ref"].(string); ok {
+		target, err := vd.resolve(ref)
+		if err != nil {
+			vd.fail(path, "%v", err)
+			return
+		}
+		vd.validate(target, v, path)
+		return
+	}
+
+	if t, ok := s["type"]; ok && !matchType(t, v) {
+		vd.fail(path, "expected %s, got %s", typeString(t), typeOf(v))
+		return
+	}
+	if enum, ok := s["enum"].([]any); ok && !slices.ContainsFunc(enum, func(e any) bool { return equal(e, v) }) {
+		vd.fail(path, "must be one of %s, got %s", joinValues(enum), formatValue(v))
+	}
+	if c, ok := s["const"]; ok && !equal(c, v) {
+		vd.fail(path, "must be %s, got %s", formatValue(c), formatValue(v))
+	}
+
+	switch x := v.(type) {
+	case map[string]any:
+		vd.validateObject(s, x, path)
+	case []any:
+		vd.validateArray(s, x, path)
+	case string:
+		vd.validateString(s, x, path)
+	default:
+		if f, ok := toFloat(v); ok {
+			vd.validateNumber(s, f, path)
+		}
+	}
+
+	if all, ok := s["allOf"].([]any); ok {
+		for _, sub := range all {
+			if m, ok := sub.(map[string]any); ok {
+				vd.validate(m, v, path)
+			}
+		}
+	}
+	if anyOf, ok := s["anyOf"].([]any); ok && vd.countMatches(anyOf, v) == 0 {
+		vd.fail(path, "does not match any of the allowed schemas")
+	}
+	if oneOf, ok := s["oneOf"].([]any); ok {
+		if n := vd.countMatches(oneOf, v); n != 1 {
+			vd.fail(path, "must match exactly one of the allowed schemas, matched %d", n)
+		}
+	}
+}
+
+func (vd *validator) validateObject(s map[string]any, obj map[string]any, path string) {
+	props, _ := s["properties"].(map[string]any)
+	if req, ok := s["required"].([]any); ok {
+		for _, r := range req {
+			name, _ := r.(string)
+			if _, ok := obj[name]; !ok {
+				vd.fail(join(path, name), "is required")
+			}
+		}
+	}
+
+	keys := make([]string, 0, len(obj))
+	for k := range obj {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if ps, ok := props[k].(map[string]any); ok {
+			vd.validate(ps, obj[k], join(path, k))
+			continue
+		}
+		switch ap := s["additionalProperties"].(type) {
+		case bool:
+			if !ap {
+				vd.fail(join(path, k), "unknown field")
+			}
+		case map[string]any:
+			vd.validate(ap, obj[k], join(path, k))
+		}
+	}
+}
+
+func (vd *validator) validateArray(s map[string]any, arr []any, path string) {
+	if n, ok := toInt(s["minItems"]); ok && len(arr) < n {
+		vd.fail(path, "must contain at least %d item(s)", n)
+	}
+	if n, ok := toInt(s["maxItems"]); ok && len(arr) > n {
+		vd.fail(path, "must contain at most %d item(s)", n)
+	}
+	if items, ok := s["items"].(map[string]any); ok {
+		for i, item := range arr {
+			vd.validate(items, item, path+"["+strconv.Itoa(i)+"]")
+		}
+	}
+}
+
+func (vd *validator) validateString(s map[string]any, str string, path string) {
+	n := utf8.RuneCountInString(str)
+	if min, ok := toInt(s["minLength"]); ok && n < min {
+		vd.fail(path, "must be at least %d character(s)", min)
+	}
+	if max, ok := toInt(s["maxLength"]); ok && n > max {
+		vd.fail(path, "must be at most %d character(s)", max)
+	}
+	if p, ok := s["pattern"].(string); ok {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			vd.fail(path, "invalid pattern %q in schema: %v", p, err)
+		} else if !re.MatchString(str) {
+			vd.fail(path, "%q does not match pattern %s", str, p)
+		}
+	}
+}
+
+func (vd *validator) validateNumber(s map[string]any, f float64, path string) {
+	if min, ok := toFloat(s["minimum"]); ok && f < min {
+		vd.fail(path, "must be >= %v, got %v", min, f)
+	}
+	if max, ok := toFloat(s["maximum"]); ok && f > max {
+		vd.fail(path, "must be <= %v, got %v", max, f)
+	}
+	if min, ok := toFloat(s["exclusiveMinimum"]); ok && f <= min {
+		vd.fail(path, "must be > %v, got %v", min, f)
+	}
+	if max, ok := toFloat(s["exclusiveMaximum"]); ok && f >= max {
+		vd.fail(path, "must be < %v, got %v", max, f)
+	}
+}
+
+// countMatches 统计 v 能通过的子 schema 个数
+func (vd *validator) countMatches(schemas []any, v any) int {
+	n := 0
+	for _, sub := range schemas {
+		m, ok := sub.(map[string]any)
+		if !ok {
+			continue
+		}
+		child := &validator{root: vd.root}
+		child.validate(m, v, "")
+		if len(child.errs) == 0 {
+			n++
+		}
+	}
+	return n
+}
+
+func (vd *validator) resolve(ref string) (map[string]any, error) {
+	name, ok := strings.CutPrefix(ref, "#/$defs/")
+	if !ok {
+		return nil, fmt.Errorf("unsupported $ref %q", ref)
+	}
+	defs, _ := vd.root["$defs"].(map[string]any)
+	target, ok := defs[name].(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("unresolved $ref %q", ref)
+	}
+	return target, nil
+}
+
+func join(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func matchType(t any, v any) bool {
+	switch x := t.(type) {
+	case string:
+		return matchOne(x, v)
+	case []any:
+		for _, one := range x {
+			if s, ok := one.(string); ok && matchOne(s, v) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+func matchOne(t string, v any) bool {
+	switch t {
+	case "object":
+		_, ok := v.(map[string]any)
+		return ok
+	case "array":
+		_, ok := v.([]any)
+		return ok
+	case "string":
+		_, ok := v.(string)
+		return ok
+	case "boolean":
+		_, ok := v.(bool)
+		return ok
+	case "null":
+		return v == nil
+	case "number":
+		_, ok := toFloat(v)
+		return ok
+	case "integer":
+		f, ok := toFloat(v)
+		return ok && f == math.Trunc(f)
+	}
+	return false
+}
+
+func typeString(t any) string {
+	if list, ok := t.([]any); ok {
+		parts := make([]string, 0, len(list))
+		for _, one := range list {
+			parts = append(parts, fmt.Sprint(one))
+		}
+		return strings.Join(parts, " or ")
+	}
+	return fmt.Sprint(t)
+}
+
+func typeOf(v any) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case map[string]any:
+		return "object"
+	case []any:
+		return "array"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	}
+	if _, ok := toFloat(v); ok {
+		return "number"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+// toFloat 数字统一按 float64 比较，兼容 JSON（float64/json.Number）与 YAML（int/uint64 等）的解码结果
+func toFloat(v any) (float64, bool) {
+	switch x := v.(type) {
+	case json.Number:
+		f, err := x.Float64()
+		return f, err == nil
+	case bool, string, nil:
+		return 0, false
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(rv.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), true
+	}
+	return 0, false
+}
+
+func toInt(v any) (int, bool) {
+	f, ok := toFloat(v)
+	return int(f), ok
+}
+
+func equal(a, b any) bool {
+	fa, okA := toFloat(a)
+	fb, okB := toFloat(b)
+	if okA || okB {
+		return okA && okB && fa == fb
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+func formatValue(v any) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
+
+func joinValues(values []any) string {
+	parts := make([]string, 0, len(values))
+	for _, v := range values {
+		parts = append(parts, formatValue(v))
+	}
+	return strings.Join(parts, " | ")
+}
@@ -0,0 +1,59 @@
+package jsonschema
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+)
+
+const testSchema = `{
+  "$defs": {
+    "tag": {"type": "string", "minLength": 1, "maxLength": 8}
+  },
+  "type": "object",
+  "additionalProperties": false,
+  "required": ["name", "score"],
+  "properties": {
+    "name": {"type": "string", "pattern": "^[a-z]+$"},
+    "score": {"type": "integer", "minimum": 0, "maximum": 100},
+    "ratio": {"type": "number", "exclusiveMaximum": 1},
+    "level": {"enum": ["low", "high"]},
+    "tags": {"type": "array", "items": {"$ref": "#/$defs/tag"}, "maxItems": 2},
+    "id": {"oneOf": [{"type": "string"}, {"type": "integer"}]},
+    "meta": {"type": "object", "additionalProperties": {"type": "boolean"}}
+  }
+}`
+
+func TestValidate(t *testing.T) {
+	s, err := Parse([]byte(testSchema))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var ok any
+	_ = json.Unmarshal([]byte(`{"name":"abc","score":90,"ratio":0.5,"level":"low","tags":["a","b"],"id":3,"meta":{"x":true}}`), &ok)
+	if errs := s.Validate(ok); len(errs) != 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+
+	var bad any
+	_ = json.Unmarshal([]byte(`{"name":"ABC","score":90.5,"ratio":1,"level":"mid","tags":["","b","c"],"id":true,"meta":{"x":1},"extra":1}`), &bad)
+	var paths []string
+	for _, e := range s.Validate(bad) {
+		paths = append(paths, e.Path)
+	}
+	want := []string{"extra", "id", "level", "meta.x", "name", "ratio", "score", "tags", "tags[0]"}
+	if !slices.Equal(paths, want) {
+		t.Fatalf("paths = %v, want %v", paths, want)
+	}
+
+	errs := s.Validate(map[string]any{"name": "abc"})
+	if len(errs) != 1 || errs[0].Error() != "score: is required" {
+		t.Fatalf("errs = %v", errs)
+	}
+
+	// YAML 解码得到的 int 也按数字处理
+	if errs := s.Validate(map[string]any{"name": "abc", "score": 7, "tags": []any{"x"}}); len(errs) != 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+}
@@ -0,0 +1,90 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+var (
+	ErrJWTInvalid = errors.New("jwt: invalid token")
+	ErrJWTExpired = errors.New("jwt: token expired")
+)
+
+// JWTClaims JWT 标准声明，可内嵌到自定义 claims 中
+type JWTClaims struct {
+	Issuer    string `json:"iss,omitempty"`
+	Subject   string `json:"sub,omitempty"`
+	Audience  string `json:"aud,omitempty"`
+	ExpiresAt int64  `json:"exp,omitempty"`
+	NotBefore int64  `json:"nbf,omitempty"`
+	IssuedAt  int64  `json:"iat,omitempty"`
+	ID        string `json:"jti,omitempty"`
+}
+
+var jwtHeaderHS256 = base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+
+// SignJWT 使用 HS256 签发 JWT，claims 需可 JSON 序列化
+func SignJWT(claims any, secret string) (string, error) {
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	signing := jwtHeaderHS256 + "." + base64.RawURLEncoding.EncodeToString(payload)
+	return signing + "." + jwtSignature(signing, secret), nil
+}
+
+// ParseJWT 校验 HS256 签名与 exp/nbf，并把 payload 解析到 claims；没有 exp 的 token 永不过期，直接拒绝
+func ParseJWT(token, secret string, claims any) error {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return ErrJWTInvalid
+	}
+	header, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return ErrJWTInvalid
+	}
+	var h struct {
+		Alg string `json:"alg"`
+	}
+	// 只接受 HS256，防止 alg=none 之类的降级
+	if json.Unmarshal(header, &h) != nil || h.Alg != "HS256" {
+		return ErrJWTInvalid
+	}
+	expected := jwtSignature(parts[0]+"."+parts[1], secret)
+	if !hmac.Equal([]byte(parts[2]), []byte(expected)) {
+		return ErrJWTInvalid
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return ErrJWTInvalid
+	}
+	var std JWTClaims
+	if json.Unmarshal(payload, &std) != nil {
+		return ErrJWTInvalid
+	}
+	now := time.Now().Unix()
+	if std.ExpiresAt == 0 {
+		return ErrJWTInvalid
+	}
+	if now >= std.ExpiresAt {
+		return ErrJWTExpired
+	}
+	if std.NotBefore != 0 && now < std.NotBefore {
+		return ErrJWTInvalid
+	}
+	if claims != nil && json.Unmarshal(payload, claims) != nil {
+		return ErrJWTInvalid
+	}
+	return nil
+}
+
+func jwtSignature(signing, secret string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(signing))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}